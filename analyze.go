@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// analyzeInput tokenizes and parses the input without rendering it and prints
+// a short report: whether it is valid JSON, its size, how deeply it is nested,
+// how many tokens it has, and how long the check took. The program exits with
+// status 1 if the input is not valid. This is much faster than rendering when
+// only a validity check is wanted.
+func analyzeInput(w io.Writer, jsonFile []byte, options Options) {
+	start := time.Now()
+	documents, tokenCount, err := checkInput(jsonFile, options)
+	elapsed := time.Since(start)
+
+	valid := "yes"
+	if err != nil {
+		valid = "no, " + err.Error()
+	}
+
+	depth := 0
+	for _, document := range documents {
+		if documentDepth := nestingDepth(document); documentDepth > depth {
+			depth = documentDepth
+		}
+	}
+
+	fmt.Fprintf(w, "valid:     %s\n", valid)
+	fmt.Fprintf(w, "size:      %d bytes\n", len(jsonFile))
+	fmt.Fprintf(w, "documents: %d\n", len(documents))
+	fmt.Fprintf(w, "depth:     %d\n", depth)
+	fmt.Fprintf(w, "tokens:    %d\n", tokenCount)
+	fmt.Fprintf(w, "time:      %v\n", elapsed)
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// checkInput tokenizes the input and parses every document in it. It returns
+// the documents, the number of tokens, and the error that comes first in the
+// input, if any.
+func checkInput(jsonFile []byte, options Options) ([][]Token, int, error) {
+	tokenArray := getTokens(jsonFile, options)
+
+	// Characters that are not part of any token, literals that are not
+	// spelled out, and tokens that JSON does not allow are found before
+	// parsing, but a parsing error can come earlier in the input
+	var inputErr *SyntaxError
+	if offset := skippedByte(jsonFile, tokenArray); offset >= 0 {
+		inputErr = &SyntaxError{Offset: offset,
+			message: fmt.Sprintf("unexpected %q at offset %d", jsonFile[offset:offset+1], offset)}
+	}
+	if offset := misreadLiteral(jsonFile, tokenArray); offset >= 0 && (inputErr == nil || offset < inputErr.Offset) {
+		end := offset
+		for end < len(jsonFile) && jsonFile[end] >= 'a' && jsonFile[end] <= 'z' {
+			end++
+		}
+		inputErr = &SyntaxError{Offset: offset,
+			message: fmt.Sprintf("invalid literal %q at offset %d", jsonFile[offset:end], offset)}
+	}
+	if tokenErrs := tokenErrors(tokenArray); len(tokenErrs) > 0 && (inputErr == nil || tokenErrs[0].Offset < inputErr.Offset) {
+		inputErr = tokenErrs[0]
+	}
+
+	if options.fixTrailingCommas {
+		tokenArray, _ = removeTrailingCommas(tokenArray)
+	}
+	tokenCount := len(tokenArray)
+
+	documents := splitDocuments(tokenArray)
+	for _, document := range documents {
+		if _, err := parseTokens(document); err != nil {
+			var parseErr *SyntaxError
+			if inputErr != nil && !(errors.As(err, &parseErr) && parseErr.Offset >= 0 && parseErr.Offset < inputErr.Offset) {
+				return nil, tokenCount, inputErr
+			}
+			return documents, tokenCount, err
+		}
+	}
+	if inputErr != nil {
+		return nil, tokenCount, inputErr
+	}
+	return documents, tokenCount, nil
+}
+
+// validateInput returns the first error in the input, once it is repaired if
+// the options ask for it, or nil if it is valid. It checks what checkInput
+// does, so numbers, escapes, and strings that JSON does not allow are errors
+// even though they can be tokenized and written back.
+func validateInput(jsonFile []byte, options Options) error {
+	if options.repair {
+		jsonFile, _ = repairJSON(jsonFile, options)
+	}
+	_, _, err := checkInput(jsonFile, options)
+	return err
+}
+
+// skippedByte returns the offset of the first character that is not white
+// space and is not part of any of the tokens, or -1 if there is none. The
+// tokenizer skips characters that cannot start a token, which makes the input
+// invalid even though the tokens may parse.
+func skippedByte(jsonFile []byte, tokenArray []Token) int {
+	next := 0 // Where the next token should start
+	for _, token := range tokenArray {
+		for i := next; i < token.offset; i++ {
+			if !isWhiteSpace(jsonFile[i]) {
+				return i
+			}
+		}
+		next = token.offset + len(token.content)
+	}
+	for i := next; i < len(jsonFile); i++ {
+		if !isWhiteSpace(jsonFile[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// misreadLiteral returns the offset of the first true, false, or null that is
+// not spelled out in the input, or -1 if there is none. The tokenizer reads a
+// literal from its first letter, so "tru}" would otherwise pass for true.
+func misreadLiteral(jsonFile []byte, tokenArray []Token) int {
+	for _, token := range tokenArray {
+		switch token.kind {
+		case LiteralBoolTrue, LiteralBoolFalse, LiteralNull:
+			if !bytes.HasPrefix(jsonFile[token.offset:], []byte(token.content)) {
+				return token.offset
+			}
+		}
+	}
+	return -1
+}
+
+// tokenErrors returns an error for each token that the tokenizer reads but
+// JSON does not allow, in the order of the input: numbers that are not written
+// the way JSON writes them, such as 01 or 1.2.3, escapes that JSON does not
+// have, such as \q or \u12zz, and control characters in strings, which have
+// to be escaped
+func tokenErrors(tokenArray []Token) []*SyntaxError {
+	var errs []*SyntaxError
+	for _, token := range tokenArray {
+		switch token.kind {
+		case Number:
+			if !numberPattern.MatchString(token.content) {
+				errs = append(errs, &SyntaxError{Offset: token.offset,
+					message: fmt.Sprintf("invalid number %q at offset %d", token.content, token.offset)})
+			}
+		case StringEscaped:
+			if !isValidEscape(token.content) {
+				errs = append(errs, &SyntaxError{Offset: token.offset,
+					message: fmt.Sprintf("invalid escape %q at offset %d", token.content, token.offset)})
+			}
+		case StringRegular, StringClose:
+			for i := 0; i < len(token.content); i++ {
+				if token.content[i] < 0x20 {
+					offset := token.offset + i
+					errs = append(errs, &SyntaxError{Offset: offset,
+						message: fmt.Sprintf("unescaped control character %q at offset %d", token.content[i:i+1], offset)})
+					break
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// nestingDepth returns how many objects and arrays are nested inside each
+// other at the deepest point of the tokens. A single scalar has a depth of 0.
+func nestingDepth(tokenArray []Token) int {
+	depth, deepest := 0, 0
+	for _, token := range tokenArray {
+		switch token.kind {
+		case ObjectOpen, ArrayOpen:
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+		case ObjectClose, ArrayClose:
+			depth--
+		}
+	}
+	return deepest
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"html"
+	"strconv"
+)
+
+// anchorStyle is added to the page header when values have anchors. Following
+// a link such as 'page.html#/items/2/name' scrolls to that value and briefly
+// flashes it.
+const anchorStyle = `.node:target { animation:anchor-flash 1.5s ease-out }
+@keyframes anchor-flash { from { background-color:#FFE066 } to { background-color:transparent } }`
+
+// anchorScript is added to the page header by --keyboard-nav. The j and k keys
+// (or the down and up arrows) move to the next and previous sibling of the
+// current value, and h moves to the value that contains it. The current value
+// is whichever one the address points at.
+const anchorScript = `function anchorSiblings(node) {
+	var parent = node.parentElement.closest(".node");
+	var scope = parent || document;
+	return Array.prototype.filter.call(scope.querySelectorAll(".node"), function (candidate) {
+		return candidate.parentElement.closest(".node") === parent;
+	});
+}
+document.addEventListener("keydown", function (event) {
+	var steps = { j: 1, ArrowDown: 1, k: -1, ArrowUp: -1, h: 0 };
+	if (event.ctrlKey || event.metaKey || event.altKey || !(event.key in steps)) {
+		return;
+	}
+	var current = document.getElementById(decodeURIComponent(location.hash.slice(1)));
+	var next = null;
+	if (!current) {
+		next = document.querySelector(".node");
+	} else if (steps[event.key] === 0) {
+		next = current.parentElement.closest(".node");
+	} else {
+		var siblings = anchorSiblings(current);
+		next = siblings[siblings.indexOf(current) + steps[event.key]];
+	}
+	if (next) {
+		location.hash = next.id;
+		event.preventDefault();
+	}
+});`
+
+// valueSpan is the range of tokens that make up a single value, from its first
+// token to its last, along with its path in the document
+type valueSpan struct {
+	start int
+	end   int
+	path  []string
+}
+
+// isAnchored returns true if the options call for anchors on every value
+func isAnchored(options Options) bool {
+	return options.anchors || options.keyboardNav
+}
+
+// anchorDecorations returns the decorations that wrap every value below the
+// top level in a span whose id is the value's JSON Pointer
+func anchorDecorations(tokenArray []Token) []Decoration {
+	decorations := make([]Decoration, len(tokenArray))
+
+	for _, span := range findValueSpans(tokenArray) {
+		// The top-level value has an empty pointer, which cannot be an id
+		if len(span.path) == 0 {
+			continue
+		}
+
+		id := html.EscapeString(formatPointer(span.path))
+		decorations[span.start].before += `<span class="node" id="` + id + `">`
+		decorations[span.end].after = `</span>` + decorations[span.end].after
+	}
+
+	return decorations
+}
+
+// findValueSpans walks the tokens and returns the span of every value in them,
+// with each container coming before the values inside it. Keys are not values
+// and so have no span of their own.
+func findValueSpans(tokenArray []Token) []valueSpan {
+	spans := make([]valueSpan, 0)
+
+	// frame is a container that is currently open
+	type frame struct {
+		isObject  bool
+		key       string // The key of the member being read
+		index     int    // The index of the element being read
+		isKeyNext bool   // Is the next string a key
+		span      int    // The container's own span
+	}
+	stack := make([]frame, 0)
+
+	// currentPath returns the path of the value being read
+	currentPath := func() []string {
+		path := make([]string, 0, len(stack))
+		for _, f := range stack {
+			if f.isObject {
+				path = append(path, f.key)
+			} else {
+				path = append(path, strconv.Itoa(f.index))
+			}
+		}
+		return path
+	}
+
+	isInString := false
+	stringStart := 0
+	isKey := false
+
+	for i, token := range tokenArray {
+		switch token.kind {
+		case ObjectOpen, ArrayOpen:
+			spans = append(spans, valueSpan{i, i, currentPath()})
+			stack = append(stack, frame{isObject: token.kind == ObjectOpen, isKeyNext: true, span: len(spans) - 1})
+		case ObjectClose, ArrayClose:
+			if len(stack) > 0 {
+				spans[stack[len(stack)-1].span].end = i
+				stack = stack[:len(stack)-1]
+			}
+		case DelimiterMember:
+			if len(stack) > 0 {
+				stack[len(stack)-1].index++
+				stack[len(stack)-1].isKeyNext = true
+			}
+		case DelimiterPair:
+			if len(stack) > 0 {
+				stack[len(stack)-1].isKeyNext = false
+			}
+		case StringRegular, StringEscaped, StringClose:
+			isOpening := !isInString
+			if isOpening {
+				stringStart = i
+				isKey = len(stack) > 0 && stack[len(stack)-1].isObject && stack[len(stack)-1].isKeyNext
+			}
+
+			isInString = !isStringEnd(token, isOpening)
+			if isInString {
+				continue
+			}
+
+			if isKey {
+				text := ""
+				for _, part := range tokenArray[stringStart : i+1] {
+					text += part.content
+				}
+				stack[len(stack)-1].key = unquoteString(text)
+			} else {
+				spans = append(spans, valueSpan{stringStart, i, currentPath()})
+			}
+		case Number, LiteralBoolTrue, LiteralBoolFalse, LiteralNull:
+			spans = append(spans, valueSpan{i, i, currentPath()})
+		}
+	}
+
+	return spans
+}
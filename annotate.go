@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// annotateTypes gives every value in the tree a short badge naming its type:
+// str, int, float, bool, null, obj{members}, or arr[elements]
+func annotateTypes(node *Node) {
+	switch node.kind {
+	case NodeObject:
+		node.annotation = "obj{" + strconv.Itoa(len(node.members)) + "}"
+	case NodeArray:
+		node.annotation = "arr[" + strconv.Itoa(len(node.elements)) + "]"
+	case NodeString:
+		node.annotation = "str"
+	case NodeNumber:
+		node.annotation = "int"
+		if strings.ContainsAny(rawText(node), ".eE") {
+			node.annotation = "float"
+		}
+	case NodeBool:
+		node.annotation = "bool"
+	case NodeNull:
+		node.annotation = "null"
+	}
+
+	for _, m := range node.members {
+		annotateTypes(m.value)
+	}
+	for _, element := range node.elements {
+		annotateTypes(element)
+	}
+}
+
+// annotateIndexes labels every element of every array in the tree with its
+// index, such as [0], so that elements of long arrays can be referred to
+// without counting
+func annotateIndexes(node *Node) {
+	for i, element := range node.elements {
+		element.label = "[" + strconv.Itoa(i) + "]"
+		annotateIndexes(element)
+	}
+	for _, m := range node.members {
+		annotateIndexes(m.value)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// printANSI prints the documents for a terminal with the same layout as the
+// HTML page. If isColored is true the tokens are colored with ANSI escape
+// sequences in the colors of the theme; otherwise the text is plain. It stops
+// with the context's error once the context is done.
+func printANSI(ctx context.Context, w io.Writer, documents [][]Token, options Options, isColored bool) error {
+	documents = localizeDocuments(documents, options)
+	colors := pageTheme(options)
+
+	for _, document := range documents {
+		state := printState{isLineStart: true}
+		for i, token := range document {
+			if i%4096 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if token.offset >= 0 {
+				options.progress.update("render", int64(token.offset), options.inputSize)
+			}
+			whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+			colorPre, colorPost := "", ""
+			if isColored {
+				colorPre, colorPost = addANSIColor(token, colors)
+			}
+			fmt.Fprint(w, whiteSpacePre+colorPre+token.content+colorPost+whiteSpacePost)
+		}
+		fmt.Fprint(w, "\n")
+	}
+	return nil
+}
+
+// FormatANSI renders the input as text for a terminal, colored in the theme of
+// the options, the same way --format=ansi --color=always does. Like Format, it
+// has no state outside its arguments, so the same input and options always
+// give the same text, which suits golden files and testable examples.
+func FormatANSI(input []byte, options Options) (string, error) {
+	documents, err := formatDocuments(context.Background(), input, options, ioutil.Discard)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	if err := printANSI(context.Background(), &text, documents, options, true); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// addANSIColor returns the escape sequences that color the token in the
+// colors of the theme, which are the same colors addColor uses in the page
+func addANSIColor(token Token, colors theme) (string, string) {
+	color := tokenColor(token, colors)
+	if color == "" {
+		return "", ""
+	}
+
+	codes := []string{"38;2;" + ansiRGB(color)}
+	if background := highlightColor(token, colors); background != "" {
+		codes = append(codes, "48;2;"+ansiRGB(background))
+	}
+	if token.highlight == HighlightRemoved {
+		codes = append(codes, "9")
+	}
+	if token.highlight == HighlightExplanation {
+		codes = append(codes, "3")
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m", "\x1b[0m"
+}
+
+// tokenColor returns the color of the theme for the token, or "" if the token
+// is not colored
+func tokenColor(token Token, colors theme) string {
+	switch token.kind {
+	case ObjectOpen, ObjectClose:
+		return colors.object
+	case ArrayOpen, ArrayClose:
+		return colors.array
+	case DelimiterPair:
+		return colors.pair
+	case DelimiterMember:
+		return colors.member
+	case StringRegular, StringClose:
+		return colors.text
+	case StringEscaped:
+		return colors.escape
+	case Number:
+		return colors.number
+	case LiteralBoolTrue, LiteralBoolFalse, LiteralNull:
+		return colors.literal
+	case Comment:
+		return colors.comment
+	case Annotation:
+		return colors.annotation
+	case ErrorMessage:
+		return colors.object
+	case Unparsed:
+		return colors.comment
+	}
+	return ""
+}
+
+// highlightColor returns the background color of the theme for the token's
+// highlight, or "" if it is not highlighted
+func highlightColor(token Token, colors theme) string {
+	switch token.highlight {
+	case HighlightAdded:
+		return colors.added
+	case HighlightChanged:
+		return colors.changed
+	case HighlightRemoved:
+		return colors.removed
+	case HighlightSynthetic:
+		return colors.changed
+	case HighlightOurs:
+		return colors.added
+	case HighlightTheirs:
+		return colors.changed
+	case HighlightAlert:
+		return colors.removed
+	}
+	return ""
+}
+
+// ansiRGB turns a CSS color such as #D75F5F into the 'red;green;blue' form
+// used by 24-bit ANSI color sequences
+func ansiRGB(color string) string {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 {
+		return "0;0;0"
+	}
+
+	parts := make([]string, 3)
+	for i := range parts {
+		value, err := strconv.ParseUint(hex[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return "0;0;0"
+		}
+		parts[i] = strconv.FormatUint(value, 10)
+	}
+	return strings.Join(parts, ";")
+}
+
+// isColorEnabled returns true if text printed to the file should be colored,
+// given --color. With "auto", color is used only when the file is a terminal
+// that understands ANSI escape sequences and the NO_COLOR environment variable
+// (https://no-color.org) is not set.
+func isColorEnabled(mode string, file *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return isTerminal(file) && isANSITerminal(file)
+}
+
+// isTerminal returns true if the file is a terminal rather than a regular
+// file or a pipe
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// isANSITerminal returns true if the terminal is known to understand ANSI
+// escape sequences, which outside of Windows is every terminal but a dumb one
+func isANSITerminal(file *os.File) bool {
+	return os.Getenv("TERM") != "dumb"
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing is the console mode that makes the Windows
+// 10 console, conhost and PowerShell windows included, read ANSI escape
+// sequences
+const enableVirtualTerminalProcessing = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// isANSITerminal returns true if the terminal understands ANSI escape
+// sequences. The console is switched to virtual terminal processing, which
+// Windows 10 and later have; older consoles fail the call and get no color,
+// unless they are known to read the sequences themselves, as ConEmu,
+// ANSICON, and the mintty and MSYS terminals that set TERM do.
+func isANSITerminal(file *os.File) bool {
+	if enableVirtualTerminal(file) {
+		return true
+	}
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("ConEmuANSI") == "ON" ||
+		os.Getenv("ANSICON") != "" || os.Getenv("TERM_PROGRAM") == "vscode" ||
+		(os.Getenv("TERM") != "" && os.Getenv("TERM") != "dumb")
+}
+
+// enableVirtualTerminal turns on virtual terminal processing for the console
+// of the file, and returns false if it is not a console or the console does
+// not have it
+func enableVirtualTerminal(file *os.File) bool {
+	handle := syscall.Handle(file.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	result, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return result != 0
+}
@@ -0,0 +1,259 @@
+package main
+
+import "strconv"
+
+// diffMaxLCSCells is the largest table that the longest common subsequence of
+// two arrays is found with, as the product of their lengths once the elements
+// they start and end with in common are left out. Larger arrays have their
+// elements matched by index instead.
+const diffMaxLCSCells = 1 << 22
+
+// diffArrays returns the changes that turn one array into another. Elements
+// that are the same in both are matched up in order by their longest common
+// subsequence, so that inserting or removing one does not change every element
+// after it, and elements that are the same but out of that order are moved.
+// With --array-key, elements are the same if that member is, and are diffed in
+// turn; otherwise, the elements left between two that match are diffed with
+// each other by position, as if they had been edited in place.
+//
+// The removals come first, from the end, followed by the additions and moves
+// from the start, each of which puts an element after the one it follows in
+// the new array. The paths of these are valid when the changes are applied one
+// after another, and afterPath says where the element ends up. The changes
+// inside matched elements come last, once every element is where it ends up.
+func diffArrays(before, after *Node, path []string, options Options) []diffChange {
+	changes := make([]diffChange, 0)
+
+	// childPath returns the path of an element of the array
+	childPath := func(index int) []string {
+		return append(append([]string{}, path...), strconv.Itoa(index))
+	}
+
+	source, isMoved := matchElements(elementIdentities(before, options), elementIdentities(after, options), options.arrayKey == "")
+
+	isKept := make([]bool, len(before.elements))
+	for _, i := range source {
+		if i >= 0 {
+			isKept[i] = true
+		}
+	}
+	for i := len(before.elements) - 1; i >= 0; i-- {
+		if !isKept[i] {
+			changes = append(changes, diffChange{op: "remove", path: childPath(i), value: before.elements[i]})
+		}
+	}
+
+	// The array is followed as the changes are applied. Elements from before
+	// are their index, and added ones are -1 less their index in after.
+	current := make([]int, 0, len(after.elements))
+	for i := range before.elements {
+		if isKept[i] {
+			current = append(current, i)
+		}
+	}
+	element := func(j int) int {
+		if source[j] >= 0 {
+			return source[j]
+		}
+		return -1 - j
+	}
+	position := func(value int) int {
+		for p, v := range current {
+			if v == value {
+				return p
+			}
+		}
+		return -1
+	}
+
+	for j := range after.elements {
+		if source[j] >= 0 && !isMoved[j] {
+			continue
+		}
+
+		from := -1
+		if isMoved[j] {
+			from = position(source[j])
+			current = append(current[:from], current[from+1:]...)
+		}
+
+		to := 0
+		if j > 0 {
+			to = position(element(j-1)) + 1
+		}
+		current = append(current, 0)
+		copy(current[to+1:], current[to:])
+		current[to] = element(j)
+
+		switch {
+		case from < 0:
+			changes = append(changes, diffChange{op: "add", path: childPath(to), value: after.elements[j], afterPath: childPath(j)})
+		case from != to:
+			changes = append(changes, diffChange{op: "move", path: childPath(to), value: after.elements[j],
+				from: childPath(from), origin: childPath(source[j]), afterPath: childPath(j)})
+		}
+	}
+
+	for j, i := range source {
+		if i >= 0 {
+			changes = append(changes, diffNodes(before.elements[i], after.elements[j], childPath(j), options)...)
+		}
+	}
+
+	return changes
+}
+
+// elementIdentities returns what each element of the array is matched by:
+// its text, or with --array-key the text of that member if it has one
+func elementIdentities(array *Node, options Options) []string {
+	identities := make([]string, len(array.elements))
+	for i, element := range array.elements {
+		if key := member(element, options.arrayKey); options.arrayKey != "" && key != nil {
+			identities[i] = "key " + valueText(key)
+		} else {
+			identities[i] = "value " + valueText(element)
+		}
+	}
+	return identities
+}
+
+// valueText returns the value written on one line without its comments,
+// annotations, or labels
+func valueText(node *Node) string {
+	var text string
+	for _, token := range nodeTokens(node) {
+		if token.kind != Comment && token.kind != Annotation {
+			text += token.content
+		}
+	}
+	return text
+}
+
+// matchElements matches the elements of after with those of before that have
+// the same identity. It returns the index in before that each element of after
+// is matched with, or -1 for one that was added, and whether the match is out
+// of order, which is a move. With pairGaps, the elements that are left between
+// two matches are matched by position as well.
+func matchElements(before, after []string, pairGaps bool) ([]int, []bool) {
+	source := make([]int, len(after))
+	isMoved := make([]bool, len(after))
+	for j := range source {
+		source[j] = -1
+	}
+
+	// The elements the arrays start and end with in common are matched first,
+	// which is all of them for most small edits
+	start := 0
+	for start < len(before) && start < len(after) && before[start] == after[start] {
+		source[start] = start
+		start++
+	}
+	end := 0
+	for end < len(before)-start && end < len(after)-start && before[len(before)-1-end] == after[len(after)-1-end] {
+		source[len(after)-1-end] = len(before) - 1 - end
+		end++
+	}
+	beforeMiddle, afterMiddle := before[start:len(before)-end], after[start:len(after)-end]
+
+	isUsed := make([]bool, len(before))
+	for _, i := range source {
+		if i >= 0 {
+			isUsed[i] = true
+		}
+	}
+
+	if len(beforeMiddle)*len(afterMiddle) <= diffMaxLCSCells {
+		for _, pair := range longestCommonSubsequence(beforeMiddle, afterMiddle) {
+			source[start+pair[1]] = start + pair[0]
+			isUsed[start+pair[0]] = true
+		}
+
+		// Elements that are the same but out of order are moved, the first
+		// one left in before going to the first one left in after
+		unused := make(map[string][]int)
+		for i := start; i < len(before)-end; i++ {
+			if !isUsed[i] {
+				unused[before[i]] = append(unused[before[i]], i)
+			}
+		}
+		for j := start; j < len(after)-end; j++ {
+			if indexes := unused[after[j]]; source[j] < 0 && len(indexes) > 0 {
+				source[j], isMoved[j] = indexes[0], true
+				isUsed[indexes[0]] = true
+				unused[after[j]] = indexes[1:]
+			}
+		}
+	} else {
+		pairGaps = true
+	}
+
+	if pairGaps {
+		// The elements left between each two that match in order, and before
+		// the first and after the last, are paired up by position
+		lastBefore, lastAfter := -1, -1
+		for j := 0; j <= len(after); j++ {
+			if j < len(after) && (source[j] < 0 || isMoved[j]) {
+				continue
+			}
+			next := len(before)
+			if j < len(after) {
+				next = source[j]
+			}
+
+			i := lastBefore + 1
+			for gap := lastAfter + 1; gap < j; gap++ {
+				for i < next && isUsed[i] {
+					i++
+				}
+				if i == next {
+					break
+				}
+				if source[gap] < 0 {
+					source[gap] = i
+					isUsed[i] = true
+				}
+			}
+			lastBefore, lastAfter = next, j
+		}
+	}
+
+	return source, isMoved
+}
+
+// longestCommonSubsequence returns the pairs of indexes in a and b of the
+// longest run of equal elements that are in the same order in both
+func longestCommonSubsequence(a, b []string) [][2]int {
+	// lengths[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	pairs := make([][2]int, 0, lengths[0][0])
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
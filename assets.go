@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// pageAssets are the styles and scripts that a page can need, by the name of
+// the file that --assets-dir writes each one to. Without --assets-dir they are
+// put in the page itself, so that each page is a single file that works on its
+// own.
+var pageAssets = map[string]string{
+	"fold.css":         foldStyle,
+	"fold.js":          foldScript,
+	"anchor.css":       anchorStyle,
+	"anchor.js":        anchorScript,
+	"raw.js":           rawScript,
+	"print.css":        printStyle,
+	"watch.js":         watchScript,
+	"side-by-side.css": sideBySideStyle,
+	"side-by-side.js":  sideBySideScript,
+	"expander.css":     expanderStyle,
+	"expander.js":      expanderScript,
+	"table.css":        tableStyle,
+	"table.js":         tableScript,
+}
+
+// printAsset prints one of the pageAssets in the head of the page, or with
+// --assets-dir a link to its file, which many pages can share
+func printAsset(w io.Writer, name string, options Options) {
+	isStyle := strings.HasSuffix(name, ".css")
+	switch {
+	case options.assetsDir == "" && isStyle:
+		fmt.Fprintln(w, "\t\t"+"<style>\n"+pageAssets[name]+"\n\t\t</style>")
+	case options.assetsDir == "":
+		fmt.Fprintln(w, "\t\t"+"<script>\n"+pageAssets[name]+"\n\t\t</script>")
+	case isStyle:
+		fmt.Fprintln(w, "\t\t"+"<link rel=\"stylesheet\" href=\""+path.Join(options.assetsPath, name)+"\">")
+	default:
+		fmt.Fprintln(w, "\t\t"+"<script src=\""+path.Join(options.assetsPath, name)+"\"></script>")
+	}
+}
+
+// writeAssets writes every one of the pageAssets to its file in the directory
+func writeAssets(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, content := range pageAssets {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assetsPathFrom returns the path of --assets-dir from the directory a page is
+// written to, for the links in the page
+func assetsPathFrom(pageDir string, options Options) string {
+	relative, err := filepath.Rel(pageDir, options.assetsDir)
+	if err != nil {
+		if relative, err = filepath.Abs(options.assetsDir); err != nil {
+			return filepath.ToSlash(options.assetsDir)
+		}
+	}
+	return filepath.ToSlash(relative)
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// avroMagic is how an Avro object container file starts
+var avroMagic = []byte("Obj\x01")
+
+// avroDecoder reads Avro values, given their schema
+type avroDecoder struct {
+	named map[string]*Node // The schemas of records, enums, and fixed, by full name
+}
+
+// readAvroFile returns the schema of an Avro object container file followed
+// by its first count records. Blocks can be uncompressed or deflated, which
+// are the codecs that every implementation has.
+func readAvroFile(data []byte, count int) ([]*Node, error) {
+	r := &binaryReader{data: data, position: len(avroMagic)}
+	metadata := make(map[string][]byte)
+	err := readAvroBlocks(r, func() error {
+		key, err := readAvroBytes(r)
+		if err != nil {
+			return err
+		}
+		value, err := readAvroBytes(r)
+		metadata[string(key)] = value
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sync, err := r.read(16)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := parseTokens(getTokens(metadata["avro.schema"], Options{}))
+	if err != nil {
+		return nil, fmt.Errorf("the schema is not JSON: %v", err)
+	}
+	codec := string(metadata["avro.codec"])
+	if codec != "" && codec != "null" && codec != "deflate" {
+		return nil, errors.New("the " + codec + " codec is not supported")
+	}
+
+	decoder := &avroDecoder{named: make(map[string]*Node)}
+	decoder.addNames(schema, "")
+
+	roots := []*Node{schema}
+	for len(roots) <= count && r.position < len(r.data) {
+		records, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, errTruncated
+		}
+		block, err := r.read(uint64(size))
+		if err != nil {
+			return nil, err
+		}
+		if codec == "deflate" {
+			if block, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+				return nil, err
+			}
+		}
+
+		blockReader := &binaryReader{data: block}
+		for i := int64(0); i < records && len(roots) <= count; i++ {
+			record, err := decoder.decode(blockReader, schema, "")
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", len(roots), err)
+			}
+			roots = append(roots, record)
+		}
+
+		marker, err := r.read(16)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(marker, sync) {
+			return nil, errors.New("a block does not end with the sync marker")
+		}
+	}
+	return roots, nil
+}
+
+// readAvroLong reads a zigzag varint, which is how Avro writes ints and longs
+func readAvroLong(r *binaryReader) (int64, error) {
+	value, err := r.readVarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(value>>1) ^ -int64(value&1), nil
+}
+
+// readAvroBytes reads bytes or a string, which are written after their length
+func readAvroBytes(r *binaryReader) ([]byte, error) {
+	length, err := readAvroLong(r)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, errors.New("a length is negative")
+	}
+	return r.read(uint64(length))
+}
+
+// readAvroBlocks reads the items of an array or map, which come in blocks that
+// start with how many items they have, until an empty one
+func readAvroBlocks(r *binaryReader, readItem func() error) error {
+	for {
+		count, err := readAvroLong(r)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		// A negative count is followed by the size of the block
+		if count < 0 {
+			count = -count
+			if _, err := readAvroLong(r); err != nil {
+				return err
+			}
+		}
+		if uint64(count) > uint64(len(r.data)) {
+			return fmt.Errorf("a block says it has %d items", count)
+		}
+		for i := int64(0); i < count; i++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// avroFullName returns the full name of a named type, which is in the
+// enclosing namespace unless it has one of its own
+func avroFullName(schema *Node, namespace string) string {
+	name := stringValue(member(schema, "name"))
+	if strings.Contains(name, ".") {
+		return name
+	}
+	if ns := member(schema, "namespace"); ns != nil && ns.kind == NodeString {
+		namespace = stringValue(ns)
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// avroNamespace returns the namespace that the types inside a named type are
+// in
+func avroNamespace(schema *Node, namespace string) string {
+	name := avroFullName(schema, namespace)
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		return name[:dot]
+	}
+	return ""
+}
+
+// addNames finds the named types in the schema, so that they can be referred
+// to by name
+func (d *avroDecoder) addNames(schema *Node, namespace string) {
+	switch schema.kind {
+	case NodeArray:
+		for _, branch := range schema.elements {
+			d.addNames(branch, namespace)
+		}
+	case NodeObject:
+		switch stringValue(member(schema, "type")) {
+		case "record", "error", "enum", "fixed":
+			d.named[avroFullName(schema, namespace)] = schema
+			namespace = avroNamespace(schema, namespace)
+		}
+		if fields := member(schema, "fields"); fields != nil {
+			for _, field := range fields.elements {
+				if fieldType := member(field, "type"); fieldType != nil {
+					d.addNames(fieldType, namespace)
+				}
+			}
+		}
+		for _, key := range []string{"type", "items", "values"} {
+			if inner := member(schema, key); inner != nil && inner.kind != NodeString {
+				d.addNames(inner, namespace)
+			}
+		}
+	}
+}
+
+// decode reads a value of the schema. Bytes and fixed are base64, and values
+// with a logical type that JSON has no type for, such as timestamps, are
+// written out and annotated with it.
+func (d *avroDecoder) decode(r *binaryReader, schema *Node, namespace string) (*Node, error) {
+	leave, err := r.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	switch schema.kind {
+	case NodeString:
+		name := stringValue(schema)
+		if named, ok := d.named[name]; ok {
+			return d.decode(r, named, namespace)
+		}
+		if named, ok := d.named[namespace+"."+name]; ok {
+			return d.decode(r, named, namespace)
+		}
+		return d.decodePrimitive(r, name)
+	case NodeArray:
+		index, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(schema.elements)) {
+			return nil, fmt.Errorf("union branch %d is not in the schema", index)
+		}
+		return d.decode(r, schema.elements[index], namespace)
+	case NodeObject:
+	default:
+		return nil, errors.New("the schema is not a type")
+	}
+
+	kind := member(schema, "type")
+	if kind == nil {
+		return nil, errors.New("a schema has no type")
+	}
+	if kind.kind != NodeString {
+		return d.decode(r, kind, namespace)
+	}
+
+	switch stringValue(kind) {
+	case "record", "error":
+		inner := avroNamespace(schema, namespace)
+		record := newObjectNode()
+		if fields := member(schema, "fields"); fields != nil {
+			for _, field := range fields.elements {
+				fieldType := member(field, "type")
+				if fieldType == nil {
+					return nil, errors.New("a field has no type")
+				}
+				value, err := d.decode(r, fieldType, inner)
+				if err != nil {
+					return nil, err
+				}
+				record.members = append(record.members, Member{newStringNode(stringValue(member(field, "name"))), value})
+			}
+		}
+		return record, nil
+	case "enum":
+		index, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		symbols := member(schema, "symbols")
+		if symbols == nil || index < 0 || index >= int64(len(symbols.elements)) {
+			return nil, fmt.Errorf("enum symbol %d is not in the schema", index)
+		}
+		return newStringNode(stringValue(symbols.elements[index])), nil
+	case "array":
+		items := member(schema, "items")
+		if items == nil {
+			return nil, errors.New("an array has no items")
+		}
+		array := newArrayNode()
+		err := readAvroBlocks(r, func() error {
+			element, err := d.decode(r, items, namespace)
+			array.elements = append(array.elements, element)
+			return err
+		})
+		return array, err
+	case "map":
+		values := member(schema, "values")
+		if values == nil {
+			return nil, errors.New("a map has no values")
+		}
+		object := newObjectNode()
+		err := readAvroBlocks(r, func() error {
+			key, err := readAvroBytes(r)
+			if err != nil {
+				return err
+			}
+			value, err := d.decode(r, values, namespace)
+			object.members = append(object.members, Member{newStringNode(string(key)), value})
+			return err
+		})
+		return object, err
+	case "fixed":
+		size := member(schema, "size")
+		if size == nil || numberValue(size) < 0 {
+			return nil, errors.New("a fixed has no size")
+		}
+		data, err := r.read(uint64(numberValue(size)))
+		if err != nil {
+			return nil, err
+		}
+		return avroLogical(bytesNode(data, "fixed"), data, schema), nil
+	}
+
+	if stringValue(kind) == "bytes" {
+		data, err := readAvroBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return avroLogical(bytesNode(data, "bytes"), data, schema), nil
+	}
+	value, err := d.decodePrimitive(r, stringValue(kind))
+	if err != nil {
+		return nil, err
+	}
+	return avroLogical(value, nil, schema), nil
+}
+
+// decodePrimitive reads a value of a primitive type
+func (d *avroDecoder) decodePrimitive(r *binaryReader, name string) (*Node, error) {
+	switch name {
+	case "null":
+		return newNullNode(), nil
+	case "boolean":
+		value, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return newBoolNode(value != 0), nil
+	case "int", "long":
+		value, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		return newNumberNode(strconv.FormatInt(value, 10)), nil
+	case "float":
+		bits, err := r.readLittleEndian(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(float64(math.Float32frombits(uint32(bits))), 32), nil
+	case "double":
+		bits, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(math.Float64frombits(bits), 64), nil
+	case "bytes":
+		data, err := readAvroBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return bytesNode(data, "bytes"), nil
+	case "string":
+		text, err := readAvroBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return newStringNode(string(text)), nil
+	}
+	return nil, errors.New("unknown type " + name)
+}
+
+// avroLogical returns the value written as its logical type, if the schema
+// has one: times as RFC 3339 strings and decimals as numbers, annotated with
+// the logical type
+func avroLogical(value *Node, data []byte, schema *Node) *Node {
+	logical := member(schema, "logicalType")
+	if logical == nil || logical.kind != NodeString {
+		return value
+	}
+	name := stringValue(logical)
+
+	switch name {
+	case "timestamp-millis", "local-timestamp-millis":
+		return annotated(newStringNode(time.UnixMilli(int64(numberValue(value))).UTC().Format(time.RFC3339Nano)), name)
+	case "timestamp-micros", "local-timestamp-micros":
+		return annotated(newStringNode(time.UnixMicro(int64(numberValue(value))).UTC().Format(time.RFC3339Nano)), name)
+	case "date":
+		return annotated(newStringNode(time.Unix(0, 0).AddDate(0, 0, int(numberValue(value))).UTC().Format("2006-01-02")), name)
+	case "decimal":
+		if data == nil {
+			break
+		}
+		// The unscaled value is a big-endian two's complement integer
+		unscaled := new(big.Int).SetBytes(data)
+		if len(data) > 0 && data[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(data))))
+		}
+		scale := 0
+		if s := member(schema, "scale"); s != nil {
+			scale = int(numberValue(s))
+		}
+		return annotated(newNumberNode(scaledDecimal(unscaled, scale)), name)
+	}
+	return annotated(value, name)
+}
+
+// scaledDecimal writes the integer divided by ten to the power of the scale
+func scaledDecimal(unscaled *big.Int, scale int) string {
+	sign, digits := "", unscaled.String()
+	if unscaled.Sign() < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if scale <= 0 {
+		return sign + digits + strings.Repeat("0", -scale)
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// binaryMaxDepth is how deeply the values of binary input can be nested,
+// which keeps input that was made to be deep from using up the stack
+const binaryMaxDepth = 10000
+
+// binaryDecoders decode the binary formats that --input reads, each into the
+// trees of the values in the input
+var binaryDecoders = map[string]func(*binaryReader) (*Node, error){
+	"msgpack": decodeMessagePack,
+	"cbor":    decodeCBOR,
+	"bson":    decodeBSON,
+}
+
+// decodedDocuments returns the documents of input in one of the binary
+// formats of --input, one for each value in it, with the options that change
+// values applied as they are to JSON. Values that JSON does not have, such as
+// binary data and timestamps, are written as strings and annotated with what
+// they were.
+func decodedDocuments(ctx context.Context, input []byte, options Options, report io.Writer) ([][]Token, error) {
+	decode, ok := binaryDecoders[options.input]
+	if !ok {
+		return nil, errors.New("Unknown input format: " + options.input)
+	}
+
+	reader := &binaryReader{data: input}
+	documents := make([][]Token, 0, 1)
+	for reader.position < len(reader.data) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		root, err := decode(reader)
+		if err != nil {
+			return nil, fmt.Errorf("%s at offset %d: %v", options.input, reader.position, err)
+		}
+		if isTreeNeeded(options) {
+			if root, err = transformTree(root, options, report); err != nil {
+				return nil, err
+			}
+		}
+		documents = append(documents, nodeTokens(root))
+	}
+	return documents, nil
+}
+
+// binaryReader reads binary input from the start
+type binaryReader struct {
+	data     []byte
+	position int
+	depth    int // How many containers the value being read is in
+}
+
+// errTruncated is the error for binary input that ends inside a value
+var errTruncated = errors.New("the input ends in the middle of a value")
+
+// read returns the next count bytes
+func (r *binaryReader) read(count uint64) ([]byte, error) {
+	if count > uint64(len(r.data)-r.position) {
+		return nil, errTruncated
+	}
+	bytes := r.data[r.position : r.position+int(count)]
+	r.position += int(count)
+	return bytes, nil
+}
+
+// readByte returns the next byte
+func (r *binaryReader) readByte() (byte, error) {
+	bytes, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return bytes[0], nil
+}
+
+// readUint returns the next big-endian unsigned integer of size bytes
+func (r *binaryReader) readUint(size int) (uint64, error) {
+	bytes, err := r.read(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range bytes {
+		value = value<<8 | uint64(b)
+	}
+	return value, nil
+}
+
+// enter notes that a container is being read, failing if they are nested too
+// deeply, and returns the function that notes it has been read
+func (r *binaryReader) enter() (func(), error) {
+	if r.depth >= binaryMaxDepth {
+		return nil, fmt.Errorf("values are nested more than %d deep", binaryMaxDepth)
+	}
+	r.depth++
+	return func() { r.depth-- }, nil
+}
+
+// annotated returns the node with the annotation, for values that JSON does
+// not have
+func annotated(node *Node, annotation string) *Node {
+	node.annotation = annotation
+	return node
+}
+
+// floatNode returns a number node for a float. JSON has no infinities or NaN,
+// so those are strings annotated as floats.
+func floatNode(value float64, bits int) *Node {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return annotated(newStringNode(strconv.FormatFloat(value, 'g', -1, bits)), "float")
+	}
+	return newNumberNode(strconv.FormatFloat(value, 'g', -1, bits))
+}
+
+// bytesNode returns binary data as a string of its base64, annotated with its
+// size
+func bytesNode(data []byte, kind string) *Node {
+	return annotated(newStringNode(base64.StdEncoding.EncodeToString(data)), fmt.Sprintf("%s, %d bytes", kind, len(data)))
+}
+
+// keyText returns the text of a value that is the key of a map, which in
+// binary formats does not have to be a string
+func keyText(key *Node) string {
+	if key.kind == NodeString {
+		return stringValue(key)
+	}
+	return valueText(key)
+}
+
+// decodeMessagePack reads a MessagePack value. Binary data and extensions are
+// base64 strings, except for timestamps, which are RFC 3339 strings.
+func decodeMessagePack(r *binaryReader) (*Node, error) {
+	code, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return newNumberNode(strconv.Itoa(int(code))), nil
+	case code >= 0xe0:
+		return newNumberNode(strconv.Itoa(int(int8(code)))), nil
+	case code >= 0x80 && code <= 0x8f:
+		return decodeMessagePackMap(r, uint64(code&0x0f))
+	case code >= 0x90 && code <= 0x9f:
+		return decodeMessagePackArray(r, uint64(code&0x0f))
+	case code >= 0xa0 && code <= 0xbf:
+		text, err := r.read(uint64(code & 0x1f))
+		if err != nil {
+			return nil, err
+		}
+		return newStringNode(string(text)), nil
+	}
+
+	// sized reads a length of size bytes and then that many bytes
+	sized := func(size int) ([]byte, error) {
+		length, err := r.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		return r.read(length)
+	}
+	// extension reads the type of an extension and then size bytes of it
+	extension := func(size int) (*Node, error) {
+		kind, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.read(uint64(size))
+		if err != nil {
+			return nil, err
+		}
+		return messagePackExtension(int8(kind), data), nil
+	}
+
+	switch code {
+	case 0xc0:
+		return newNullNode(), nil
+	case 0xc2:
+		return newBoolNode(false), nil
+	case 0xc3:
+		return newBoolNode(true), nil
+	case 0xc4, 0xc5, 0xc6:
+		data, err := sized(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return bytesNode(data, "binary"), nil
+	case 0xc7, 0xc8, 0xc9:
+		length, err := r.readUint(1 << (code - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		if length > uint64(len(r.data)) {
+			return nil, errTruncated
+		}
+		return extension(int(length))
+	case 0xca:
+		bits, err := r.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(float64(math.Float32frombits(uint32(bits))), 32), nil
+	case 0xcb:
+		bits, err := r.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(math.Float64frombits(bits), 64), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		value, err := r.readUint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return newNumberNode(strconv.FormatUint(value, 10)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		value, err := r.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		// The value is sign-extended from its size
+		shift := uint(64 - 8*size)
+		return newNumberNode(strconv.FormatInt(int64(value<<shift)>>shift, 10)), nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return extension(1 << (code - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		text, err := sized(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return newStringNode(string(text)), nil
+	case 0xdc, 0xdd:
+		length, err := r.readUint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMessagePackArray(r, length)
+	case 0xde, 0xdf:
+		length, err := r.readUint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMessagePackMap(r, length)
+	}
+	return nil, fmt.Errorf("0x%02x is not the start of a value", code)
+}
+
+// decodeElements reads the elements of an array, with decode reading each
+func (r *binaryReader) decodeElements(length uint64, decode func(*binaryReader) (*Node, error)) (*Node, error) {
+	leave, err := r.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	array := newArrayNode()
+	for i := uint64(0); i < length; i++ {
+		element, err := decode(r)
+		if err == cborBreak && length == indefiniteLength {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		array.elements = append(array.elements, element)
+	}
+	return array, nil
+}
+
+// decodeMembers reads the keys and values of a map, with decode reading each.
+// Keys that are not strings are written as strings of their value.
+func (r *binaryReader) decodeMembers(length uint64, decode func(*binaryReader) (*Node, error)) (*Node, error) {
+	leave, err := r.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	object := newObjectNode()
+	for i := uint64(0); i < length; i++ {
+		key, err := decode(r)
+		if err == cborBreak && length == indefiniteLength {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		value, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		object.members = append(object.members, Member{newStringNode(keyText(key)), value})
+	}
+	return object, nil
+}
+
+// decodeMessagePackArray reads the elements of a MessagePack array
+func decodeMessagePackArray(r *binaryReader, length uint64) (*Node, error) {
+	return r.decodeElements(length, decodeMessagePack)
+}
+
+// decodeMessagePackMap reads the members of a MessagePack map
+func decodeMessagePackMap(r *binaryReader, length uint64) (*Node, error) {
+	return r.decodeMembers(length, decodeMessagePack)
+}
+
+// messagePackExtension returns the value of an extension. Type -1 is a
+// timestamp, in one of three sizes; other types are kept as their data.
+func messagePackExtension(kind int8, data []byte) *Node {
+	if kind == -1 {
+		var moment time.Time
+		isTimestamp := true
+		switch len(data) {
+		case 4:
+			moment = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+		case 8:
+			value := binary.BigEndian.Uint64(data)
+			moment = time.Unix(int64(value&0x3ffffffff), int64(value>>34))
+		case 12:
+			moment = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data[:4])))
+		default:
+			isTimestamp = false
+		}
+		if isTimestamp {
+			return annotated(newStringNode(moment.UTC().Format(time.RFC3339Nano)), "timestamp")
+		}
+	}
+	return bytesNode(data, fmt.Sprintf("extension %d", kind))
+}
+
+// indefiniteLength is the length of an array or map that a break ends
+const indefiniteLength = math.MaxUint64
+
+// cborBreak is returned by decodeCBOR for the end of an indefinite length
+// item, which is not a value
+var cborBreak = errors.New("unexpected break")
+
+// decodeCBOR reads a CBOR value. Byte strings are base64 strings, and tags
+// that JSON has no type for are annotated on the value they tag, except for
+// times, which are RFC 3339 strings, and big numbers, which are numbers.
+func decodeCBOR(r *binaryReader) (*Node, error) {
+	initial, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := initial>>5, initial&0x1f
+
+	if initial == 0xff {
+		return nil, cborBreak
+	}
+	if major == 7 {
+		return decodeCBORSimple(r, info)
+	}
+
+	// The argument is the value, length, or tag, depending on the major type
+	var argument uint64
+	isIndefinite := false
+	switch {
+	case info < 24:
+		argument = uint64(info)
+	case info <= 27:
+		if argument, err = r.readUint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	case info == 31 && major >= 2 && major <= 5:
+		isIndefinite = true
+	default:
+		return nil, fmt.Errorf("0x%02x is not the start of a value", initial)
+	}
+
+	switch major {
+	case 0:
+		return newNumberNode(strconv.FormatUint(argument, 10)), nil
+	case 1:
+		value := new(big.Int).SetUint64(argument)
+		return newNumberNode(value.Neg(value).Sub(value, big.NewInt(1)).String()), nil
+	case 2, 3:
+		data, err := readCBORString(r, major, argument, isIndefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == 2 {
+			return bytesNode(data, "bytes"), nil
+		}
+		return newStringNode(string(data)), nil
+	case 4:
+		if isIndefinite {
+			argument = indefiniteLength
+		}
+		return r.decodeElements(argument, decodeCBOR)
+	case 5:
+		if isIndefinite {
+			argument = indefiniteLength
+		}
+		return r.decodeMembers(argument, decodeCBOR)
+	}
+	return decodeCBORTag(r, argument)
+}
+
+// readCBORString reads the data of a byte or text string, which an indefinite
+// length string has in chunks
+func readCBORString(r *binaryReader, major byte, length uint64, isIndefinite bool) ([]byte, error) {
+	if !isIndefinite {
+		return r.read(length)
+	}
+
+	var data []byte
+	for {
+		chunk, err := decodeCBOR(r)
+		if err == cborBreak {
+			return data, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if chunk.kind != NodeString {
+			return nil, errors.New("a chunk of a string is not a string")
+		}
+		if major == 2 {
+			decoded, _ := base64.StdEncoding.DecodeString(stringValue(chunk))
+			data = append(data, decoded...)
+		} else {
+			data = append(data, stringValue(chunk)...)
+		}
+	}
+}
+
+// decodeCBORSimple reads a simple value or a float
+func decodeCBORSimple(r *binaryReader, info byte) (*Node, error) {
+	switch info {
+	case 20:
+		return newBoolNode(false), nil
+	case 21:
+		return newBoolNode(true), nil
+	case 22:
+		return newNullNode(), nil
+	case 23:
+		return annotated(newNullNode(), "undefined"), nil
+	case 24:
+		value, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return annotated(newNumberNode(strconv.Itoa(int(value))), "simple"), nil
+	case 25:
+		bits, err := r.readUint(2)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(halfFloat(uint16(bits)), 32), nil
+	case 26:
+		bits, err := r.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(float64(math.Float32frombits(uint32(bits))), 32), nil
+	case 27:
+		bits, err := r.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(math.Float64frombits(bits), 64), nil
+	}
+	if info < 20 {
+		return annotated(newNumberNode(strconv.Itoa(int(info))), "simple"), nil
+	}
+	return nil, fmt.Errorf("0x%02x is not the start of a value", 0xe0|info)
+}
+
+// halfFloat returns the value of a 16-bit IEEE 754 float
+func halfFloat(bits uint16) float64 {
+	exponent, fraction := int(bits>>10&0x1f), float64(bits&0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(fraction, -24)
+	case 31:
+		value = math.Inf(1)
+		if fraction != 0 {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(fraction+1024, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// decodeCBORTag reads the value that a tag applies to
+func decodeCBORTag(r *binaryReader, tag uint64) (*Node, error) {
+	leave, err := r.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	value, err := decodeCBOR(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tag == 0 && value.kind == NodeString:
+		return annotated(value, "date/time"), nil
+	case tag == 1 && value.kind == NodeNumber:
+		moment := time.Unix(0, int64(numberValue(value)*1e9))
+		return annotated(newStringNode(moment.UTC().Format(time.RFC3339Nano)), "epoch time"), nil
+	case (tag == 2 || tag == 3) && value.kind == NodeString:
+		data, _ := base64.StdEncoding.DecodeString(stringValue(value))
+		number := new(big.Int).SetBytes(data)
+		if tag == 3 {
+			number.Neg(number).Sub(number, big.NewInt(1))
+		}
+		return newNumberNode(number.String()), nil
+	}
+
+	text := fmt.Sprintf("tag %d", tag)
+	if value.annotation != "" {
+		text += ", " + value.annotation
+	}
+	return annotated(value, text), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// brokenDocuments returns the tokens that --show-errors prints for input that
+// is not valid. Everything before the first error is tokenized and laid out as
+// usual, and it is followed by the message of the error and the rest of the
+// input as it was written, so that it is clear how far the input made sense.
+// Options that change the values are left out, since they need valid input.
+func brokenDocuments(ctx context.Context, jsonFile []byte, err error, options Options) ([][]Token, error) {
+	var syntaxError *SyntaxError
+	if !errors.As(err, &syntaxError) {
+		return nil, err
+	}
+
+	// Errors at the end of the input, such as a missing bracket, come after
+	// all of it
+	offset := syntaxError.Offset
+	if offset < 0 {
+		offset = len(jsonFile)
+	}
+
+	tokenArray, err := tokenize(ctx, jsonFile[:offset], options)
+	if err != nil {
+		return nil, err
+	}
+	if options.fixTrailingCommas {
+		tokenArray, _ = removeTrailingCommas(tokenArray)
+	}
+
+	documents := splitDocuments(tokenArray)
+	if len(documents) == 0 {
+		documents = [][]Token{{}}
+	}
+	last := len(documents) - 1
+	documents[last] = append(documents[last], makeToken("error: "+syntaxError.Error(), ErrorMessage))
+	if offset < len(jsonFile) {
+		documents[last] = append(documents[last], Token{content: string(jsonFile[offset:]), kind: Unparsed, offset: offset})
+	}
+	return documents, nil
+}
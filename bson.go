@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodeBSON reads a BSON document, as mongodump writes one after another.
+// The types that JSON does not have are annotated with their BSON type:
+// ObjectIds are their hex, with the time they were made, dates are RFC 3339
+// strings, and binary data is base64, except for UUIDs.
+func decodeBSON(r *binaryReader) (*Node, error) {
+	return decodeBSONDocument(r, false)
+}
+
+// readLittleEndian returns the next little-endian unsigned integer of size
+// bytes, which is how BSON writes numbers
+func (r *binaryReader) readLittleEndian(size int) (uint64, error) {
+	bytes, err := r.read(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for i := len(bytes) - 1; i >= 0; i-- {
+		value = value<<8 | uint64(bytes[i])
+	}
+	return value, nil
+}
+
+// readCString returns the text up to the next zero byte, which is how BSON
+// writes names
+func (r *binaryReader) readCString() (string, error) {
+	for end := r.position; end < len(r.data); end++ {
+		if r.data[end] == 0 {
+			text := string(r.data[r.position:end])
+			r.position = end + 1
+			return text, nil
+		}
+	}
+	return "", errTruncated
+}
+
+// readBSONString returns a string, which BSON writes after its length
+func (r *binaryReader) readBSONString() (string, error) {
+	length, err := r.readLittleEndian(4)
+	if err != nil {
+		return "", err
+	}
+	if length == 0 {
+		return "", errors.New("a string has no room for its zero byte")
+	}
+	text, err := r.read(length)
+	if err != nil {
+		return "", err
+	}
+	return string(text[:length-1]), nil
+}
+
+// decodeBSONDocument reads a document as an object, or as an array if
+// isArray, whose names are only the index of each element
+func decodeBSONDocument(r *binaryReader, isArray bool) (*Node, error) {
+	leave, err := r.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	start := r.position
+	size, err := r.readLittleEndian(4)
+	if err != nil {
+		return nil, err
+	}
+	if size < 5 || size > uint64(len(r.data)-start) {
+		return nil, fmt.Errorf("a document says it has %d bytes", size)
+	}
+	end := start + int(size)
+
+	node := newObjectNode()
+	if isArray {
+		node = newArrayNode()
+	}
+	for {
+		kind, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if kind == 0 {
+			break
+		}
+		name, err := r.readCString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeBSONValue(r, kind)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if r.position >= end {
+			return nil, fmt.Errorf("%s goes past the end of its document", name)
+		}
+		if isArray {
+			node.elements = append(node.elements, value)
+		} else {
+			node.members = append(node.members, Member{newStringNode(name), value})
+		}
+	}
+	if r.position != end {
+		return nil, fmt.Errorf("a document ends %d bytes from where it says", r.position-end)
+	}
+	return node, nil
+}
+
+// decodeBSONValue reads a value of the type
+func decodeBSONValue(r *binaryReader, kind byte) (*Node, error) {
+	switch kind {
+	case 0x01:
+		bits, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatNode(math.Float64frombits(bits), 64), nil
+	case 0x02, 0x0d, 0x0e:
+		text, err := r.readBSONString()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case 0x0d:
+			return annotated(newStringNode(text), "JavaScript"), nil
+		case 0x0e:
+			return annotated(newStringNode(text), "symbol"), nil
+		}
+		return newStringNode(text), nil
+	case 0x03, 0x04:
+		return decodeBSONDocument(r, kind == 0x04)
+	case 0x05:
+		return decodeBSONBinary(r)
+	case 0x06:
+		return annotated(newNullNode(), "undefined"), nil
+	case 0x07:
+		id, err := r.read(12)
+		if err != nil {
+			return nil, err
+		}
+		return objectIDNode(id), nil
+	case 0x08:
+		value, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return newBoolNode(value != 0), nil
+	case 0x09:
+		milliseconds, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		moment := time.Unix(0, 0).Add(time.Duration(int64(milliseconds)) * time.Millisecond)
+		return annotated(newStringNode(moment.UTC().Format(time.RFC3339Nano)), "DateTime"), nil
+	case 0x0a:
+		return newNullNode(), nil
+	case 0x0b:
+		pattern, err := r.readCString()
+		if err != nil {
+			return nil, err
+		}
+		flags, err := r.readCString()
+		if err != nil {
+			return nil, err
+		}
+		return annotated(newStringNode("/"+pattern+"/"+flags), "regex"), nil
+	case 0x0c:
+		namespace, err := r.readBSONString()
+		if err != nil {
+			return nil, err
+		}
+		id, err := r.read(12)
+		if err != nil {
+			return nil, err
+		}
+		return annotated(newStringNode(namespace), "DBPointer "+hex.EncodeToString(id)), nil
+	case 0x0f:
+		if _, err := r.readLittleEndian(4); err != nil {
+			return nil, err
+		}
+		code, err := r.readBSONString()
+		if err != nil {
+			return nil, err
+		}
+		scope, err := decodeBSONDocument(r, false)
+		if err != nil {
+			return nil, err
+		}
+		object := newObjectNode()
+		object.members = []Member{{newStringNode("code"), newStringNode(code)}, {newStringNode("scope"), scope}}
+		return annotated(object, "JavaScript with scope"), nil
+	case 0x10:
+		value, err := r.readLittleEndian(4)
+		if err != nil {
+			return nil, err
+		}
+		return newNumberNode(strconv.Itoa(int(int32(value)))), nil
+	case 0x11:
+		value, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		// The seconds are the high half and the increment the low half
+		object := newObjectNode()
+		object.members = []Member{
+			{newStringNode("t"), newNumberNode(strconv.FormatUint(value>>32, 10))},
+			{newStringNode("i"), newNumberNode(strconv.FormatUint(value&0xffffffff, 10))},
+		}
+		return annotated(object, "Timestamp "+time.Unix(int64(value>>32), 0).UTC().Format(time.RFC3339)), nil
+	case 0x12:
+		value, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		return newNumberNode(strconv.FormatInt(int64(value), 10)), nil
+	case 0x13:
+		low, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		high, err := r.readLittleEndian(8)
+		if err != nil {
+			return nil, err
+		}
+		return decimal128Node(high, low), nil
+	case 0xff:
+		return annotated(newNullNode(), "MinKey"), nil
+	case 0x7f:
+		return annotated(newNullNode(), "MaxKey"), nil
+	}
+	return nil, fmt.Errorf("0x%02x is not a BSON type", kind)
+}
+
+// decodeBSONBinary reads binary data, which is base64 annotated with its
+// subtype, except for UUIDs, which are written the usual way
+func decodeBSONBinary(r *binaryReader) (*Node, error) {
+	length, err := r.readLittleEndian(4)
+	if err != nil {
+		return nil, err
+	}
+	subtype, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.read(length)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case (subtype == 0x03 || subtype == 0x04) && len(data) == 16:
+		text := hex.EncodeToString(data)
+		uuid := text[:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:]
+		if subtype == 0x03 {
+			return annotated(newStringNode(uuid), "legacy UUID"), nil
+		}
+		return annotated(newStringNode(uuid), "UUID"), nil
+	case subtype == 0x02 && len(data) >= 4:
+		// The old binary subtype repeats the length before the data
+		data = data[4:]
+	}
+	if subtype == 0x00 {
+		return bytesNode(data, "binary"), nil
+	}
+	return bytesNode(data, fmt.Sprintf("binary subtype %d", subtype)), nil
+}
+
+// objectIDNode returns an ObjectId as its hex, annotated with the time it was
+// made, which is its first four bytes
+func objectIDNode(id []byte) *Node {
+	seconds := int64(id[0])<<24 | int64(id[1])<<16 | int64(id[2])<<8 | int64(id[3])
+	created := time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	return annotated(newStringNode(hex.EncodeToString(id)), "ObjectId, made "+created)
+}
+
+// decimal128Node returns an IEEE 754 decimal128, given as its high and low
+// halves, as a number. Infinities and NaN are strings, as they are for
+// floats.
+func decimal128Node(high, low uint64) *Node {
+	sign := ""
+	if high>>63 != 0 {
+		sign = "-"
+	}
+	switch high >> 58 & 0x1f {
+	case 0x1e:
+		return annotated(newStringNode(sign+"Infinity"), "decimal128")
+	case 0x1f:
+		return annotated(newStringNode("NaN"), "decimal128")
+	}
+
+	// A coefficient with the longer exponent field is too large to be valid,
+	// which makes it zero
+	var exponent int
+	coefficient := new(big.Int)
+	if high>>61&0x3 == 0x3 {
+		exponent = int(high>>47&0x3fff) - 6176
+	} else {
+		exponent = int(high>>49&0x3fff) - 6176
+		coefficient.SetUint64(high & (1<<49 - 1))
+		coefficient.Lsh(coefficient, 64).Or(coefficient, new(big.Int).SetUint64(low))
+	}
+
+	digits := coefficient.String()
+	var text string
+	switch {
+	case exponent > 0:
+		text = digits + "e" + strconv.Itoa(exponent)
+	case exponent == 0:
+		text = digits
+	case -exponent < len(digits):
+		text = digits[:len(digits)+exponent] + "." + digits[len(digits)+exponent:]
+	case -exponent < len(digits)+20:
+		text = "0." + strings.Repeat("0", -exponent-len(digits)) + digits
+	default:
+		text = digits + "e" + strconv.Itoa(exponent)
+	}
+	return annotated(newNumberNode(sign+text), "decimal128")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// chatFormat is how the document is posted to a chat tool, as code blocks in
+// messages that fit its length limit
+type chatFormat struct {
+	limit     int               // The most characters a message can have
+	fence     string            // What opens each code block
+	escaper   *strings.Replacer // What the text needs escaped in a message
+	truncated string            // The note after the last message, given how many lines were left out
+}
+
+// chatFormats are the chat tools, by the name of their --format
+var chatFormats = map[string]chatFormat{
+	// Slack asks for messages of at most 4,000 characters, and its mrkdwn
+	// needs &, <, and > escaped even in code blocks, which take no language
+	"slack": {
+		limit:     4000,
+		fence:     "```",
+		escaper:   strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "`", `\u0060`),
+		truncated: "_%d more lines were left out. Upload the JSON as a snippet to share all of it._",
+	},
+	"discord": {
+		limit:     2000,
+		fence:     "```json",
+		escaper:   strings.NewReplacer("`", `\u0060`),
+		truncated: "*%d more lines were left out. Attach the JSON as a file to share all of it.*",
+	},
+}
+
+// printChat prints the documents as messages for a chat tool, each one a code
+// block that fits in a message, separated by blank lines. Backticks, which can
+// only be in strings and comments, are written as \u0060 so they cannot end a code block.
+// With --max-messages, the messages past the limit are left out and the last
+// one says how much is missing.
+func printChat(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	format := chatFormats[options.format]
+	lines, err := layoutText(ctx, documents, pageTheme(options), 0)
+	if err != nil {
+		return err
+	}
+
+	textLines := make([]string, len(lines))
+	for i, line := range lines {
+		var text strings.Builder
+		for _, run := range line {
+			text.WriteString(run.text)
+		}
+		textLines[i] = format.escaper.Replace(text.String())
+	}
+
+	// Room is left in every message for the fences and the note
+	room := format.limit - len(format.fence) - len("\n\n```") - len(format.truncated) - 20
+	messages := chunkLines(textLines, room)
+
+	leftOut := 0
+	if options.maxMessages > 0 && len(messages) > options.maxMessages {
+		for _, message := range messages[options.maxMessages:] {
+			leftOut += len(message)
+		}
+		messages = messages[:options.maxMessages]
+	}
+
+	for i, message := range messages {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, format.fence)
+		fmt.Fprintln(w, strings.Join(message, "\n"))
+		fmt.Fprintln(w, "```")
+	}
+	if leftOut > 0 {
+		fmt.Fprintf(w, format.truncated+"\n", leftOut)
+	}
+	return nil
+}
+
+// chunkLines splits the lines into groups that each have at most limit
+// characters, counting a newline after each line. Lines that are longer than
+// the limit are split on their own.
+func chunkLines(lines []string, limit int) [][]string {
+	chunks := make([][]string, 0)
+	chunk := make([]string, 0)
+	length := 0
+
+	for _, line := range lines {
+		pieces := []string{line}
+		if utf8.RuneCountInString(line) > limit-1 {
+			pieces = splitRunes(line, limit-1)
+		}
+
+		for _, piece := range pieces {
+			pieceLength := utf8.RuneCountInString(piece) + 1
+			if length+pieceLength > limit && len(chunk) > 0 {
+				chunks = append(chunks, chunk)
+				chunk, length = make([]string, 0), 0
+			}
+			chunk = append(chunk, piece)
+			length += pieceLength
+		}
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// splitRunes splits the text into pieces of at most size characters
+func splitRunes(text string, size int) []string {
+	runes := []rune(text)
+	pieces := make([]string, 0, len(runes)/size+1)
+	for start := 0; start < len(runes); start += size {
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		pieces = append(pieces, string(runes[start:end]))
+	}
+	return pieces
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommand is a program that reads or writes the system clipboard,
+// along with its arguments
+type clipboardCommand struct {
+	name      string
+	arguments []string
+}
+
+// clipboardCommands returns the programs that can read (paste) or write
+// (copy) the clipboard on this system, in the order they should be tried.
+// Linux has no single clipboard, so the Wayland tools are tried before the X11
+// ones when a Wayland session is running.
+func clipboardCommands(isCopy bool) []clipboardCommand {
+	switch runtime.GOOS {
+	case "darwin":
+		if isCopy {
+			return []clipboardCommand{{"pbcopy", nil}}
+		}
+		return []clipboardCommand{{"pbpaste", nil}}
+	case "windows":
+		if isCopy {
+			return []clipboardCommand{{"powershell", []string{"-NoProfile", "-Command",
+				"[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())"}}}
+		}
+		return []clipboardCommand{{"powershell", []string{"-NoProfile", "-Command",
+			"[Console]::OutputEncoding = [Text.Encoding]::UTF8; Get-Clipboard -Raw"}}}
+	}
+
+	wayland := []clipboardCommand{{"wl-paste", []string{"--no-newline"}}}
+	x11 := []clipboardCommand{
+		{"xclip", []string{"-selection", "clipboard", "-out"}},
+		{"xsel", []string{"--clipboard", "--output"}},
+	}
+	if isCopy {
+		wayland = []clipboardCommand{{"wl-copy", nil}}
+		x11 = []clipboardCommand{
+			{"xclip", []string{"-selection", "clipboard", "-in"}},
+			{"xsel", []string{"--clipboard", "--input"}},
+		}
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append(wayland, x11...)
+	}
+	return append(x11, wayland...)
+}
+
+// findClipboardCommand returns the first of the clipboard programs that is
+// installed
+func findClipboardCommand(isCopy bool) (*exec.Cmd, error) {
+	names := make([]string, 0)
+	for _, command := range clipboardCommands(isCopy) {
+		if _, err := exec.LookPath(command.name); err == nil {
+			return exec.Command(command.name, command.arguments...), nil
+		}
+		names = append(names, command.name)
+	}
+	return nil, errors.New("no clipboard program found; install one of " + strings.Join(names, ", "))
+}
+
+// readClipboard returns the text on the system clipboard
+func readClipboard() ([]byte, error) {
+	command, err := findClipboardCommand(false)
+	if err != nil {
+		return nil, err
+	}
+	command.Stderr = os.Stderr
+	return command.Output()
+}
+
+// writeClipboard puts the text on the system clipboard
+func writeClipboard(text []byte) error {
+	command, err := findClipboardCommand(true)
+	if err != nil {
+		return err
+	}
+	command.Stdin = bytes.NewReader(text)
+	command.Stderr = os.Stderr
+	return command.Run()
+}
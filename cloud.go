@@ -0,0 +1,162 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cloudARNListSize is how many ARNs a list has to have before
+// --preset=cloud-cli folds it
+const cloudARNListSize = 4
+
+// cloudMaxString is how long an encoded string, such as a base64 policy, can
+// be before --preset=cloud-cli truncates it
+const cloudMaxString = 80
+
+// cloudEncodedPattern matches the text of a string that is base64 or a URL
+// encoded document, which is how cloud CLIs return policies and user data
+var cloudEncodedPattern = regexp.MustCompile(`^"([A-Za-z0-9+/=_-]+|%7B[A-Za-z0-9%._~+-]*)"$`)
+
+// cloudErrorKeys are the keys of values that report errors, in lower case
+var cloudErrorKeys = map[string]bool{
+	"error": true, "errors": true, "errorcode": true, "errormessage": true, "failure": true,
+	"failures": true, "failurereason": true, "statusreason": true, "statusmessage": true,
+}
+
+// cloudStatusKeys are the keys of values that report a status, in lower case
+var cloudStatusKeys = map[string]bool{
+	"status": true, "state": true, "statuscode": true, "health": true, "healthstatus": true,
+	"lifecyclestate": true, "provisioningstate": true, "stackstatus": true,
+}
+
+// cloudFailedPattern matches a status that says something went wrong
+var cloudFailedPattern = regexp.MustCompile(`(?i)fail|error|unhealthy|rollback|stopped|terminated|denied|degraded|impaired`)
+
+// expanderStyle and expanderScript are added to the page header for
+// --preset=cloud-cli. A truncated string shows its start and unfolds when it
+// is clicked.
+const expanderStyle = `.expander { cursor:pointer }
+.expander.collapsed { display:inline-block; max-width:48ch; overflow:hidden; text-overflow:ellipsis; white-space:nowrap; vertical-align:bottom }`
+
+const expanderScript = `document.addEventListener("click", function (event) {
+	var expander = event.target.closest(".expander");
+	if (expander) {
+		expander.classList.toggle("collapsed");
+	}
+});`
+
+// isCloudPreset returns true if the options call for the detectors of
+// --preset=cloud-cli
+func isCloudPreset(options Options) bool {
+	return options.preset == "cloud-cli"
+}
+
+// markCloudValues marks what matters in the response of a cloud CLI and
+// returns how many values it marked. Errors, and statuses that say something
+// failed, are highlighted as alerts and other statuses as changed, and lists
+// of ARNs are annotated with how many there are. With isShortened, for output
+// that cannot be folded or expanded, those lists are left out and encoded
+// strings are truncated.
+func markCloudValues(node *Node, isShortened bool) int {
+	marked := 0
+	for _, m := range node.members {
+		key := strings.ToLower(stringValue(m.key))
+		switch {
+		case cloudErrorKeys[key] && m.value.kind != NodeNull && !isEmptyContainer(m.value) && valueText(m.value) != `""`:
+			m.value.highlight = HighlightAlert
+			marked++
+		case cloudStatusKeys[key] && m.value.kind != NodeObject && m.value.kind != NodeArray:
+			m.value.highlight = HighlightChanged
+			if cloudFailedPattern.MatchString(logText(m.value)) {
+				m.value.highlight = HighlightAlert
+			}
+			marked++
+		}
+		marked += markCloudValues(m.value, isShortened)
+	}
+	for _, element := range node.elements {
+		marked += markCloudValues(element, isShortened)
+	}
+
+	switch {
+	case isARNList(node):
+		node.annotation = strconv.Itoa(len(node.elements)) + " ARNs"
+		if isShortened {
+			node.annotation += " hidden"
+			node.elements = nil
+		}
+		marked++
+	case isShortened && node.kind == NodeString && isEncodedString(rawText(node)):
+		text := stringValue(node)
+		node.tokens = newStringNode(text[:cloudMaxString/2] + "…").tokens
+		node.annotation = strconv.Itoa(len(text)) + " characters"
+		marked++
+	}
+	return marked
+}
+
+// isARNList returns true if the value is an array of at least
+// cloudARNListSize ARNs
+func isARNList(node *Node) bool {
+	if node.kind != NodeArray || len(node.elements) < cloudARNListSize {
+		return false
+	}
+	for _, element := range node.elements {
+		if element.kind != NodeString || !strings.HasPrefix(stringValue(element), "arn:") {
+			return false
+		}
+	}
+	return true
+}
+
+// isEncodedString returns true if the text of the string, with its quotes, is
+// long and base64 or URL encoded
+func isEncodedString(text string) bool {
+	return len(text) > cloudMaxString+2 && cloudEncodedPattern.MatchString(text)
+}
+
+// cloudDecorations returns the decorations that let the encoded strings in the
+// tokens be truncated and expanded, and the opening brackets of the lists of
+// ARNs, which start out folded
+func cloudDecorations(tokenArray []Token) ([]Decoration, map[int]bool) {
+	decorations := make([]Decoration, len(tokenArray))
+	folded := make(map[int]bool)
+
+	spans := findValueSpans(tokenArray)
+	for i, span := range spans {
+		switch tokenArray[span.start].kind {
+		case ArrayOpen:
+			// The elements are the spans after it that are one level deeper
+			count, isARNs := 0, true
+			for _, inner := range spans[i+1:] {
+				if inner.start > span.end {
+					break
+				}
+				if len(inner.path) == len(span.path)+1 {
+					count++
+					isARNs = isARNs && strings.HasPrefix(spanText(tokenArray, inner), `"arn:`)
+				}
+			}
+			if isARNs && count >= cloudARNListSize {
+				folded[span.start] = true
+			}
+		case ObjectOpen:
+		default:
+			if isEncodedString(spanText(tokenArray, span)) {
+				decorations[span.start].before += `<span class="expander collapsed" title="Click to show all of it">`
+				decorations[span.end].after = `</span>` + decorations[span.end].after
+			}
+		}
+	}
+	return decorations, folded
+}
+
+// spanText returns the text of the tokens of a value
+func spanText(tokenArray []Token, span valueSpan) string {
+	var text strings.Builder
+	for _, token := range tokenArray[span.start : span.end+1] {
+		text.WriteString(token.content)
+	}
+	return text.String()
+}
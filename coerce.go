@@ -0,0 +1,43 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// coerceNumberPattern matches strings that are a number as JSON writes them.
+// Numbers with leading zeros, such as zip codes, are not, so they stay text.
+var coerceNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// coerceTypes turns every string in the tree that is only a number into that
+// number, and "true" and "false" into booleans, as exports from CSV often
+// need. It returns a description of each value it changed, with its path.
+func coerceTypes(node *Node, path []string) []string {
+	coerced := make([]string, 0)
+
+	for _, m := range node.members {
+		coerced = append(coerced, coerceTypes(m.value, append(append([]string{}, path...), stringValue(m.key)))...)
+	}
+	for i, element := range node.elements {
+		coerced = append(coerced, coerceTypes(element, append(append([]string{}, path...), strconv.Itoa(i)))...)
+	}
+
+	if node.kind != NodeString {
+		return coerced
+	}
+
+	text := stringValue(node)
+	var value *Node
+	switch {
+	case text == "true" || text == "false":
+		value = newBoolNode(text == "true")
+	case coerceNumberPattern.MatchString(text):
+		value = newNumberNode(text)
+	default:
+		return coerced
+	}
+
+	coerced = append(coerced, formatPointer(path)+" from "+rawText(node)+" to "+text)
+	node.kind, node.tokens = value.kind, value.tokens
+	return coerced
+}
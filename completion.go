@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// programName is the name that completion scripts complete for
+const programName = "json-pretty-printer"
+
+// completionFlag describes a flag for the completion scripts
+type completionFlag struct {
+	name      string
+	usage     string
+	takesFile bool     // Its value is usually a file name
+	isBool    bool     // It takes no value
+	choices   []string // The values it accepts, if they are fixed
+}
+
+// runCompletion prints the completion script for the shell named in the
+// arguments: bash, zsh, fish, or powershell. The scripts are generated from
+// the flag definitions, so they always cover every flag, subcommand, theme,
+// and format.
+func runCompletion(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("completion needs a shell: bash, zsh, fish, or powershell")
+	}
+
+	flags := completionFlags()
+	switch arguments[0] {
+	case "bash":
+		printBashCompletion(os.Stdout, flags)
+	case "zsh":
+		printZshCompletion(os.Stdout, flags)
+	case "fish":
+		printFishCompletion(os.Stdout, flags)
+	case "powershell":
+		printPowerShellCompletion(os.Stdout, flags)
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown shell: "+arguments[0])
+		os.Exit(2)
+	}
+}
+
+// completionFlags returns every flag in the order they are listed in help
+func completionFlags() []completionFlag {
+	flags := flag.NewFlagSet(programName, flag.ContinueOnError)
+	defineFlags(flags, &Options{})
+
+	completions := make([]completionFlag, 0)
+	flags.VisitAll(func(f *flag.Flag) {
+		completion := completionFlag{name: f.Name, usage: f.Usage, choices: flagChoices[f.Name]}
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+			completion.isBool = true
+		} else if _, isString := f.Value.(flag.Getter).Get().(string); isString && completion.choices == nil {
+			completion.takesFile = true
+		}
+		completions = append(completions, completion)
+	})
+	return completions
+}
+
+// printBashCompletion prints a completion script for bash
+func printBashCompletion(w io.Writer, flags []completionFlag) {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "--" + f.name
+	}
+
+	fmt.Fprintln(w, "# bash completion for "+programName)
+	fmt.Fprintln(w, "# Load it with: source <("+programName+" completion bash)")
+	fmt.Fprintln(w, "_json_pretty_printer() {")
+	fmt.Fprintln(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Fprintln(w, "\tlocal prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+	fmt.Fprintln(w, "\tcase \"$prev\" in")
+	for _, f := range flags {
+		switch {
+		case f.choices != nil:
+			fmt.Fprintf(w, "\t--%s|-%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn ;;\n",
+				f.name, f.name, strings.Join(f.choices, " "))
+		case f.takesFile:
+			fmt.Fprintf(w, "\t--%s|-%s)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn ;;\n", f.name, f.name)
+		case !f.isBool:
+			fmt.Fprintf(w, "\t--%s|-%s)\n\t\tCOMPREPLY=()\n\t\treturn ;;\n", f.name, f.name)
+		}
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "\tif [[ \"$cur\" == -* ]]; then")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, "\telif [[ $COMP_CWORD -eq 1 ]]; then")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintln(w, "\telse")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _json_pretty_printer "+programName)
+}
+
+// printZshCompletion prints a completion script for zsh
+func printZshCompletion(w io.Writer, flags []completionFlag) {
+	// escape makes text safe inside the brackets of a single-quoted spec
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace
+
+	fmt.Fprintln(w, "#compdef "+programName)
+	fmt.Fprintln(w, "# Load it by saving it as _"+programName+" in a directory on $fpath")
+	fmt.Fprintln(w, "_arguments \\")
+	for _, f := range flags {
+		switch {
+		case f.isBool:
+			fmt.Fprintf(w, "\t'--%s[%s]' \\\n", f.name, escape(f.usage))
+		case f.choices != nil:
+			fmt.Fprintf(w, "\t'--%s=[%s]:%s:(%s)' \\\n", f.name, escape(f.usage), f.name, strings.Join(f.choices, " "))
+		case f.takesFile:
+			fmt.Fprintf(w, "\t'--%s=[%s]:%s:_files' \\\n", f.name, escape(f.usage), f.name)
+		default:
+			fmt.Fprintf(w, "\t'--%s=[%s]:%s: ' \\\n", f.name, escape(f.usage), f.name)
+		}
+	}
+	fmt.Fprintf(w, "\t'1:command or file:{_alternative \"commands:command:(%s)\" \"files:file:_files\"}' \\\n", strings.Join(commandNames(), " "))
+	fmt.Fprintln(w, "\t'*:file:_files'")
+}
+
+// printFishCompletion prints a completion script for fish
+func printFishCompletion(w io.Writer, flags []completionFlag) {
+	// quote makes text safe inside a single-quoted fish string
+	quote := func(text string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(text) + "'"
+	}
+
+	fmt.Fprintln(w, "# fish completion for "+programName)
+	fmt.Fprintln(w, "# Load it with: "+programName+" completion fish | source")
+	fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -f -a %s\n", programName, quote(strings.Join(commandNames(), " ")))
+	for _, f := range flags {
+		line := fmt.Sprintf("complete -c %s -l %s -d %s", programName, f.name, quote(f.usage))
+		switch {
+		case f.choices != nil:
+			line += " -x -a " + quote(strings.Join(f.choices, " "))
+		case f.takesFile:
+			line += " -r -F"
+		case !f.isBool:
+			line += " -x"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// printPowerShellCompletion prints a completion script for PowerShell
+func printPowerShellCompletion(w io.Writer, flags []completionFlag) {
+	// quote makes text safe inside a single-quoted PowerShell string
+	quote := func(text string) string {
+		return "'" + strings.Replace(text, "'", "''", -1) + "'"
+	}
+
+	fmt.Fprintln(w, "# PowerShell completion for "+programName)
+	fmt.Fprintln(w, "# Load it with: "+programName+" completion powershell | Out-String | Invoke-Expression")
+	fmt.Fprintln(w, "Register-ArgumentCompleter -Native -CommandName "+programName+" -ScriptBlock {")
+	fmt.Fprintln(w, "\tparam($wordToComplete, $commandAst, $cursorPosition)")
+
+	quotedCommands := make([]string, len(commands))
+	for i, command := range commandNames() {
+		quotedCommands[i] = quote(command)
+	}
+	fmt.Fprintln(w, "\t$commands = @("+strings.Join(quotedCommands, ", ")+")")
+
+	fmt.Fprintln(w, "\t$flags = [ordered]@{")
+	for _, f := range flags {
+		fmt.Fprintf(w, "\t\t%s = %s\n", quote("--"+f.name), quote(f.usage))
+	}
+	fmt.Fprintln(w, "\t}")
+
+	fmt.Fprintln(w, "\t$choices = @{")
+	for _, f := range flags {
+		if f.choices != nil {
+			quoted := make([]string, len(f.choices))
+			for i, choice := range f.choices {
+				quoted[i] = quote(choice)
+			}
+			fmt.Fprintf(w, "\t\t%s = @(%s)\n", quote("--"+f.name), strings.Join(quoted, ", "))
+		}
+	}
+	fmt.Fprintln(w, "\t}")
+
+	fmt.Fprintln(w, `	$words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })
+	$previous = if ($wordToComplete) { $words[-2] } else { $words[-1] }
+	if ($choices.Contains($previous)) {
+		$candidates = $choices[$previous]
+	} elseif ($wordToComplete -like '-*') {
+		$candidates = $flags.Keys
+	} elseif ($words.Count -le 2) {
+		$candidates = $commands
+	} else {
+		return
+	}
+	$candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+		$tip = if ($flags.Contains($_)) { $flags[$_] } else { $_ }
+		[System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $tip)
+	}
+}`)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configFileName is the file that sets flags for the files in its directory
+// and the directories below it
+const configFileName = ".jsonpretty.toml"
+
+// configKeys are the flags a configuration file can set. A file that is
+// checked into a project is trusted no more than the files it formats, so it
+// can only choose how they are read and laid out, and not options that change
+// their values, such as --substitute-env and --prune-nulls.
+var configKeys = []string{"allow-comments", "fix-trailing-commas", "sort-keys", "sort-array-by"}
+
+// findConfigFile returns the configuration file nearest to the file, searching
+// its directory and then each directory above it, or "" if there is none
+func findConfigFile(fileName string) string {
+	directory, err := filepath.Abs(filepath.Dir(fileName))
+	if err != nil {
+		return ""
+	}
+
+	for {
+		configFile := filepath.Join(directory, configFileName)
+		if info, err := os.Stat(configFile); err == nil && !info.IsDir() {
+			return configFile
+		}
+
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return ""
+		}
+		directory = parent
+	}
+}
+
+// readConfigFile reads a configuration file and returns its settings as flag
+// arguments. Each key is the name of one of configKeys, such as
+// sort-array-by = "id" or allow-comments = true. Only the part of TOML that
+// flags need is read: keys, strings, numbers, booleans, arrays of strings for
+// flags that can be given more than once, and comments.
+func readConfigFile(configFile string) ([]string, error) {
+	file, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	arguments := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		// fail returns an error that points at the line
+		fail := func(format string, values ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", configFile, lineNumber, fmt.Sprintf(format, values...))
+		}
+
+		equals := strings.Index(line, "=")
+		if equals < 0 {
+			return nil, fail("expected key = value")
+		}
+		key := strings.TrimSpace(line[:equals])
+		if unquoted, err := readTOMLString(key); err == nil {
+			key = unquoted
+		}
+		if !isConfigKey(key) {
+			return nil, fail("unknown setting %s, a configuration file can only set %s", key, strings.Join(configKeys, ", "))
+		}
+
+		values, err := readTOMLValue(strings.TrimSpace(line[equals+1:]))
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+		for _, value := range values {
+			arguments = append(arguments, "--"+key+"="+value)
+		}
+	}
+	return arguments, scanner.Err()
+}
+
+// isConfigKey returns true if a configuration file can set the flag
+func isConfigKey(key string) bool {
+	for _, configKey := range configKeys {
+		if key == configKey {
+			return true
+		}
+	}
+	return false
+}
+
+// readTOMLValue returns the text of a value, or of each string in an array
+func readTOMLValue(text string) ([]string, error) {
+	if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+		values := make([]string, 0)
+		for _, element := range splitTOMLArray(text[1 : len(text)-1]) {
+			value, err := readTOMLString(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+
+	if value, err := readTOMLString(text); err == nil {
+		return []string{value}, nil
+	}
+
+	if text == "true" || text == "false" {
+		return []string{text}, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(text, "_", "", -1), 64); err == nil {
+		return []string{strings.Replace(text, "_", "", -1)}, nil
+	}
+	return nil, fmt.Errorf("%s is not a string, number, boolean, or array of strings", text)
+}
+
+// readTOMLString returns the text of a basic ("...") or literal ('...') string
+func readTOMLString(text string) (string, error) {
+	switch {
+	case len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"':
+		return strconv.Unquote(text)
+	case len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'':
+		return text[1 : len(text)-1], nil
+	}
+	return "", fmt.Errorf("%s is not a string", text)
+}
+
+// splitTOMLArray splits the inside of an array at the commas that are not in
+// strings
+func splitTOMLArray(text string) []string {
+	elements := make([]string, 0)
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch character := text[i]; {
+		case quote != 0 && character == '\\' && quote == '"':
+			i++
+		case quote != 0 && character == quote:
+			quote = 0
+		case quote == 0 && (character == '"' || character == '\''):
+			quote = character
+		case quote == 0 && character == ',':
+			elements = append(elements, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		elements = append(elements, last)
+	}
+	return elements
+}
+
+// stripTOMLComment removes a '#' comment that is not in a string
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch character := line[i]; {
+		case quote != 0 && character == '\\' && quote == '"':
+			i++
+		case quote != 0 && character == quote:
+			quote = 0
+		case quote == 0 && (character == '"' || character == '\''):
+			quote = character
+		case quote == 0 && character == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// configuredOptions returns the options for a file in the batch modes. The
+// nearest configuration file sets the flags first, and the flags given on the
+// command line are read after it, so they win. Options are cached by
+// configuration file.
+func configuredOptions(fileName string, options Options, cache map[string]Options) (Options, error) {
+	configFile := findConfigFile(fileName)
+	if configFile == "" {
+		return options, nil
+	}
+	if cached, ok := cache[configFile]; ok {
+		return cached, nil
+	}
+
+	arguments, err := readConfigFile(configFile)
+	if err != nil {
+		return options, err
+	}
+	configured, _, err := readOptions(append(arguments, options.flagArguments...), flag.ContinueOnError)
+	if err != nil {
+		return options, fmt.Errorf("%s: %v", configFile, err)
+	}
+
+	cache[configFile] = configured
+	return configured, nil
+}
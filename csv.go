@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvNumberPattern matches the fields that are inferred to be numbers: those
+// written as JSON writes them, so that numbers with leading zeros, such as
+// ZIP codes and IDs, stay strings
+var csvNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// csvDocuments returns the document of CSV input, which is an array with an
+// object for each row, with the options that change values applied as they
+// are to JSON. The members are named by the header row, or by --headers if
+// the input has none.
+func csvDocuments(ctx context.Context, input []byte, options Options, report io.Writer) ([][]Token, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(input, []byte("\ufeff"))))
+	reader.Comma = csvDelimiter(options)
+	reader.FieldsPerRecord = -1 // Rows with too few or too many fields are kept
+	reader.ReuseRecord = true
+
+	var headers []string
+	if options.headers != "" {
+		headers = strings.Split(options.headers, ",")
+	}
+
+	root := newArrayNode()
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv: %v", err)
+		}
+		if headers == nil {
+			headers = append([]string(nil), record...)
+			continue
+		}
+		root.elements = append(root.elements, csvRow(record, csvKeys(headers, len(record)), !options.noInferTypes))
+	}
+	fmt.Fprintf(report, "Read %d CSV row(s) of %d column(s)\n", len(root.elements), len(headers))
+
+	if isTreeNeeded(options) {
+		var err error
+		if root, err = transformTree(root, options, report); err != nil {
+			return nil, err
+		}
+	}
+	return [][]Token{nodeTokens(root)}, nil
+}
+
+// csvDelimiter returns the character that separates fields: the one that
+// --delimiter names, or a tab for a .tsv file and a comma otherwise
+func csvDelimiter(options Options) rune {
+	switch {
+	case options.delimiter == "tab":
+		return '\t'
+	case options.delimiter != "":
+		delimiter, _ := utf8.DecodeRuneInString(options.delimiter)
+		return delimiter
+	case strings.EqualFold(filepath.Ext(options.fileName), ".tsv"):
+		return '\t'
+	}
+	return ','
+}
+
+// csvKeys returns the keys of a row with count fields. Headers that are empty
+// or repeated are made unique, and fields past the last header are named by
+// their column, such as column5.
+func csvKeys(headers []string, count int) []string {
+	keys := make([]string, 0, count)
+	seen := make(map[string]bool)
+	for i := 0; i < count || i < len(headers); i++ {
+		key := "column" + strconv.Itoa(i+1)
+		if i < len(headers) && strings.TrimSpace(headers[i]) != "" {
+			key = strings.TrimSpace(headers[i])
+		}
+		for base, suffix := key, 2; seen[key]; suffix++ {
+			key = base + "_" + strconv.Itoa(suffix)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// csvRow returns the object for one row. Columns that the row is missing are
+// null, and with inferTypes, so are empty fields, and fields that look like
+// numbers and booleans are turned into them.
+func csvRow(record []string, keys []string, inferTypes bool) *Node {
+	row := newObjectNode()
+	for i, key := range keys {
+		value := newNullNode()
+		if i < len(record) {
+			value = csvValue(record[i], inferTypes)
+		}
+		row.members = append(row.members, Member{key: newStringNode(key), value: value})
+	}
+	return row
+}
+
+// csvValue returns the value of a field
+func csvValue(field string, inferTypes bool) *Node {
+	if !inferTypes {
+		return newStringNode(field)
+	}
+	switch {
+	case field == "":
+		return newNullNode()
+	case field == "true" || field == "TRUE" || field == "True":
+		return newBoolNode(true)
+	case field == "false" || field == "FALSE" || field == "False":
+		return newBoolNode(false)
+	case csvNumberPattern.MatchString(field):
+		return newNumberNode(field)
+	}
+	return newStringNode(field)
+}
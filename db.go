@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dbCommand is the client program that runs a query for a DSN, and the bytes
+// it separates the fields and records of its output with
+type dbCommand struct {
+	command         *exec.Cmd
+	fieldSeparator  byte
+	recordSeparator byte
+}
+
+// runDB runs a query against a database and renders the document in each row
+// it returns, for JSON and JSONB columns. The DSN is a postgres:// URL or a
+// libpq connection string, which is run with psql, or a SQLite database file
+// or sqlite: path, which is run with sqlite3, so neither needs a driver. The
+// document is the last column of each row, and the columns before it, such
+// as an id, are written in a comment above it. Rows that are not JSON are
+// rendered as a string.
+func runDB(options Options, arguments []string) {
+	if len(arguments) != 2 {
+		panic("db needs a DSN and a query")
+	}
+
+	client, err := findDBCommand(arguments[0], arguments[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	client.command.Stderr = os.Stderr
+	output, err := client.command.Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", client.command.Path, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	documents, err := dbDocuments(ctx, output, client, options)
+	if err != nil {
+		exitOnError(err, options)
+	}
+	fmt.Fprintf(os.Stderr, "%d row(s)\n", len(documents))
+
+	if err := printOutput(ctx, os.Stdout, documents, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+}
+
+// findDBCommand returns the client that runs the query for the DSN, with its
+// output unaligned, with a header, and separated by bytes that cannot be in
+// JSON text
+func findDBCommand(dsn, query string) (dbCommand, error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"), strings.Contains(dsn, "="):
+		return dbCommand{exec.Command("psql", "--no-psqlrc", "--quiet", "--no-align", "--pset=footer=off",
+			"--set=ON_ERROR_STOP=1", "--field-separator-zero", "--record-separator-zero",
+			"--dbname="+dsn, "--command="+query), 0, 0}, nil
+	case strings.HasPrefix(dsn, "sqlite:"), strings.HasPrefix(dsn, "sqlite3:"), strings.HasPrefix(dsn, "file:"):
+		dsn = strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite3:"), "sqlite:")
+		fallthrough
+	case isSQLiteFile(dsn):
+		return dbCommand{exec.Command("sqlite3", "-batch", "-bail", "-header", "-ascii", dsn, query), 0x1f, 0x1e}, nil
+	}
+	return dbCommand{}, errors.New("Unknown DSN: use a postgres:// URL, a connection string such as host=... dbname=..., or a SQLite file")
+}
+
+// isSQLiteFile returns true if the path has the extension of a SQLite
+// database or is a file that starts with the SQLite header
+func isSQLiteFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3", ".db3":
+		return true
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, 16)
+	n, _ := file.Read(header)
+	return string(header[:n]) == "SQLite format 3\x00"
+}
+
+// dbDocuments turns the output of the client into a document for each row,
+// after the header with the names of the columns
+func dbDocuments(ctx context.Context, output []byte, client dbCommand, options Options) ([][]Token, error) {
+	output = bytes.TrimRight(output, "\n")
+	output = bytes.TrimSuffix(output, []byte{client.recordSeparator})
+	if len(output) == 0 {
+		return [][]Token{}, nil
+	}
+
+	records := bytes.Split(output, []byte{client.recordSeparator})
+	columns := strings.Split(string(records[0]), string(client.fieldSeparator))
+	documents := make([][]Token, 0, len(records)-1)
+	for _, record := range records[1:] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fields := bytes.Split(record, []byte{client.fieldSeparator})
+		value := fields[len(fields)-1]
+
+		var document []Token
+		if len(bytes.TrimSpace(value)) == 0 {
+			// Both clients write NULL as nothing
+			document = nodeTokens(newNullNode())
+		} else if _, _, err := checkInput(value, options); err != nil {
+			document = nodeTokens(annotated(newStringNode(string(value)), "not JSON"))
+		} else {
+			documents, err := formatDocuments(ctx, value, options, os.Stderr)
+			if err != nil {
+				return nil, err
+			}
+			for _, tokenArray := range documents {
+				document = append(document, tokenArray...)
+			}
+		}
+
+		if len(fields) > 1 {
+			labels := make([]string, 0, len(fields)-1)
+			for i, field := range fields[:len(fields)-1] {
+				name := ""
+				if i < len(columns) {
+					name = columns[i] + ": "
+				}
+				labels = append(labels, name+strings.Replace(string(field), "*/", "* /", -1))
+			}
+			document = append([]Token{makeToken("/* "+strings.Join(labels, ", ")+" */", Comment)}, document...)
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
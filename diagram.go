@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The diagrams cut values short at diagramMaxValue characters and only draw
+// the first diagramMaxElements elements of an array, so that large documents
+// still give diagrams that can be laid out and read
+const (
+	diagramMaxValue    = 40
+	diagramMaxElements = 20
+)
+
+// diagramNode is one object or array of the document in a diagram, with the
+// scalar values it holds written in it
+type diagramNode struct {
+	id    string
+	lines []string // The first names the container, the rest are its scalars
+}
+
+// diagramEdge goes from a container to an object or array inside it, labeled
+// with its key or index
+type diagramEdge struct {
+	from, to, label string
+}
+
+// printDiagram prints a Graphviz DOT or Mermaid diagram of the structure of
+// the documents, for --format=dot and --format=mermaid. Each object and array
+// is a node that lists its scalar members, and the objects and arrays inside
+// it are joined to it by edges labeled with their keys or indexes.
+func printDiagram(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	var nodes []diagramNode
+	var edges []diagramEdge
+	for _, document := range documents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		root, err := parseTokensContext(ctx, valueTokens(document))
+		if err != nil {
+			return err
+		}
+		nodes, edges = addDiagramNode(root, nodes, edges)
+	}
+
+	if options.format == "mermaid" {
+		fmt.Fprintln(w, "flowchart LR")
+		for _, node := range nodes {
+			fmt.Fprintf(w, "\t%s[\"%s\"]\n", node.id, mermaidText(strings.Join(node.lines, "\n")))
+		}
+		for _, edge := range edges {
+			fmt.Fprintf(w, "\t%s -->|\"%s\"| %s\n", edge.from, mermaidText(edge.label), edge.to)
+		}
+		return nil
+	}
+
+	fmt.Fprintln(w, "digraph json {")
+	fmt.Fprintln(w, "\t"+"rankdir=LR;")
+	fmt.Fprintln(w, "\t"+"node [shape=box, fontname=\"monospace\"];")
+	fmt.Fprintln(w, "\t"+"edge [fontname=\"monospace\"];")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "\t%s [label=\"%s\\l\"];\n", node.id, strings.Join(dotTexts(node.lines), "\\l"))
+	}
+	for _, edge := range edges {
+		fmt.Fprintf(w, "\t%s -> %s [label=\"%s\"];\n", edge.from, edge.to, dotTexts([]string{edge.label})[0])
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// addDiagramNode adds the node of an object or array, and those of the
+// objects and arrays inside it, with the edges between them. A scalar
+// document gets a node of its own.
+func addDiagramNode(node *Node, nodes []diagramNode, edges []diagramEdge) ([]diagramNode, []diagramEdge) {
+	this := diagramNode{id: "n" + strconv.Itoa(len(nodes))}
+	nodes = append(nodes, this)
+	index := len(nodes) - 1
+
+	var lines []string
+	addChild := func(label string, value *Node) {
+		if value.kind != NodeObject && value.kind != NodeArray {
+			lines = append(lines, label+": "+diagramValue(value))
+			return
+		}
+		edges = append(edges, diagramEdge{from: this.id, to: "n" + strconv.Itoa(len(nodes)), label: label})
+		nodes, edges = addDiagramNode(value, nodes, edges)
+	}
+
+	switch node.kind {
+	case NodeObject:
+		lines = append(lines, "{} "+strconv.Itoa(len(node.members))+" member(s)")
+		for _, m := range node.members {
+			addChild(stringValue(m.key), m.value)
+		}
+	case NodeArray:
+		lines = append(lines, "[] "+strconv.Itoa(len(node.elements))+" element(s)")
+		for i, element := range node.elements {
+			if i == diagramMaxElements {
+				lines = append(lines, "... "+strconv.Itoa(len(node.elements)-i)+" more")
+				break
+			}
+			addChild(strconv.Itoa(i), element)
+		}
+	default:
+		lines = append(lines, diagramValue(node))
+	}
+
+	nodes[index].lines = lines
+	return nodes, edges
+}
+
+// diagramValue returns a scalar as JSON writes it, cut short if it is long
+func diagramValue(node *Node) string {
+	text := rawText(node)
+	if runes := []rune(text); len(runes) > diagramMaxValue {
+		text = string(runes[:diagramMaxValue-3]) + "..."
+	}
+	return text
+}
+
+// dotTexts returns the lines with the characters that DOT strings give a
+// meaning to escaped
+func dotTexts(lines []string) []string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(line)
+	}
+	return escaped
+}
+
+// mermaidText returns the text of a Mermaid label, in which quotes, angle
+// brackets, and '#' are written as entities and lines are broken with <br/>
+func mermaidText(text string) string {
+	return strings.NewReplacer("#", "#35;", `"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", "<br/>").Replace(text)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// diffChange is a single difference between two documents, written the same
+// way as a JSON Patch operation: "add", "remove", "replace", or "move" at a path
+type diffChange struct {
+	op        string
+	path      []string
+	value     *Node    // The new value for "add", "replace", and "move", the old for "remove"
+	from      []string // Where "move" takes the value from
+	origin    []string // Where a moved value was in the old document
+	afterPath []string // Where the value is in the new document, if not at path
+}
+
+// newPath returns where the value of an "add", "replace", or "move" is in the
+// new document. Changes to arrays can have a different path, since the ones
+// after them shift the elements.
+func (change diffChange) newPath() []string {
+	if change.afterPath != nil {
+		return change.afterPath
+	}
+	return change.path
+}
+
+// runDiff compares the two JSON files named in the arguments. By default it
+// prints the second file with everything that differs from the first file
+// highlighted; with --output=patch it prints a JSON Patch (RFC 6902) that turns
+// the first file into the second, and with --output=unified it prints a
+// unified diff of the two files as they are formatted. Differences at the paths that --ignore-path
+// matches are left out.
+func runDiff(options Options, arguments []string) {
+	if len(arguments) != 2 {
+		panic("diff needs two filenames")
+	}
+
+	before, err := readJSONFile(arguments[0], options)
+	if err != nil {
+		panic(err)
+	}
+	after, err := readJSONFile(arguments[1], options)
+	if err != nil {
+		panic(err)
+	}
+
+	changes := diffNodes(before, after, []string{}, options)
+	if len(options.ignorePaths) > 0 {
+		changes, err = ignoreChanges(changes, options.ignorePaths)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	switch options.output {
+	case "tree":
+		if err := printOutput(ctx, os.Stdout, [][]Token{nodeTokens(highlightChanges(after, changes))}, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+			exitOnError(err, options)
+		}
+	case "patch":
+		// The patch is printed whole, so the time can only run out before it
+		if err := ctx.Err(); err != nil {
+			exitOnError(err, options)
+		}
+		printText(os.Stdout, nodeTokens(patchFromChanges(changes)))
+	case "unified":
+		if err := printUnified(ctx, os.Stdout, before, after, arguments, options); err != nil {
+			exitOnError(err, options)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown diff output: "+options.output)
+		os.Exit(2)
+	}
+}
+
+// diffNodes returns the changes that turn before into after, in an order in
+// which they can be applied one after another. Members are matched by key and
+// elements as diffArrays does; values of different kinds are replaced whole.
+// Numbers that are within --tolerance of each other are the same.
+func diffNodes(before, after *Node, path []string, options Options) []diffChange {
+	changes := make([]diffChange, 0)
+
+	// childPath returns the path of a member or element of this value
+	childPath := func(segment string) []string {
+		return append(append([]string{}, path...), segment)
+	}
+
+	switch {
+	case before.kind == NodeObject && after.kind == NodeObject:
+		for _, m := range before.members {
+			key := stringValue(m.key)
+			if member(after, key) == nil {
+				changes = append(changes, diffChange{op: "remove", path: childPath(key), value: m.value})
+			}
+		}
+		for _, m := range after.members {
+			key := stringValue(m.key)
+			if previous := member(before, key); previous != nil {
+				changes = append(changes, diffNodes(previous, m.value, childPath(key), options)...)
+			} else {
+				changes = append(changes, diffChange{op: "add", path: childPath(key), value: m.value})
+			}
+		}
+	case before.kind == NodeArray && after.kind == NodeArray:
+		changes = append(changes, diffArrays(before, after, path, options)...)
+	case before.kind == NodeNumber && after.kind == NodeNumber && options.tolerance > 0:
+		if !isWithinTolerance(numberValue(before), numberValue(after), options.tolerance) {
+			changes = append(changes, diffChange{op: "replace", path: path, value: after})
+		}
+	case !nodesEqual(before, after):
+		changes = append(changes, diffChange{op: "replace", path: path, value: after})
+	}
+
+	return changes
+}
+
+// isWithinTolerance returns true if the numbers differ by at most the
+// tolerance, either absolutely or relative to the larger of them, so that it
+// works for numbers near zero as well as large ones
+func isWithinTolerance(a, b, tolerance float64) bool {
+	difference := math.Abs(a - b)
+	return difference <= tolerance || difference <= tolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// ignoreChanges returns the changes that are not at or inside a path that
+// matches one of the patterns. Patterns are JSON Pointers whose segments can
+// have wildcards, such as /items/*/updatedAt, and a '**' segment matches any
+// number of segments, so /**/timestamp matches that key at any depth.
+func ignoreChanges(changes []diffChange, patterns []string) ([]diffChange, error) {
+	patternSegments := make([][]string, len(patterns))
+	for i, pattern := range patterns {
+		segments, err := parsePointer(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("--ignore-path: %v", err)
+		}
+		patternSegments[i] = segments
+	}
+
+	kept := make([]diffChange, 0, len(changes))
+	for _, change := range changes {
+		if !isPathIgnored(change.newPath(), patternSegments) {
+			kept = append(kept, change)
+		}
+	}
+	return kept, nil
+}
+
+// isPathIgnored returns true if the path, or a path that contains it, matches
+// one of the patterns
+func isPathIgnored(path []string, patterns [][]string) bool {
+	for i := 0; i <= len(path); i++ {
+		for _, pattern := range patterns {
+			if matchGlobSegments(pattern, path[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patchFromChanges writes the changes as a JSON Patch document
+func patchFromChanges(changes []diffChange) *Node {
+	patch := newArrayNode()
+
+	for _, change := range changes {
+		operation := newObjectNode()
+		operation.members = append(operation.members,
+			Member{newStringNode("op"), newStringNode(change.op)},
+			Member{newStringNode("path"), newStringNode(formatPointer(change.path))})
+
+		switch change.op {
+		case "move":
+			operation.members = append(operation.members, Member{newStringNode("from"), newStringNode(formatPointer(change.from))})
+		case "add", "replace":
+			value := copyNode(change.value)
+			value.comments = nil
+			operation.members = append(operation.members, Member{newStringNode("value"), value})
+		}
+
+		patch.elements = append(patch.elements, operation)
+	}
+
+	return patch
+}
+
+// highlightChanges returns a copy of the new document in which added values
+// are highlighted as added, replaced values as changed, moved elements as
+// changed with where they moved from, and each removed value leaves a
+// highlighted comment in the container it was removed from
+func highlightChanges(after *Node, changes []diffChange) *Node {
+	view := copyNode(after)
+
+	for _, change := range changes {
+		switch change.op {
+		case "add", "replace":
+			highlight := HighlightAdded
+			if change.op == "replace" {
+				highlight = HighlightChanged
+			}
+
+			path := change.newPath()
+			if node := lookupPath(view, path); node != nil {
+				node.highlight = highlight
+			}
+
+			// A new member has its key highlighted along with its value
+			if change.op == "add" && len(path) > 0 {
+				parent := lookupPath(view, path[:len(path)-1])
+				key := path[len(path)-1]
+				for _, m := range parent.members {
+					if stringValue(m.key) == key {
+						m.key.highlight = highlight
+					}
+				}
+			}
+		case "move":
+			if node := lookupPath(view, change.newPath()); node != nil {
+				node.highlight = HighlightChanged
+				node.annotation = "moved from " + formatPointer(change.origin)
+			}
+		case "remove":
+			if parent := lookupPath(view, change.path[:len(change.path)-1]); parent != nil {
+				markRemoval(parent, change.path, change.value)
+			}
+		}
+	}
+
+	return view
+}
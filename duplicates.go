@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// duplicateMinValues is how many values an object or array has to hold,
+// counting itself, before it is reported as a duplicate, which leaves out
+// small values such as {} and [0, 0] that repeat without meaning anything
+const duplicateMinValues = 4
+
+// duplicateFinder gives every value of a document a number that is the same
+// for values that are equal, as nodesEqual compares them, so that repeated
+// values can be found without comparing each value with every other
+type duplicateFinder struct {
+	ids    map[string]int // The number of each canonical form seen so far
+	values map[*Node]int  // The number of each value
+	counts map[*Node]int  // How many values each value holds, counting itself
+	first  map[int]string // The JSON Pointer where each number is first seen
+}
+
+// findDuplicates reports every object and array of the document that is the
+// same as one before it, as the pointers of the two. Values inside a
+// duplicate are not reported on their own. With isReferenced, each duplicate
+// is emptied and annotated with where it is the same as, to shrink the
+// output.
+func findDuplicates(root *Node, isReferenced bool) []string {
+	finder := &duplicateFinder{
+		ids:    make(map[string]int),
+		values: make(map[*Node]int),
+		counts: make(map[*Node]int),
+		first:  make(map[int]string),
+	}
+	finder.number(root)
+
+	var duplicates []string
+	var walk func(node *Node, path []string)
+	walk = func(node *Node, path []string) {
+		if node.kind != NodeObject && node.kind != NodeArray {
+			return
+		}
+		if finder.counts[node] >= duplicateMinValues {
+			pointer := formatPointer(path)
+			if original, ok := finder.first[finder.values[node]]; ok {
+				duplicates = append(duplicates, pointer+" is the same as "+original)
+				if isReferenced {
+					node.members, node.elements, node.closingComments = nil, nil, nil
+					node.highlight = HighlightSynthetic
+					node.annotation = "same as " + original
+				}
+				return
+			}
+			finder.first[finder.values[node]] = pointer
+		}
+
+		for _, m := range node.members {
+			walk(m.value, append(path[:len(path):len(path)], stringValue(m.key)))
+		}
+		for i, element := range node.elements {
+			walk(element, append(path[:len(path):len(path)], strconv.Itoa(i)))
+		}
+	}
+	walk(root, []string{})
+	return duplicates
+}
+
+// number gives the value and every value inside it their numbers, from the
+// innermost out, and returns the number of the value. Objects are the same
+// whatever order their members are in, and numbers are the same if their
+// values are, as nodesEqual has them.
+func (f *duplicateFinder) number(node *Node) int {
+	var form strings.Builder
+	count := 1
+	switch node.kind {
+	case NodeObject:
+		members := make([]string, len(node.members))
+		for i, m := range node.members {
+			members[i] = quoteString(stringValue(m.key)) + ":" + strconv.Itoa(f.number(m.value))
+			count += f.counts[m.value]
+		}
+		sort.Strings(members)
+		form.WriteString("{" + strings.Join(members, ",") + "}")
+	case NodeArray:
+		form.WriteString("[")
+		for _, element := range node.elements {
+			form.WriteString(strconv.Itoa(f.number(element)) + ",")
+			count += f.counts[element]
+		}
+		form.WriteString("]")
+	case NodeString:
+		form.WriteString(quoteString(stringValue(node)))
+	case NodeNumber:
+		form.WriteString(strconv.FormatFloat(numberValue(node), 'g', -1, 64))
+	default:
+		form.WriteString(rawText(node))
+	}
+
+	id, ok := f.ids[form.String()]
+	if !ok {
+		id = len(f.ids)
+		f.ids[form.String()] = id
+	}
+	f.values[node] = id
+	f.counts[node] = count
+	return id
+}
@@ -0,0 +1,73 @@
+package main
+
+import "strconv"
+
+// kindNames names each kind of value for --explain, in the singular and the
+// plural
+var kindNames = map[int][2]string{
+	NodeObject: {"object", "objects"},
+	NodeArray:  {"array", "arrays"},
+	NodeString: {"string", "strings"},
+	NodeNumber: {"number", "numbers"},
+	NodeBool:   {"boolean", "booleans"},
+	NodeNull:   {"null", "nulls"},
+}
+
+// explainTree gives every value in the tree a short explanation of what it
+// is, such as "object with 3 members" or "array of 5 strings", for people who
+// are new to JSON and for documentation. Explanations are printed after the
+// value like annotations, but in italics so that they stand apart from them.
+func explainTree(node *Node) {
+	node.explanation = explainValue(node)
+
+	for _, m := range node.members {
+		explainTree(m.value)
+	}
+	for _, element := range node.elements {
+		explainTree(element)
+	}
+}
+
+// explainValue returns the explanation of a single value
+func explainValue(node *Node) string {
+	switch node.kind {
+	case NodeObject:
+		switch len(node.members) {
+		case 0:
+			return "empty object"
+		case 1:
+			return "object with 1 member"
+		}
+		return "object with " + strconv.Itoa(len(node.members)) + " members"
+	case NodeArray:
+		if len(node.elements) == 0 {
+			return "empty array"
+		}
+		return "array of " + countValues(node.elements)
+	case NodeBool:
+		return "boolean, true or false"
+	case NodeNull:
+		return "null, meaning no value"
+	}
+	return kindNames[node.kind][0]
+}
+
+// countValues says how many values there are, naming their kind if they are
+// all of the same kind, such as "5 strings" or "2 values"
+func countValues(values []*Node) string {
+	kind := values[0].kind
+	for _, value := range values {
+		if value.kind != kind {
+			kind = 0
+		}
+	}
+
+	singular, plural := "value", "values"
+	if names, ok := kindNames[kind]; ok {
+		singular, plural = names[0], names[1]
+	}
+	if len(values) == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(len(values)) + " " + plural
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// fieldsMaxWidth is the widest a column of --fields is padded to. Longer
+// values are printed whole and push the columns after them along.
+const fieldsMaxWidth = 40
+
+// recordColumns prints NDJSON records as rows of the fields that --fields
+// selects, in columns that only ever get wider, so that the rows stay lined
+// up as records arrive
+type recordColumns struct {
+	fields       []string
+	widths       []int
+	isHeaderDone bool
+}
+
+// newRecordColumns returns the columns for the fields, separated by commas
+func newRecordColumns(fields string) *recordColumns {
+	columns := &recordColumns{}
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			columns.fields = append(columns.fields, field)
+			columns.widths = append(columns.widths, utf8.RuneCountInString(field))
+		}
+	}
+	return columns
+}
+
+// print prints a line of NDJSON as a row, in the color of its level if it is
+// a log record. A field is found by its key or, failing that, as a dotted
+// path such as user.id, and a field the record does not have is shown as -.
+// Blank lines are skipped and lines that are not JSON are printed as they are.
+func (c *recordColumns) print(w io.Writer, line []byte, options Options, isColored bool) {
+	colors := pageTheme(options)
+	if !c.isHeaderDone {
+		c.isHeaderDone = true
+		runs := make([]textRun, len(c.fields))
+		for i, field := range c.fields {
+			runs[i] = textRun{text: field, color: colors.annotation}
+		}
+		c.printRow(w, runs, isColored)
+	}
+
+	if len(strings.TrimSpace(string(line))) == 0 {
+		return
+	}
+	root := parseRecord(line, options)
+	if root == nil {
+		fmt.Fprintln(w, string(line))
+		return
+	}
+	if record, ok := readLogRecord(root, options); ok {
+		colors = levelTheme(colors, record.level)
+	}
+
+	runs := make([]textRun, len(c.fields))
+	for i, field := range c.fields {
+		runs[i] = fieldRun(recordField(root, field), colors, options)
+	}
+	c.printRow(w, runs, isColored)
+}
+
+// printRow prints the runs as a row of the columns, widening them to fit
+func (c *recordColumns) printRow(w io.Writer, runs []textRun, isColored bool) {
+	var row strings.Builder
+	for i, run := range runs {
+		width := utf8.RuneCountInString(run.text)
+		if width > c.widths[i] && c.widths[i] < fieldsMaxWidth {
+			c.widths[i] = min(width, fieldsMaxWidth)
+		}
+
+		row.WriteString(ansiRun(run, isColored))
+		if i < len(runs)-1 {
+			if padding := c.widths[i] - width; padding > 0 {
+				row.WriteString(strings.Repeat(" ", padding))
+			}
+			row.WriteString("  ")
+		}
+	}
+	fmt.Fprintln(w, row.String())
+}
+
+// recordField returns the value of a field of the record, or nil if it does
+// not have one
+func recordField(root *Node, field string) *Node {
+	if value := member(root, field); value != nil {
+		return value
+	}
+	return lookupPath(root, strings.Split(field, "."))
+}
+
+// fieldRun returns the value written on one line in the color of its kind.
+// Strings are written without their quotes, and with their line breaks and
+// tabs escaped so that they stay on the row, and numbers are written in the
+// number format, if there is one.
+func fieldRun(value *Node, colors theme, options Options) textRun {
+	if value == nil {
+		return textRun{text: "-", color: colors.comment}
+	}
+
+	switch value.kind {
+	case NodeString:
+		text := strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(stringValue(value))
+		return textRun{text: text, color: colors.text}
+	case NodeNumber:
+		if options.numberFormat != nil {
+			return textRun{text: options.numberFormat(valueText(value)), color: colors.number}
+		}
+		return textRun{text: valueText(value), color: colors.number}
+	case NodeObject:
+		return textRun{text: valueText(value), color: colors.object}
+	case NodeArray:
+		return textRun{text: valueText(value), color: colors.array}
+	}
+	return textRun{text: valueText(value), color: colors.literal}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// formattedFile is one of the files of a page with several files
+type formattedFile struct {
+	fileName  string
+	size      int
+	raw       []byte // The input, for --embed-raw and --side-by-side
+	documents [][]Token
+	err       error // Why the file could not be formatted, if it could not
+}
+
+// runFormatFiles prints a single HTML page for all of the files, with a table
+// of contents at the top. Files that are not valid are listed with their
+// error instead of stopping the others.
+func runFormatFiles(options Options, fileNames []string) {
+	if options.format != "html" {
+		fmt.Fprintln(os.Stderr, "Several files can only be rendered with --format=html")
+		os.Exit(2)
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+
+	files, err := formatFiles(ctx, fileNames, options)
+	if err != nil {
+		exitOnError(err, options)
+	}
+	if err := printFilesPage(ctx, os.Stdout, files, options); err != nil {
+		exitOnError(err, options)
+	}
+}
+
+// formatFiles reads and formats each of the files. A file that cannot be read
+// or is not valid keeps its error instead of stopping the others.
+func formatFiles(ctx context.Context, fileNames []string, options Options) ([]formattedFile, error) {
+	files := make([]formattedFile, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		file := formattedFile{fileName: fileName}
+
+		jsonFile, err := ioutil.ReadFile(fileName)
+		if err == nil {
+			file.size = len(jsonFile)
+			if options.embedRaw || options.sideBySide {
+				file.raw = jsonFile
+			}
+			err = validateInput(jsonFile, options)
+		}
+		if err == nil {
+			file.documents, err = formatDocuments(ctx, jsonFile, options, ioutil.Discard)
+		}
+
+		// Running out of time stops every file, not only this one
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		file.err = err
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// printFilesPage prints an HTML page with each of the files under its own
+// heading, after a table of contents that links to each file along with its
+// size and whether it is valid
+func printFilesPage(ctx context.Context, w io.Writer, files []formattedFile, options Options) error {
+	colors := pageTheme(options)
+	printHeader(w, options)
+
+	fmt.Fprintln(w, "\t\t"+"<nav style=\"font-family:sans-serif\">")
+	fmt.Fprintln(w, "\t\t\t"+"<ul>")
+	for i, file := range files {
+		badge, background := "valid", colors.added
+		if file.err != nil {
+			badge, background = "invalid", colors.removed
+		}
+		fmt.Fprintf(w, "\t\t\t\t<li><a href=\"#file-%d\">%s</a> %s "+
+			"<span style=\"background-color:%s; border-radius:3px; padding:0 4px; font-size:80%%\">%s</span></li>\n",
+			i+1, html.EscapeString(file.fileName), formatBytes(int64(file.size)), background, badge)
+	}
+	fmt.Fprintln(w, "\t\t\t"+"</ul>")
+	fmt.Fprintln(w, "\t\t"+"</nav>")
+
+	for i, file := range files {
+		fmt.Fprintln(w, "\t\t"+"<h2 id=\"file-"+strconv.Itoa(i+1)+"\" style=\"font-family:sans-serif\">"+
+			html.EscapeString(file.fileName)+"</h2>")
+
+		if file.err != nil {
+			fmt.Fprintln(w, "\t\t"+"<p style=\"font-family:monospace; color:"+colors.object+"\">"+
+				html.EscapeString(file.err.Error())+"</p>")
+			continue
+		}
+
+		for j, document := range file.documents {
+			if j > 0 {
+				printSeparator(w, options)
+			}
+			if err := printDocument(ctx, w, document, options); err != nil {
+				return err
+			}
+		}
+	}
+
+	printFooter(w)
+	return nil
+}
+
+// runFormatDirectory renders each of the files, and every .json file in the
+// directories, to its own page in the output directory, along with an
+// index.html that lists them all with their size, whether they are valid, how
+// deeply they are nested, and a link to their page. Each page keeps the path
+// of its file, with .html added, so a formatted dump of a directory of
+// configuration can be browsed like the original.
+func runFormatDirectory(options Options, arguments []string) {
+	if len(arguments) < 1 {
+		panic("--output-dir needs at least one file or directory")
+	}
+
+	fileNames, err := expandFileNames(arguments, options)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+
+	files, err := formatFiles(ctx, fileNames, options)
+	if err != nil {
+		exitOnError(err, options)
+	}
+
+	pageNames := make([]string, len(files))
+	for i, file := range files {
+		if file.err == nil {
+			pageNames[i] = outputPageName(file.fileName)
+		}
+	}
+	writeFilePages(ctx, files, pageNames, options)
+}
+
+// writeFilePages writes each of the files that could be formatted to its page
+// in the output directory, at the path in pageNames with the same index, and
+// then the index.html that lists them
+func writeFilePages(ctx context.Context, files []formattedFile, pageNames []string, options Options) {
+	pageCount := 0
+	for i, file := range files {
+		if file.err != nil {
+			continue
+		}
+		pageCount++
+
+		pagePath := filepath.Join(options.outputDir, filepath.FromSlash(pageNames[i]))
+		if err := os.MkdirAll(filepath.Dir(pagePath), 0755); err != nil {
+			panic(err)
+		}
+
+		pageOptions := options
+		pageOptions.fileName, pageOptions.rawInput = file.fileName, file.raw
+		if options.assetsDir != "" {
+			pageOptions.assetsPath = assetsPathFrom(filepath.Dir(pagePath), options)
+		}
+
+		var page bytes.Buffer
+		if err := printPageContext(ctx, &page, file.documents, pageOptions); err != nil {
+			exitOnError(err, options)
+		}
+		if err := ioutil.WriteFile(pagePath, page.Bytes(), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	var index bytes.Buffer
+	printIndexPage(&index, files, pageNames, options)
+	if err := ioutil.WriteFile(filepath.Join(options.outputDir, "index.html"), index.Bytes(), 0644); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d page(s) and index.html to %s\n", pageCount, options.outputDir)
+}
+
+// outputPageName returns the path of the page for the file in the output
+// directory, which is its path from the current directory with .html added.
+// Files outside the current directory only keep their name.
+func outputPageName(fileName string) string {
+	relative := filepath.Base(fileName)
+	if absolute, err := filepath.Abs(fileName); err == nil {
+		if working, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(working, absolute); err == nil && !strings.HasPrefix(rel, "..") {
+				relative = rel
+			}
+		}
+	}
+	return filepath.ToSlash(relative) + ".html"
+}
+
+// printIndexPage prints the page that lists the files, linking each valid one
+// to its page
+func printIndexPage(w io.Writer, files []formattedFile, pageNames []string, options Options) {
+	colors := pageTheme(options)
+	printHeader(w, options)
+
+	fmt.Fprintln(w, "\t\t"+"<table style=\"font-family:sans-serif; border-collapse:collapse\">")
+	fmt.Fprintln(w, "\t\t\t"+"<tr><th align=\"left\">File</th><th align=\"right\">Size</th>"+
+		"<th align=\"left\">Valid</th><th align=\"right\">Depth</th></tr>")
+	for i, file := range files {
+		name := html.EscapeString(file.fileName)
+		valid := "yes"
+		depth := ""
+		if file.err != nil {
+			valid = "<span style=\"color:" + colors.object + "\">no, " + html.EscapeString(file.err.Error()) + "</span>"
+		} else {
+			name = "<a href=\"" + html.EscapeString(pageNames[i]) + "\">" + name + "</a>"
+			deepest := 0
+			for _, document := range file.documents {
+				if documentDepth := nestingDepth(document); documentDepth > deepest {
+					deepest = documentDepth
+				}
+			}
+			depth = strconv.Itoa(deepest)
+		}
+		fmt.Fprintf(w, "\t\t\t<tr><td>%s</td><td align=\"right\">%s</td><td>%s</td><td align=\"right\">%s</td></tr>\n",
+			name, formatBytes(int64(file.size)), valid, depth)
+	}
+	fmt.Fprintln(w, "\t\t"+"</table>")
+
+	printFooter(w)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// flattenTree turns the document into a single-level object whose keys are the
+// paths of its scalar values, such as "items.0.name" for the "dot" style or
+// "/items/0/name" for the "pointer" style. In the dot style, dots and
+// backslashes in keys are escaped with a backslash, as is a '/' that starts
+// the path, so that unflattenTree can read the path back. Empty objects and
+// arrays are kept as values so that nothing is lost. A scalar document is
+// left as it is.
+func flattenTree(root *Node, style string) *Node {
+	if root.kind != NodeObject && root.kind != NodeArray {
+		return root
+	}
+
+	flat := newObjectNode()
+	flat.comments = root.comments
+	flat.trailingComments = root.trailingComments
+
+	// addLeaves adds every scalar or empty container under the node
+	var addLeaves func(node *Node, path []string)
+	addLeaves = func(node *Node, path []string) {
+		isEmpty := len(node.members) == 0 && len(node.elements) == 0
+		if (node.kind != NodeObject && node.kind != NodeArray) || (isEmpty && len(path) > 0) {
+			key := formatDottedPath(path)
+			if style == "pointer" {
+				key = formatPointer(path)
+			}
+			leaf := copyNode(node)
+			leaf.comments = nil
+			flat.members = append(flat.members, Member{newStringNode(key), leaf})
+			return
+		}
+
+		for _, m := range node.members {
+			addLeaves(m.value, append(append([]string{}, path...), stringValue(m.key)))
+		}
+		for i, element := range node.elements {
+			addLeaves(element, append(append([]string{}, path...), strconv.Itoa(i)))
+		}
+	}
+
+	addLeaves(root, []string{})
+	return flat
+}
+
+// unflattenTree reverses flattenTree. Keys starting with '/' are read as JSON
+// Pointers and all other keys are split on the dots that are not escaped with
+// a backslash. A path segment made only of
+// digits creates an array, so objects with numeric keys do not survive a round
+// trip. An index can be no larger than the number of keys, which is as long as
+// a flattened array can be, and larger ones are kept as object keys so that a
+// short key cannot ask for a huge array. Documents that are not objects are
+// left as they are.
+func unflattenTree(root *Node) *Node {
+	if root.kind != NodeObject {
+		return root
+	}
+
+	var nested *Node
+	for _, m := range root.members {
+		key := stringValue(m.key)
+
+		path := parseDottedPath(key)
+		if strings.HasPrefix(key, "/") {
+			path, _ = parsePointer(key)
+		}
+
+		value := copyNode(m.value)
+		value.comments = nil
+		nested = setNestedValue(nested, path, value, len(root.members))
+	}
+
+	if nested == nil {
+		nested = newObjectNode()
+	}
+	nested.comments = root.comments
+	nested.trailingComments = root.trailingComments
+	return nested
+}
+
+// formatDottedPath joins the segments of a path with dots, escaping the dots
+// and backslashes in them and a '/' at the start
+func formatDottedPath(path []string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, ".", `\.`)
+	segments := make([]string, len(path))
+	for i, segment := range path {
+		segments[i] = escaper.Replace(segment)
+	}
+	key := strings.Join(segments, ".")
+	if strings.HasPrefix(key, "/") {
+		key = `\` + key
+	}
+	return key
+}
+
+// parseDottedPath splits a path that formatDottedPath wrote into its segments
+func parseDottedPath(key string) []string {
+	var path []string
+	var segment strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key):
+			i++
+			segment.WriteByte(key[i])
+		case key[i] == '.':
+			path = append(path, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(key[i])
+		}
+	}
+	return append(path, segment.String())
+}
+
+// setNestedValue stores the value at the path inside the container, creating
+// the container and any objects or arrays along the path that are missing, and
+// returns the container. Segments are only indexes below maxIndex.
+func setNestedValue(container *Node, path []string, value *Node, maxIndex int) *Node {
+	if len(path) == 0 {
+		return value
+	}
+
+	segment := path[0]
+	index, err := strconv.Atoi(segment)
+	isIndex := err == nil && index >= 0 && index < maxIndex && isAllDigits(segment)
+
+	if container == nil || (container.kind != NodeObject && container.kind != NodeArray) {
+		if isIndex {
+			container = newArrayNode()
+		} else {
+			container = newObjectNode()
+		}
+	}
+
+	if container.kind == NodeArray && isIndex {
+		// Missing elements before the index are filled in with null
+		for len(container.elements) <= index {
+			container.elements = append(container.elements, newNullNode())
+		}
+
+		child := container.elements[index]
+		if child.kind == NodeNull && len(path) > 1 {
+			child = nil
+		}
+		container.elements[index] = setNestedValue(child, path[1:], value, maxIndex)
+		return container
+	}
+
+	if container.kind == NodeArray {
+		// A key that is not an index turns the array into an object
+		converted := newObjectNode()
+		for i, element := range container.elements {
+			converted.members = append(converted.members, Member{newStringNode(strconv.Itoa(i)), element})
+		}
+		container = converted
+	}
+
+	child := member(container, segment)
+	updated := setNestedValue(child, path[1:], value, maxIndex)
+	if child == nil {
+		container.members = append(container.members, Member{newStringNode(segment), updated})
+	} else {
+		addValue(container, []string{segment}, updated)
+	}
+
+	return container
+}
+
+// isAllDigits returns true if the text is made up of only '0' through '9'
+func isAllDigits(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if !isDigit(text[i]) {
+			return false
+		}
+	}
+	return len(text) > 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// runFmt formats the JSON files named in the arguments in place, as plain,
+// indented JSON with the options applied, the way pre-commit and husky pass
+// the files that are staged. Directories are searched for .json files, and
+// files that .jsonprettyignore or --exclude match are skipped. The nearest
+// .jsonpretty.toml can set the flags for each file. With --check the files are
+// only checked and left as they are. Each file that is, or would be, changed
+// is printed, followed by a summary on stderr. The exit status is 1 if any
+// file is not valid, or with --check if any file is not formatted.
+func runFmt(options Options, arguments []string) {
+	if len(arguments) < 1 {
+		panic("fmt needs at least one filename")
+	}
+
+	fileNames, err := expandFileNames(arguments, options)
+	if err != nil {
+		panic(err)
+	}
+
+	configs := make(map[string]Options) // The options of each configuration file
+	var changed, unchanged, invalid int
+	for _, fileName := range fileNames {
+		jsonFile, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", fileName, err)
+			invalid++
+			continue
+		}
+
+		fileOptions, err := configuredOptions(fileName, options, configs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			invalid++
+			continue
+		}
+
+		formatted, err := formatJSON(context.Background(), jsonFile, fileOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", fileName, err)
+			invalid++
+			continue
+		}
+
+		if bytes.Equal(formatted, jsonFile) {
+			unchanged++
+			continue
+		}
+		changed++
+
+		if !options.check {
+			info, err := os.Stat(fileName)
+			if err != nil {
+				panic(err)
+			}
+			if err := ioutil.WriteFile(fileName, formatted, info.Mode().Perm()); err != nil {
+				panic(err)
+			}
+		}
+		fmt.Println(fileName)
+	}
+
+	if options.check {
+		fmt.Fprintf(os.Stderr, "%d file(s) would be reformatted, %d already formatted, %d invalid\n",
+			changed, unchanged, invalid)
+	} else {
+		fmt.Fprintf(os.Stderr, "%d file(s) reformatted, %d already formatted, %d invalid\n",
+			changed, unchanged, invalid)
+	}
+
+	if invalid > 0 || (options.check && changed > 0) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import "strconv"
+
+// Decoration holds extra HTML that is printed around a single token, inside
+// the white space that comes before and after it. Decorations let features
+// such as folding wrap a whole value in markup while the tokens themselves are
+// still styled one at a time.
+type Decoration struct {
+	before string // Printed after the white space before the token
+	after  string // Printed before the white space after the token
+}
+
+// foldStyle and foldScript are added to the page header when containers can
+// be folded. Clicking an opening bracket folds its container into a summary
+// such as '{…} 14 keys' and clicking it again unfolds it.
+const foldStyle = `.fold-toggle { cursor:pointer }
+.fold-ellipsis, .fold-size { display:none; color:#9A9A9A }
+.folded > .fold-body { display:none }
+.folded > .fold-ellipsis, .folded > .fold-size { display:inline }`
+
+const foldScript = `document.addEventListener("click", function (event) {
+	var toggle = event.target.closest(".fold-toggle");
+	if (toggle) {
+		toggle.parentNode.classList.toggle("folded");
+	}
+});`
+
+// foldDecorations returns the decorations that make every object and array in
+// the tokens foldable. The size of each container is counted up front so that
+// the folded summary can show it. Containers nested at least foldedDepth levels
+// deep start out folded, where the top-level value is at depth 0; a
+// foldedDepth of 0 leaves everything unfolded. The containers whose opening
+// brackets are in folded start out folded as well.
+func foldDecorations(tokenArray []Token, foldedDepth int, folded map[int]bool) []Decoration {
+	decorations := make([]Decoration, len(tokenArray))
+
+	// Find how deeply each container is nested
+	depths := make(map[int]int)
+	depth := 0
+	for i, token := range tokenArray {
+		switch token.kind {
+		case ObjectOpen, ArrayOpen:
+			depths[i] = depth
+			depth++
+		case ObjectClose, ArrayClose:
+			depth--
+		}
+	}
+
+	for open, close := range matchBrackets(tokenArray) {
+		size := containerSize(tokenArray[open+1 : close])
+
+		summary := strconv.Itoa(size) + " items"
+		if tokenArray[open].kind == ObjectOpen {
+			summary = strconv.Itoa(size) + " keys"
+		}
+
+		foldClass := "fold"
+		if (foldedDepth > 0 && depths[open] >= foldedDepth) || folded[open] {
+			foldClass = "fold folded"
+		}
+
+		decorations[open].before += `<span class="` + foldClass + `"><span class="fold-toggle">`
+		decorations[open].after += `</span><span class="fold-body">`
+		decorations[close].before += `</span><span class="fold-ellipsis">…</span>`
+		decorations[close].after += `<span class="fold-size"> ` + summary + `</span></span>`
+	}
+
+	return decorations
+}
+
+// matchBrackets returns the index of the closing bracket for the index of each
+// opening bracket in the tokens. Brackets that are never closed are left out.
+func matchBrackets(tokenArray []Token) map[int]int {
+	matches := make(map[int]int)
+	openings := make([]int, 0)
+
+	for i, token := range tokenArray {
+		switch token.kind {
+		case ObjectOpen, ArrayOpen:
+			openings = append(openings, i)
+		case ObjectClose, ArrayClose:
+			if len(openings) > 0 {
+				matches[openings[len(openings)-1]] = i
+				openings = openings[:len(openings)-1]
+			}
+		}
+	}
+
+	return matches
+}
+
+// containerSize counts the members or elements directly inside a container,
+// given the tokens between its brackets
+func containerSize(tokenArray []Token) int {
+	depth := 0
+	size := 0
+	isEmpty := true
+
+	for _, token := range tokenArray {
+		switch token.kind {
+		case Comment, Annotation:
+			continue
+		case ObjectOpen, ArrayOpen:
+			depth++
+		case ObjectClose, ArrayClose:
+			depth--
+		case DelimiterMember:
+			if depth == 0 {
+				size++
+			}
+		}
+		isEmpty = false
+	}
+
+	if isEmpty {
+		return 0
+	}
+	return size + 1
+}
+
+// wrapDecorations combines two sets of decorations for the same tokens so that
+// the outer markup encloses the inner markup
+func wrapDecorations(outer, inner []Decoration) []Decoration {
+	decorations := make([]Decoration, len(inner))
+	for i := range inner {
+		decorations[i].before = outer[i].before + inner[i].before
+		decorations[i].after = inner[i].after + outer[i].after
+	}
+	return decorations
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// followInterval is how often --follow checks for more of the file after it
+// has read to the end
+const followInterval = 250 * time.Millisecond
+
+// runFollow keeps reading the file named in the arguments as it grows, as
+// tail -f does, and prints each line of NDJSON as soon as it is complete, for
+// a terminal as with --format=ansi. It starts from the beginning of the file,
+// and a file that gets shorter, such as a log that was rotated, is read again
+// from its start. A named pipe is read as its writers write to it, and
+// standard input, given as -, until it ends. With --fields, each record is a
+// row of only those fields.
+func runFollow(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("--follow needs one file, or - for standard input")
+	}
+
+	isColored := isColorEnabled(options.color, os.Stdout)
+	err := followLines(arguments[0], recordPrinter(options, isColored))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// recordPrinter returns what prints each line of NDJSON: a row of the fields
+// with --fields, and otherwise the whole record
+func recordPrinter(options Options, isColored bool) func(line []byte) {
+	if options.fields != "" {
+		columns := newRecordColumns(options.fields)
+		return func(line []byte) {
+			columns.print(os.Stdout, line, options, isColored)
+		}
+	}
+	return func(line []byte) {
+		printRecord(os.Stdout, line, options, isColored)
+	}
+}
+
+// followLines calls handle with each line of the file, without its line
+// break, once the whole line has been written. It only returns at the end of
+// standard input or on an error.
+func followLines(fileName string, handle func(line []byte)) error {
+	file := os.Stdin
+	if fileName != "-" {
+		var err error
+		file, err = os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	reader := bufio.NewReader(file)
+	var partial []byte
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		partial = append(partial, chunk...)
+		if err == nil {
+			handle(bytes.TrimRight(partial, "\r\n"))
+			partial = nil
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+
+		if file == os.Stdin {
+			if len(partial) > 0 {
+				handle(partial)
+			}
+			return nil
+		}
+		if isTruncated(file) {
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			reader.Reset(file)
+			partial = nil
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// isTruncated returns true if the file is now shorter than what was read of
+// it. Pipes cannot be truncated.
+func isTruncated(file *os.File) bool {
+	position, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode().IsRegular() && info.Size() < position
+}
+
+// printRecord prints a line of NDJSON formatted for a terminal, in the color
+// of its level if it is a log record. Blank lines are skipped, and a line that
+// is not valid JSON, such as a message that was not written as a structured
+// log, is printed as it is. What the options changed is not reported, since it
+// would be reported again for every line.
+func printRecord(w io.Writer, line []byte, options Options, isColored bool) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+
+	root := parseRecord(line, options)
+	if root == nil {
+		fmt.Fprintln(w, string(line))
+		return
+	}
+	if record, ok := readLogRecord(root, options); ok {
+		options.colors = levelTheme(pageTheme(options), record.level)
+	}
+	printANSI(context.Background(), w, [][]Token{nodeTokens(root)}, options, isColored)
+}
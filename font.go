@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bitmapFont is a monospace font of pixels, which images are drawn in
+type bitmapFont struct {
+	width, height int               // The size of each character cell in pixels
+	glyphs        map[rune][]uint64 // The rows of each character, where bit x is column x
+}
+
+// glyph returns the rows of the character, or of '?' if the font does not
+// have it. Spaces that are missing are left blank.
+func (font bitmapFont) glyph(character rune) []uint64 {
+	if rows, ok := font.glyphs[character]; ok {
+		return rows
+	}
+	if character == ' ' {
+		return nil
+	}
+	return font.glyphs['?']
+}
+
+// builtinGlyphs are the printable ASCII characters, from space to '~', of the
+// font that is used when --font is not given. Each is 5 pixels wide, with the
+// top row first and the leftmost pixel in the highest bit. Capitals sit on the
+// first 7 rows and the last 2 are for descenders.
+var builtinGlyphs = [95][9]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // space
+	{0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04, 0x00, 0x00}, // !
+	{0x0A, 0x0A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // "
+	{0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A, 0x00, 0x00}, // #
+	{0x04, 0x0F, 0x14, 0x0E, 0x05, 0x1E, 0x04, 0x00, 0x00}, // $
+	{0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03, 0x00, 0x00}, // %
+	{0x0C, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0D, 0x00, 0x00}, // &
+	{0x04, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // '
+	{0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02, 0x00, 0x00}, // (
+	{0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08, 0x00, 0x00}, // )
+	{0x00, 0x04, 0x15, 0x0E, 0x15, 0x04, 0x00, 0x00, 0x00}, // *
+	{0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00, 0x00, 0x00}, // +
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08, 0x00}, // ,
+	{0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x00}, // -
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C, 0x00, 0x00}, // .
+	{0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00, 0x00, 0x00}, // /
+	{0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E, 0x00, 0x00}, // 0
+	{0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E, 0x00, 0x00}, // 1
+	{0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F, 0x00, 0x00}, // 2
+	{0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E, 0x00, 0x00}, // 3
+	{0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02, 0x00, 0x00}, // 4
+	{0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E, 0x00, 0x00}, // 5
+	{0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E, 0x00, 0x00}, // 6
+	{0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08, 0x00, 0x00}, // 7
+	{0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E, 0x00, 0x00}, // 8
+	{0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C, 0x00, 0x00}, // 9
+	{0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00, 0x00, 0x00}, // :
+	{0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x04, 0x08, 0x00, 0x00}, // ;
+	{0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02, 0x00, 0x00}, // <
+	{0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00, 0x00, 0x00}, // =
+	{0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08, 0x00, 0x00}, // >
+	{0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04, 0x00, 0x00}, // ?
+	{0x0E, 0x11, 0x01, 0x0D, 0x15, 0x15, 0x0E, 0x00, 0x00}, // @
+	{0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11, 0x00, 0x00}, // A
+	{0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E, 0x00, 0x00}, // B
+	{0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E, 0x00, 0x00}, // C
+	{0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C, 0x00, 0x00}, // D
+	{0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F, 0x00, 0x00}, // E
+	{0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10, 0x00, 0x00}, // F
+	{0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F, 0x00, 0x00}, // G
+	{0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11, 0x00, 0x00}, // H
+	{0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E, 0x00, 0x00}, // I
+	{0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C, 0x00, 0x00}, // J
+	{0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11, 0x00, 0x00}, // K
+	{0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F, 0x00, 0x00}, // L
+	{0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11, 0x00, 0x00}, // M
+	{0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11, 0x00, 0x00}, // N
+	{0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E, 0x00, 0x00}, // O
+	{0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10, 0x00, 0x00}, // P
+	{0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D, 0x00, 0x00}, // Q
+	{0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11, 0x00, 0x00}, // R
+	{0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E, 0x00, 0x00}, // S
+	{0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x00}, // T
+	{0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E, 0x00, 0x00}, // U
+	{0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04, 0x00, 0x00}, // V
+	{0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A, 0x00, 0x00}, // W
+	{0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11, 0x00, 0x00}, // X
+	{0x11, 0x11, 0x0A, 0x04, 0x04, 0x04, 0x04, 0x00, 0x00}, // Y
+	{0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F, 0x00, 0x00}, // Z
+	{0x0E, 0x08, 0x08, 0x08, 0x08, 0x08, 0x0E, 0x00, 0x00}, // [
+	{0x00, 0x10, 0x08, 0x04, 0x02, 0x01, 0x00, 0x00, 0x00}, // \
+	{0x0E, 0x02, 0x02, 0x02, 0x02, 0x02, 0x0E, 0x00, 0x00}, // ]
+	{0x04, 0x0A, 0x11, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // ^
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F, 0x00}, // _
+	{0x08, 0x04, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // `
+	{0x00, 0x00, 0x0E, 0x01, 0x0F, 0x11, 0x0F, 0x00, 0x00}, // a
+	{0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x1E, 0x00, 0x00}, // b
+	{0x00, 0x00, 0x0E, 0x10, 0x10, 0x11, 0x0E, 0x00, 0x00}, // c
+	{0x01, 0x01, 0x0D, 0x13, 0x11, 0x11, 0x0F, 0x00, 0x00}, // d
+	{0x00, 0x00, 0x0E, 0x11, 0x1F, 0x10, 0x0E, 0x00, 0x00}, // e
+	{0x06, 0x09, 0x08, 0x1C, 0x08, 0x08, 0x08, 0x00, 0x00}, // f
+	{0x00, 0x00, 0x0F, 0x11, 0x11, 0x0F, 0x01, 0x01, 0x0E}, // g
+	{0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x11, 0x00, 0x00}, // h
+	{0x04, 0x00, 0x0C, 0x04, 0x04, 0x04, 0x0E, 0x00, 0x00}, // i
+	{0x02, 0x00, 0x06, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C}, // j
+	{0x10, 0x10, 0x12, 0x14, 0x18, 0x14, 0x12, 0x00, 0x00}, // k
+	{0x0C, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E, 0x00, 0x00}, // l
+	{0x00, 0x00, 0x1A, 0x15, 0x15, 0x11, 0x11, 0x00, 0x00}, // m
+	{0x00, 0x00, 0x16, 0x19, 0x11, 0x11, 0x11, 0x00, 0x00}, // n
+	{0x00, 0x00, 0x0E, 0x11, 0x11, 0x11, 0x0E, 0x00, 0x00}, // o
+	{0x00, 0x00, 0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10}, // p
+	{0x00, 0x00, 0x0D, 0x13, 0x11, 0x0F, 0x01, 0x01, 0x01}, // q
+	{0x00, 0x00, 0x16, 0x19, 0x10, 0x10, 0x10, 0x00, 0x00}, // r
+	{0x00, 0x00, 0x0E, 0x10, 0x0E, 0x01, 0x1E, 0x00, 0x00}, // s
+	{0x08, 0x08, 0x1C, 0x08, 0x08, 0x09, 0x06, 0x00, 0x00}, // t
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x13, 0x0D, 0x00, 0x00}, // u
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x0A, 0x04, 0x00, 0x00}, // v
+	{0x00, 0x00, 0x11, 0x11, 0x15, 0x15, 0x0A, 0x00, 0x00}, // w
+	{0x00, 0x00, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x00, 0x00}, // x
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x0F, 0x01, 0x01, 0x0E}, // y
+	{0x00, 0x00, 0x1F, 0x02, 0x04, 0x08, 0x1F, 0x00, 0x00}, // z
+	{0x02, 0x04, 0x04, 0x08, 0x04, 0x04, 0x02, 0x00, 0x00}, // {
+	{0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x00}, // |
+	{0x08, 0x04, 0x04, 0x02, 0x04, 0x04, 0x08, 0x00, 0x00}, // }
+	{0x00, 0x00, 0x08, 0x15, 0x02, 0x00, 0x00, 0x00, 0x00}, // ~
+}
+
+// builtinFont returns the font of builtinGlyphs, with a pixel between
+// characters and between lines
+func builtinFont() bitmapFont {
+	font := bitmapFont{width: 6, height: 11, glyphs: make(map[rune][]uint64)}
+	for i, glyph := range builtinGlyphs {
+		rows := make([]uint64, font.height)
+		for row, bits := range glyph {
+			for column := 0; column < 5; column++ {
+				if bits&(1<<uint(4-column)) != 0 {
+					rows[row+1] |= 1 << uint(column)
+				}
+			}
+		}
+		font.glyphs[rune(' '+i)] = rows
+	}
+	return font
+}
+
+// readBDFFont reads a monospace font in the Glyph Bitmap Distribution Format,
+// which most bitmap fonts for terminals, such as Terminus and GNU Unifont, are
+// available in
+func readBDFFont(fileName string) (bitmapFont, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return bitmapFont{}, err
+	}
+	defer file.Close()
+
+	font := bitmapFont{glyphs: make(map[rune][]uint64)}
+	var boundingX, ascent, descent int
+	hasAscent := false
+
+	// The character being read
+	encoding := -1
+	var glyphWidth, glyphHeight, glyphX, glyphY int
+	var bitmap []string
+	isBitmap := false
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		// numbers reads the numbers after the keyword
+		numbers := func(count int) ([]int, error) {
+			if len(fields) < count+1 {
+				return nil, fmt.Errorf("%s:%d: %s needs %d numbers", fileName, lineNumber, fields[0], count)
+			}
+			values := make([]int, count)
+			for i := range values {
+				value, err := strconv.Atoi(fields[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %v", fileName, lineNumber, err)
+				}
+				values[i] = value
+			}
+			return values, nil
+		}
+
+		if isBitmap && fields[0] != "ENDCHAR" {
+			bitmap = append(bitmap, fields[0])
+			continue
+		}
+
+		switch fields[0] {
+		case "FONTBOUNDINGBOX":
+			values, err := numbers(4)
+			if err != nil {
+				return bitmapFont{}, err
+			}
+			font.width, boundingX = values[0], values[2]
+			if !hasAscent {
+				ascent, descent = values[1]+values[3], -values[3]
+			}
+		case "FONT_ASCENT", "FONT_DESCENT":
+			values, err := numbers(1)
+			if err != nil {
+				return bitmapFont{}, err
+			}
+			if fields[0] == "FONT_ASCENT" {
+				ascent = values[0]
+			} else {
+				descent = values[0]
+			}
+			hasAscent = true
+		case "ENCODING":
+			values, err := numbers(1)
+			if err != nil {
+				return bitmapFont{}, err
+			}
+			encoding = values[0]
+		case "BBX":
+			values, err := numbers(4)
+			if err != nil {
+				return bitmapFont{}, err
+			}
+			glyphWidth, glyphHeight, glyphX, glyphY = values[0], values[1], values[2], values[3]
+		case "BITMAP":
+			isBitmap = true
+			bitmap = bitmap[:0]
+		case "ENDCHAR":
+			isBitmap = false
+			if encoding < 0 {
+				continue
+			}
+
+			// Each row of the bitmap is placed in the character cell by the
+			// glyph's offset from the baseline and from the left of the font
+			rows := make([]uint64, ascent+descent)
+			for row, text := range bitmap {
+				bits, err := strconv.ParseUint(text, 16, 64)
+				if err != nil || row >= glyphHeight {
+					return bitmapFont{}, fmt.Errorf("%s:%d: bad bitmap row %s", fileName, lineNumber, text)
+				}
+				y := ascent - glyphY - glyphHeight + row
+				if y < 0 || y >= len(rows) {
+					continue
+				}
+				for column := 0; column < glyphWidth; column++ {
+					x := glyphX - boundingX + column
+					if bits&(1<<uint(len(text)*4-1-column)) != 0 && x >= 0 && x < font.width {
+						rows[y] |= 1 << uint(x)
+					}
+				}
+			}
+			font.glyphs[rune(encoding)] = rows
+			encoding = -1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return bitmapFont{}, err
+	}
+
+	font.height = ascent + descent
+	if font.width <= 0 || font.width > 64 || font.height <= 0 {
+		return bitmapFont{}, fmt.Errorf("%s: the font must have a FONTBOUNDINGBOX of up to 64 pixels wide", fileName)
+	}
+	return font, nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// geoSummarySize is the fewest positions that a geometry has before its
+// coordinates are annotated with how many there are
+const geoSummarySize = 100
+
+// The map preview is at most this many pixels wide and tall, and draws at most
+// geoMaxLinePoints points of each line or ring, skipping evenly between them
+const (
+	geoPreviewWidth  = 360
+	geoPreviewHeight = 240
+	geoPreviewMargin = 8
+	geoMaxLinePoints = 1000
+)
+
+// geoJSONTypes are the types of GeoJSON objects (RFC 7946), and the member
+// that each one needs to have
+var geoJSONTypes = map[string]string{
+	"Point":              "coordinates",
+	"MultiPoint":         "coordinates",
+	"LineString":         "coordinates",
+	"MultiLineString":    "coordinates",
+	"Polygon":            "coordinates",
+	"MultiPolygon":       "coordinates",
+	"GeometryCollection": "geometries",
+	"Feature":            "geometry",
+	"FeatureCollection":  "features",
+}
+
+// geoShape is one thing the map preview draws: a point, a line, or a polygon
+// with its holes
+type geoShape struct {
+	kind  string // "point", "line", or "polygon"
+	lines [][][2]float64
+}
+
+// isGeoJSON returns true if the node is a GeoJSON object
+func isGeoJSON(node *Node) bool {
+	if node == nil || node.kind != NodeObject {
+		return false
+	}
+	required, ok := geoJSONTypes[geoType(node)]
+	return ok && member(node, required) != nil
+}
+
+// geoType returns the type of a GeoJSON object, or "" if its "type" member is
+// missing or not a string
+func geoType(node *Node) string {
+	if value := member(node, "type"); value != nil && value.kind == NodeString {
+		return stringValue(value)
+	}
+	return ""
+}
+
+// summarizeGeoJSON annotates the coordinates of each large geometry in the
+// document with how many positions they have, and returns how many features
+// and positions the whole document has
+func summarizeGeoJSON(node *Node) (features, positions int) {
+	if !isGeoJSON(node) {
+		return 0, 0
+	}
+
+	switch geoType(node) {
+	case "FeatureCollection":
+		for _, feature := range member(node, "features").elements {
+			f, p := summarizeGeoJSON(feature)
+			features, positions = features+f, positions+p
+		}
+		return features, positions
+	case "Feature":
+		_, positions = summarizeGeoJSON(member(node, "geometry"))
+		return 1, positions
+	case "GeometryCollection":
+		for _, geometry := range member(node, "geometries").elements {
+			_, p := summarizeGeoJSON(geometry)
+			positions += p
+		}
+		return 0, positions
+	}
+
+	coordinates := member(node, "coordinates")
+	positions = countPositions(coordinates)
+	if positions >= geoSummarySize {
+		annotated(coordinates, strconv.Itoa(positions)+" coordinates")
+	}
+	return 0, positions
+}
+
+// countPositions returns how many positions are nested in coordinates, where
+// a position is an array of numbers
+func countPositions(node *Node) int {
+	if node.kind != NodeArray {
+		return 0
+	}
+	if len(node.elements) > 0 && node.elements[0].kind == NodeNumber {
+		return 1
+	}
+	count := 0
+	for _, element := range node.elements {
+		count += countPositions(element)
+	}
+	return count
+}
+
+// geoShapes returns the shapes of every geometry in the GeoJSON object
+func geoShapes(node *Node) []geoShape {
+	if !isGeoJSON(node) {
+		return nil
+	}
+
+	var shapes []geoShape
+	coordinates := member(node, "coordinates")
+	switch geoType(node) {
+	case "FeatureCollection":
+		for _, feature := range member(node, "features").elements {
+			shapes = append(shapes, geoShapes(feature)...)
+		}
+	case "Feature":
+		shapes = geoShapes(member(node, "geometry"))
+	case "GeometryCollection":
+		for _, geometry := range member(node, "geometries").elements {
+			shapes = append(shapes, geoShapes(geometry)...)
+		}
+	case "Point":
+		if position, ok := geoPosition(coordinates); ok {
+			shapes = append(shapes, geoShape{kind: "point", lines: [][][2]float64{{position}}})
+		}
+	case "MultiPoint":
+		shapes = append(shapes, geoShape{kind: "point", lines: [][][2]float64{geoLine(coordinates)}})
+	case "LineString":
+		shapes = append(shapes, geoShape{kind: "line", lines: [][][2]float64{geoLine(coordinates)}})
+	case "MultiLineString", "Polygon":
+		kind := "line"
+		if geoType(node) == "Polygon" {
+			kind = "polygon"
+		}
+		shape := geoShape{kind: kind}
+		for _, line := range coordinates.elements {
+			shape.lines = append(shape.lines, geoLine(line))
+		}
+		shapes = append(shapes, shape)
+	case "MultiPolygon":
+		for _, polygon := range coordinates.elements {
+			shape := geoShape{kind: "polygon"}
+			for _, ring := range polygon.elements {
+				shape.lines = append(shape.lines, geoLine(ring))
+			}
+			shapes = append(shapes, shape)
+		}
+	}
+	return shapes
+}
+
+// geoLine returns the longitude and latitude of each position in an array of
+// them, leaving out the ones that are not positions
+func geoLine(node *Node) [][2]float64 {
+	if node == nil || node.kind != NodeArray {
+		return nil
+	}
+	var line [][2]float64
+	for _, element := range node.elements {
+		if position, ok := geoPosition(element); ok {
+			line = append(line, position)
+		}
+	}
+	return line
+}
+
+// geoPosition returns the longitude and latitude of a position, or false if
+// the node is not one
+func geoPosition(node *Node) ([2]float64, bool) {
+	if node == nil || node.kind != NodeArray || len(node.elements) < 2 ||
+		node.elements[0].kind != NodeNumber || node.elements[1].kind != NodeNumber {
+		return [2]float64{}, false
+	}
+	return [2]float64{numberValue(node.elements[0]), numberValue(node.elements[1])}, true
+}
+
+// printGeoPreview prints a small map of the shapes of a GeoJSON document as an
+// SVG. Longitudes are scaled by the cosine of the middle latitude so that
+// shapes away from the equator are not stretched.
+func printGeoPreview(w io.Writer, root *Node, colors theme) {
+	shapes := geoShapes(root)
+	west, south, east, north := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, shape := range shapes {
+		for _, line := range shape.lines {
+			for _, position := range line {
+				west, east = math.Min(west, position[0]), math.Max(east, position[0])
+				south, north = math.Min(south, position[1]), math.Max(north, position[1])
+			}
+		}
+	}
+	if math.IsInf(west, 0) {
+		return
+	}
+
+	// A single point, or a line straight along a meridian or parallel, still
+	// gets some room around it
+	if east-west < 1e-3 {
+		west, east = west-0.01, east+0.01
+	}
+	if north-south < 1e-3 {
+		south, north = south-0.01, north+0.01
+	}
+	aspect := math.Cos((south + north) / 2 * math.Pi / 180)
+	spanX := (east - west) * aspect
+	spanY := north - south
+	scale := math.Min(float64(geoPreviewWidth-2*geoPreviewMargin)/spanX, float64(geoPreviewHeight-2*geoPreviewMargin)/spanY)
+	width := math.Ceil(spanX*scale) + 2*geoPreviewMargin
+	height := math.Ceil(spanY*scale) + 2*geoPreviewMargin
+	project := func(position [2]float64) (x, y string) {
+		x = strconv.FormatFloat(geoPreviewMargin+(position[0]-west)*aspect*scale, 'f', 1, 64)
+		y = strconv.FormatFloat(geoPreviewMargin+(north-position[1])*scale, 'f', 1, 64)
+		return x, y
+	}
+
+	fmt.Fprintf(w, "\t\t"+"<svg width=\"%g\" height=\"%g\" style=\"display:block; margin-bottom:8px; border:1px solid %s\">\n", width, height, colors.separator)
+	for _, shape := range shapes {
+		switch shape.kind {
+		case "point":
+			for _, position := range shape.lines[0] {
+				x, y := project(position)
+				fmt.Fprintf(w, "\t\t\t"+"<circle cx=\"%s\" cy=\"%s\" r=\"3\" fill=\"%s\"/>\n", x, y, colors.literal)
+			}
+		case "line":
+			for _, line := range shape.lines {
+				fmt.Fprintf(w, "\t\t\t"+"<polyline points=\"%s\" fill=\"none\" stroke=\"%s\"/>\n", geoPoints(line, project), colors.number)
+			}
+		case "polygon":
+			var path strings.Builder
+			for _, ring := range shape.lines {
+				if len(ring) > 0 {
+					path.WriteString("M" + geoPoints(ring, project) + "Z")
+				}
+			}
+			fmt.Fprintf(w, "\t\t\t"+"<path d=\"%s\" fill=\"%s\" fill-opacity=\"0.25\" fill-rule=\"evenodd\" stroke=\"%s\"/>\n", path.String(), colors.number, colors.number)
+		}
+	}
+	fmt.Fprintln(w, "\t\t"+"</svg>")
+}
+
+// geoPoints returns the projected points of a line, separated by spaces. Long
+// lines are thinned to geoMaxLinePoints, always keeping the last point so
+// that rings stay closed.
+func geoPoints(line [][2]float64, project func([2]float64) (string, string)) string {
+	step := len(line)/geoMaxLinePoints + 1
+	points := make([]string, 0, len(line)/step+1)
+	for i := 0; i < len(line); i += step {
+		x, y := project(line[i])
+		points = append(points, x+","+y)
+	}
+	if (len(line)-1)%step != 0 {
+		x, y := project(line[len(line)-1])
+		points = append(points, x+","+y)
+	}
+	return strings.Join(points, " ")
+}
@@ -0,0 +1,3 @@
+module github.com/ashtonc/json-pretty-printer
+
+go 1.24
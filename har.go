@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// harEntry is a request and its response from an HTTP Archive
+type harEntry struct {
+	method  string
+	url     string
+	status  string // The status code and text, such as 200 OK
+	isError bool   // The status is 400 or more, or the request failed
+	bodies  []harBody
+}
+
+// harBody is the body of a request or a response
+type harBody struct {
+	name      string // Request or Response
+	mimeType  string
+	text      []byte
+	documents [][]Token // The body formatted, if it is JSON
+}
+
+// runHAR renders an HTTP Archive (HAR), such as a browser exports from its
+// network panel, for --har: a list of its entries with their method, URL, and
+// status, followed by the body of each request and response, highlighted if it
+// is JSON and otherwise described by its type and size. It prints a page, or
+// text for a terminal with --format=ansi.
+func runHAR(ctx context.Context, w io.Writer, jsonFile []byte, options Options) error {
+	root, err := parseTokensContext(ctx, getTokens(jsonFile, options))
+	if err != nil {
+		return err
+	}
+	archive := member(root, "log")
+	entries := member(archive, "entries")
+	if entries == nil || entries.kind != NodeArray {
+		return fmt.Errorf("not an HTTP Archive: there is no log.entries array")
+	}
+
+	harEntries := make([]harEntry, 0, len(entries.elements))
+	for _, element := range entries.elements {
+		entry, err := readHAREntry(ctx, element, options)
+		if err != nil {
+			return err
+		}
+		harEntries = append(harEntries, entry)
+	}
+
+	if options.format == "ansi" {
+		return printHARText(ctx, w, harEntries, options, isColorEnabled(options.color, os.Stdout))
+	}
+	return printHARPage(ctx, w, harEntries, options)
+}
+
+// readHAREntry reads an entry of the archive and formats its bodies that are
+// JSON
+func readHAREntry(ctx context.Context, element *Node, options Options) (harEntry, error) {
+	request, response := member(element, "request"), member(element, "response")
+	entry := harEntry{method: harText(member(request, "method")), url: harText(member(request, "url"))}
+
+	status := member(response, "status")
+	if status != nil {
+		code, _ := strconv.Atoi(logText(status))
+		entry.status = strings.TrimSpace(logText(status) + " " + harText(member(response, "statusText")))
+		entry.isError = code == 0 || code >= 400
+	}
+
+	bodies := []struct {
+		name    string
+		content *Node
+	}{
+		{"Request", member(request, "postData")},
+		{"Response", member(response, "content")},
+	}
+	for _, b := range bodies {
+		text := member(b.content, "text")
+		if text == nil || text.kind != NodeString || stringValue(text) == "" {
+			continue
+		}
+
+		body := harBody{name: b.name, mimeType: harText(member(b.content, "mimeType")), text: []byte(stringValue(text))}
+		if encoding := member(b.content, "encoding"); encoding != nil && stringValue(encoding) == "base64" {
+			if decoded, err := base64.StdEncoding.DecodeString(stringValue(text)); err == nil {
+				body.text = decoded
+			}
+		}
+		if _, _, err := checkInput(body.text, options); err == nil {
+			documents, err := formatDocuments(ctx, body.text, options, ioutil.Discard)
+			if err != nil {
+				return entry, err
+			}
+			body.documents = documents
+		}
+		entry.bodies = append(entry.bodies, body)
+	}
+	return entry, nil
+}
+
+// harText returns a value of the archive as text, or "" if the archive leaves
+// it out
+func harText(value *Node) string {
+	if value == nil {
+		return ""
+	}
+	return logText(value)
+}
+
+// describeBody says what a body that is not JSON is, such as text/html, 12 KB
+func describeBody(body harBody) string {
+	mimeType := body.mimeType
+	if mimeType == "" {
+		mimeType = "unknown type"
+	}
+	return mimeType + ", " + formatBytes(int64(len(body.text))) + ", not JSON"
+}
+
+// printHARPage prints the entries as a page, with a table of the entries at
+// the top that links to each one
+func printHARPage(ctx context.Context, w io.Writer, entries []harEntry, options Options) error {
+	colors := pageTheme(options)
+	printHeader(w, options)
+
+	fmt.Fprintln(w, "\t\t"+"<table style=\"font-family:sans-serif; border-collapse:collapse\">")
+	fmt.Fprintln(w, "\t\t\t"+"<tr><th align=\"left\">Method</th><th align=\"left\">URL</th><th align=\"left\">Status</th></tr>")
+	for i, entry := range entries {
+		status := html.EscapeString(entry.status)
+		if entry.isError {
+			status = "<span style=\"color:" + colors.object + "\">" + status + "</span>"
+		}
+		fmt.Fprintf(w, "\t\t\t<tr><td>%s</td><td><a href=\"#entry-%d\">%s</a></td><td>%s</td></tr>\n",
+			html.EscapeString(entry.method), i+1, html.EscapeString(entry.url), status)
+	}
+	fmt.Fprintln(w, "\t\t"+"</table>")
+
+	for i, entry := range entries {
+		fmt.Fprintln(w, "\t\t"+"<h2 id=\"entry-"+strconv.Itoa(i+1)+"\" style=\"font-family:sans-serif; word-break:break-all\">"+
+			html.EscapeString(entry.method+" "+entry.url)+"</h2>")
+		fmt.Fprintln(w, "\t\t"+"<p style=\"font-family:sans-serif\">"+html.EscapeString(entry.status)+"</p>")
+
+		for _, body := range entry.bodies {
+			fmt.Fprintln(w, "\t\t"+"<h3 style=\"font-family:sans-serif\">"+body.name+" body</h3>")
+			if body.documents == nil {
+				fmt.Fprintln(w, "\t\t"+"<p style=\"font-family:sans-serif; color:"+colors.comment+"\">"+
+					html.EscapeString(describeBody(body))+"</p>")
+				continue
+			}
+			for j, document := range body.documents {
+				if j > 0 {
+					printSeparator(w, options)
+				}
+				if err := printDocument(ctx, w, document, options); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	printFooter(w)
+	return nil
+}
+
+// printHARText prints the entries for a terminal, each one a line with its
+// method, URL, and status followed by its bodies
+func printHARText(ctx context.Context, w io.Writer, entries []harEntry, options Options, isColored bool) error {
+	colors := pageTheme(options)
+	for _, entry := range entries {
+		statusColor := colors.annotation
+		if entry.isError {
+			statusColor = colors.object
+		}
+		fmt.Fprintln(w, ansiRun(textRun{text: entry.method, color: colors.pair}, isColored)+" "+
+			ansiRun(textRun{text: entry.url, color: colors.text}, isColored)+" "+
+			ansiRun(textRun{text: entry.status, color: statusColor}, isColored))
+
+		for _, body := range entry.bodies {
+			if body.documents == nil {
+				fmt.Fprintln(w, ansiRun(textRun{text: body.name + " body: " + describeBody(body), color: colors.comment}, isColored))
+				continue
+			}
+			fmt.Fprintln(w, ansiRun(textRun{text: body.name + " body:", color: colors.comment}, isColored))
+			if err := printANSI(ctx, w, body.documents, options, isColored); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
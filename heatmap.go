@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// valueSizes returns the size in bytes that each value of the spans has when
+// it is written as compact JSON, without white space, comments, or
+// annotations
+func valueSizes(tokenArray []Token, spans []valueSpan) []int {
+	// Sizes up to each token make the size of any span a subtraction
+	before := make([]int, len(tokenArray)+1)
+	for i, token := range tokenArray {
+		before[i+1] = before[i]
+		if token.kind != WhiteSpace && token.kind != Comment && token.kind != Annotation {
+			before[i+1] += len(token.content)
+		}
+	}
+
+	sizes := make([]int, len(spans))
+	for i, span := range spans {
+		sizes[i] = before[span.end+1] - before[span.start]
+	}
+	return sizes
+}
+
+// heatmapDecorations returns the decorations that tint the background of each
+// object and array by how much of the document it is, for --heatmap, from the
+// page's background to the background of removed values. The tint grows with
+// the square root of the share, so that containers of a few percent can still
+// be seen next to the largest one. Hovering over a container shows its path
+// and size.
+func heatmapDecorations(tokenArray []Token, colors theme) []Decoration {
+	decorations := make([]Decoration, len(tokenArray))
+	spans := findValueSpans(tokenArray)
+	sizes := valueSizes(tokenArray, spans)
+	if len(spans) == 0 || sizes[0] == 0 {
+		return decorations
+	}
+
+	// The document itself would be tinted all over, so it is left as it is
+	total := float64(sizes[0])
+	for i, span := range spans[1:] {
+		kind := tokenArray[span.start].kind
+		if kind != ObjectOpen && kind != ArrayOpen {
+			continue
+		}
+
+		share := float64(sizes[i+1]) / total
+		tint := int(math.Round(math.Sqrt(share) * 100))
+		title := fmt.Sprintf("%s: %s, %.1f%%", formatPointer(span.path), formatBytes(int64(sizes[i+1])), share*100)
+		decorations[span.start].before += fmt.Sprintf(`<span style="background-color:color-mix(in srgb, %s %d%%, %s)" title="%s">`,
+			colors.removed, tint, colors.background, html.EscapeString(title))
+		decorations[span.end].after = `</span>` + decorations[span.end].after
+	}
+	return decorations
+}
+
+// printSizeReport prints the count largest values of the documents, for
+// --size-report: the size of each as compact JSON, its share of all of the
+// documents, and its JSON Pointer, which is prefixed with the number of its
+// document if there are several. Values inside a large value are listed as
+// well, so that what makes it large can be seen.
+func printSizeReport(w io.Writer, documents [][]Token, count int) {
+	type sizedValue struct {
+		document int
+		path     []string
+		size     int
+	}
+
+	var values []sizedValue
+	total := 0
+	for i, document := range documents {
+		spans := findValueSpans(document)
+		sizes := valueSizes(document, spans)
+		for j, span := range spans {
+			if len(span.path) == 0 {
+				total += sizes[j]
+				continue
+			}
+			values = append(values, sizedValue{document: i + 1, path: span.path, size: sizes[j]})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].size > values[j].size
+	})
+	if len(values) > count {
+		values = values[:count]
+	}
+
+	fmt.Fprintf(w, "%d document(s), %s\n", len(documents), formatBytes(int64(total)))
+	for _, value := range values {
+		path := formatPointer(value.path)
+		if len(documents) > 1 {
+			path = "#" + strconv.Itoa(value.document) + " " + path
+		}
+		fmt.Fprintf(w, "%10s %6.1f%%  %s\n", formatBytes(int64(value.size)), float64(value.size)/float64(total)*100, path)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the file in the current directory that lists the files the
+// batch modes skip, in the same syntax as .gitignore
+const ignoreFileName = ".jsonprettyignore"
+
+// stringList is a flag that can be given more than once, collecting each value
+type stringList []string
+
+// String returns the values joined by commas
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+// Set adds a value
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// Get returns the values
+func (list *stringList) Get() interface{} {
+	return []string(*list)
+}
+
+// ignoreRule is a single pattern of an ignore file
+type ignoreRule struct {
+	pattern    string
+	isNegated  bool // It starts with '!' and brings back what was ignored
+	isDirOnly  bool // It ends with '/' and only matches directories
+	isAnchored bool // It has a '/' and is matched against the whole path
+}
+
+// parseIgnoreRule reads a line of an ignore file. It returns false for blank
+// lines and comments.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.isNegated = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// An escaped '#' or '!' is part of the pattern
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.isDirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.isAnchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	rule.pattern = line
+	return rule, line != ""
+}
+
+// ignoreRules returns the rules of the ignore file in the current directory,
+// if there is one, followed by the --exclude patterns, which use the same
+// syntax
+func ignoreRules(options Options) ([]ignoreRule, error) {
+	rules := make([]ignoreRule, 0)
+
+	file, err := os.Open(ignoreFileName)
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+				rules = append(rules, rule)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, exclude := range options.exclude {
+		if rule, ok := parseIgnoreRule(exclude); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// isIgnored returns true if the rules skip the file or directory. As with git,
+// the last rule that matches wins, and nothing inside an ignored directory can
+// be brought back.
+func isIgnored(rules []ignoreRule, fileName string, isDir bool) bool {
+	if len(rules) == 0 {
+		return false
+	}
+
+	relative := fileName
+	if absolute, err := filepath.Abs(fileName); err == nil {
+		if working, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(working, absolute); err == nil {
+				relative = rel
+			}
+		}
+	}
+	segments := strings.Split(filepath.ToSlash(relative), "/")
+
+	// Every directory that leads to the file is checked first
+	for i := 1; i <= len(segments); i++ {
+		isSegmentDir := i < len(segments) || isDir
+		ignored := false
+		for _, rule := range rules {
+			if rule.isDirOnly && !isSegmentDir {
+				continue
+			}
+			if matchIgnoreRule(rule, segments[:i]) {
+				ignored = !rule.isNegated
+			}
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIgnoreRule returns true if the pattern of the rule matches the path.
+// A pattern without a '/' matches the last segment at any depth.
+func matchIgnoreRule(rule ignoreRule, segments []string) bool {
+	if !rule.isAnchored {
+		matched, _ := path.Match(rule.pattern, segments[len(segments)-1])
+		return matched
+	}
+	return matchGlobSegments(strings.Split(rule.pattern, "/"), segments)
+}
+
+// matchGlobSegments matches a pattern against a path one segment at a time,
+// where a '**' segment matches any number of segments, including none
+func matchGlobSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchGlobSegments(pattern[1:], segments[1:])
+}
+
+// expandFileNames returns the files named in the arguments that are not
+// ignored. Directories are searched for .json files all the way down, and
+// ignored directories are not searched at all.
+func expandFileNames(arguments []string, options Options) ([]string, error) {
+	rules, err := ignoreRules(options)
+	if err != nil {
+		return nil, err
+	}
+
+	fileNames := make([]string, 0, len(arguments))
+	for _, argument := range arguments {
+		info, err := os.Stat(argument)
+		if err != nil || !info.IsDir() {
+			// Files that cannot be read are kept so that the error is shown
+			if !isIgnored(rules, argument, false) {
+				fileNames = append(fileNames, argument)
+			}
+			continue
+		}
+
+		err = filepath.Walk(argument, func(fileName string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if isIgnored(rules, fileName, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() && strings.EqualFold(filepath.Ext(fileName), ".json") {
+				fileNames = append(fileNames, fileName)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fileNames, nil
+}
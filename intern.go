@@ -0,0 +1,38 @@
+package main
+
+// Keys longer than internMaxLength are kept as they are, since long keys
+// seldom repeat, and the pool stops taking new strings
+// once it has internMaxStrings of them, so that input with few repeats does
+// not fill it for nothing
+const (
+	internMaxLength  = 64
+	internMaxStrings = 1 << 16
+)
+
+// stringPool hands out one copy of each key it has seen, so that the keys
+// that repeat throughout a large document, such as "id" and "name", take up
+// memory once instead of once for every record. Values are not pooled, since
+// ids, timestamps, and most other values are different in every record.
+type stringPool struct {
+	strings map[string]string
+}
+
+// newStringPool returns an empty pool
+func newStringPool() *stringPool {
+	return &stringPool{strings: make(map[string]string)}
+}
+
+// intern returns the copy of the text in the pool, adding it if it is not
+// there yet
+func (pool *stringPool) intern(text string) string {
+	if len(text) > internMaxLength {
+		return text
+	}
+	if interned, ok := pool.strings[text]; ok {
+		return interned
+	}
+	if len(pool.strings) < internMaxStrings {
+		pool.strings[text] = text
+	}
+	return text
+}
@@ -1,75 +1,1246 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
+	"math/rand"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-func main() {
-	// Check whether or not a file was passed in; panic if no file is listed
-	if len(os.Args) < 2 {
-		panic("Filename not detected")
+// commands lists the subcommands that can come before the flags and files,
+// along with the arguments they take and what they do, for help and the
+// generated documentation
+var commands = []struct {
+	name      string
+	arguments string
+	usage     string
+}{
+	{"diff", "before.json after.json",
+		"render the second file with its differences from the first highlighted, or print them as a JSON Patch with --output=patch"},
+	{"merge", "file.json...",
+		"layer the files on top of each other, later files winning, and render the result"},
+	{"import-theme", "theme.json|theme.tmTheme",
+		"convert a VS Code or TextMate color theme into a theme file for --theme"},
+	{"completion", "bash|zsh|fish|powershell",
+		"print a completion script for the shell"},
+	{"gen-man", "",
+		"print a man page in roff"},
+	{"merge3", "base.json ours.json theirs.json",
+		"merge the changes that ours and theirs made to base and render the result, with conflicts highlighted"},
+	{"fmt", "file.json|directory...",
+		"format the files, and the .json files in the directories, in place as plain, indented JSON, or only check them with --check"},
+	{"openapi", "spec.json",
+		"render the request and response examples of every operation of an OpenAPI spec, on one page or with --output-dir a page each"},
+	{"jwt", "token|file|-",
+		"decode a JSON Web Token and render its header and payload, with its times, such as exp, written out and the signature unchecked"},
+	{"preview", "file.avro|file.parquet",
+		"render the schema of an Avro data file and its first --records records, or the schema and metadata in the footer of a Parquet file"},
+	{"db", "dsn query",
+		"run a query against PostgreSQL with psql or SQLite with sqlite3 and render the JSON column of each row it returns, with the other columns in a comment"},
+	{"terraform", "plan.json|state.json",
+		"render the output of terraform show -json: a summary of the changes of a plan and each resource with what changes highlighted, or each resource in state"},
+	{"logs", "[file.ndjson|-]",
+		"print structured log records with their time, level, and message on one line and the rest of their fields as JSON beneath, following the file with --follow"},
+}
+
+// commandNames returns the names of the subcommands
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, command := range commands {
+		names[i] = command.name
+	}
+	return names
+}
+
+// flagChoices lists the values that the flags with a fixed set of values
+// accept. Themes can also be files, so only the built-in ones are listed.
+var flagChoices = map[string][]string{
+	"format":               {"html", "ansi", "jsonschema", "pdf", "png", "slack", "discord", "github-annotations", "sarif", "table", "dot", "mermaid"},
+	"color":                {"auto", "always", "never"},
+	"theme":                themeNames(),
+	"output":               {"tree", "patch", "unified"},
+	"strategy":             {"deep", "last-wins", "concat"},
+	"path-style":           {"dot", "pointer"},
+	"sample-mode":          {"first", "last", "random"},
+	"log-format":           {"text", "json"},
+	"normalize-timestamps": {"local", "utc", "relative"},
+	"input":                {"json", "msgpack", "cbor", "bson", "csv"},
+	"preset":               {"cloud-cli", "package-json"},
+	"duplicates":           {"report", "reference"},
+}
+
+// isFlagChoice returns true if the value is one of the choices for the flag
+func isFlagChoice(name, value string) bool {
+	for _, choice := range flagChoices[name] {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommand returns true if the argument names one of the subcommands
+func isCommand(argument string) bool {
+	for _, command := range commands {
+		if argument == command.name {
+			return true
+		}
+	}
+	return false
+}
+
+// runFormat styles the JSON file named in the arguments and prints it as HTML,
+// which is what the program does when no subcommand is given
+func runFormat(options Options, arguments []string) {
+	var fileName string
+	var jsonFile []byte
+	var err error
+
+	// Pages link to the shared styles and scripts, from where they are written
+	if options.assetsDir != "" {
+		if err := writeAssets(options.assetsDir); err != nil {
+			panic(err)
+		}
+		options.assetsPath = assetsPathFrom(".", options)
+	}
+
+	// Files and directories can be rendered to a directory of pages instead,
+	// and several files are otherwise rendered together on one page
+	if options.outputDir != "" {
+		runFormatDirectory(options, arguments)
+		return
+	}
+	if !options.clipboardIn && len(arguments) > 1 {
+		runFormatFiles(options, arguments)
+		return
+	}
+
+	if options.clipboardIn {
+		// The JSON comes from the clipboard instead of a file
+		fileName = "clipboard"
+		jsonFile, err = readClipboard()
+		if err != nil {
+			panic(err)
+		}
+		if options.teeFile != "" {
+			if err := ioutil.WriteFile(options.teeFile, jsonFile, 0644); err != nil {
+				panic(err)
+			}
+		}
+		options.progress = newProgressReporter(options, int64(len(jsonFile)))
+	} else {
+		// Check whether or not a file was passed in; panic if no file is listed
+		if len(arguments) < 1 {
+			panic("Filename not detected")
+		}
+
+		// Open the JSON file; if there is a file error, quit the program
+		fileName = arguments[0]
+		start := time.Now()
+		jsonFile, options.progress, err = readFileWithProgress(fileName, options)
+		if err != nil {
+			panic(err)
+		}
+		logger(options).Info("read", "file", fileName, "bytes", len(jsonFile), "duration", time.Since(start))
+	}
+
+	options.inputSize = int64(len(jsonFile))
+	options.fileName = fileName
+	if (options.embedRaw || options.sideBySide) && options.input == "json" {
+		options.rawInput = jsonFile
+	}
+	defer options.progress.finish()
+
+	// Write the warnings for CI before anything can stop on the input
+	if options.reportFile != "" {
+		report := formatText(nodeTokens(buildProblemsReport(findProblems(jsonFile, options), fileName)))
+		if err := ioutil.WriteFile(options.reportFile, []byte(report), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	// Only check the input if asked, without rendering it
+	if options.analyze {
+		analyzeInput(os.Stdout, jsonFile, options)
+		return
+	}
+
+	// Linting reports the problems in the input instead of rendering it
+	if isLintFormat(options.format) {
+		if !lintInput(os.Stdout, jsonFile, fileName, options) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+
+	// An HTTP Archive is shown as its requests and responses instead
+	if options.har {
+		if err := runHAR(ctx, os.Stdout, jsonFile, options); err != nil {
+			exitOnError(err, options)
+		}
+		return
+	}
+
+	documents, err := formatDocuments(ctx, jsonFile, options, os.Stderr)
+	if err != nil {
+		exitOnError(err, options)
+	}
+
+	// The largest values are listed instead of rendering the documents
+	if options.sizeReport > 0 {
+		printSizeReport(os.Stdout, documents, options.sizeReport)
+		return
+	}
+
+	// Make sure that printing will not change any of the values
+	if options.verify {
+		start := time.Now()
+		if err := verifyDocuments(documents, options); err != nil {
+			fmt.Fprintln(os.Stderr, "Verification failed: "+err.Error())
+			os.Exit(1)
+		}
+		logger(options).Info("verify", "documents", len(documents), "duration", time.Since(start))
 	}
 
-	// Open the JSON file; if there is a file error, quit the program
-	fileName := os.Args[1]
-	jsonFile, err := ioutil.ReadFile(fileName)
+	// Record where each rendered token came from before printing
+	if options.sourceMapFile != "" {
+		sourceMap := formatText(nodeTokens(buildSourceMap(documents, fileName)))
+		if err := ioutil.WriteFile(options.sourceMapFile, []byte(sourceMap), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	// The page goes to the clipboard instead of standard output if asked
+	if options.clipboardOut {
+		var page bytes.Buffer
+		if err := printOutput(ctx, &page, documents, options, options.color == "always"); err != nil {
+			exitOnError(err, options)
+		}
+		if err := writeClipboard(page.Bytes()); err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(os.Stderr, "Copied %d bytes to the clipboard\n", page.Len())
+		return
+	}
+
+	// Output that would go to the terminal is opened in the browser instead
+	if options.open && isTerminal(os.Stdout) {
+		if err := printToBrowser(ctx, documents, options); err != nil {
+			exitOnError(err, options)
+		}
+		return
+	}
+
+	start := time.Now()
+	output := &countingWriter{w: os.Stdout}
+	if err := printOutput(ctx, output, documents, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+	logger(options).Info("render", "format", options.format, "bytes", output.count, "duration", time.Since(start))
+}
+
+// timeoutContext returns the context that formatting runs in, which gives up
+// once --timeout has passed
+func timeoutContext(options Options) (context.Context, context.CancelFunc) {
+	if options.timeout > 0 {
+		return context.WithTimeout(context.Background(), options.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// exitOnError stops the program because of the error. Running out of time is
+// expected with --timeout, so it is reported without a stack trace.
+func exitOnError(err error, options Options) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "\nGave up after --timeout=%v\n", options.timeout)
+		os.Exit(1)
+	}
+	panic(err)
+}
+
+// printOutput prints the documents in the format that the options chose:
+// whatever an output plugin prints, the output template, a PDF or PNG image,
+// messages for a chat tool, text for a terminal, which is only colored if
+// isColored is true, or else an HTML page
+func printOutput(ctx context.Context, w io.Writer, documents [][]Token, options Options, isColored bool) error {
+	if options.plugin != "" {
+		return runPlugin(ctx, w, documents, options)
+	}
+	if options.outputTemplate != "" {
+		return printTemplate(ctx, w, documents, options)
+	}
+	if options.format == "pdf" {
+		return printPDF(ctx, w, documents, options)
+	}
+	if options.format == "png" {
+		return printPNG(ctx, w, documents, options)
+	}
+	if _, ok := chatFormats[options.format]; ok {
+		return printChat(ctx, w, documents, options)
+	}
+	if options.format == "dot" || options.format == "mermaid" {
+		return printDiagram(ctx, w, documents, options)
+	}
+	if options.format == "table" {
+		return printTable(ctx, w, documents, options)
+	}
+	if options.format == "ansi" {
+		return printANSI(ctx, w, documents, options, isColored)
+	}
+	return printPageContext(ctx, w, documents, options)
+}
+
+// Format renders the input as an HTML page the same way the program does when
+// no subcommand is given. It is how other front ends, such as the WebAssembly
+// build, share the formatter. The page only depends on the input and the
+// options, so it can be compared against a golden file.
+func Format(input []byte, options Options) (string, error) {
+	return FormatContext(context.Background(), input, options)
+}
+
+// FormatContext is Format that gives up with the context's error once the
+// context is done, so that formatting an input that takes too long can be
+// cancelled
+func FormatContext(ctx context.Context, input []byte, options Options) (string, error) {
+	documents, err := formatDocuments(ctx, input, options, ioutil.Discard)
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+
+	var page strings.Builder
+	if err := printPageContext(ctx, &page, documents, options); err != nil {
+		return "", err
+	}
+	return page.String(), nil
+}
+
+// formatDocuments turns the input into the tokens of each top-level value that
+// is rendered, with every option that changes the values applied. Repairs and
+// fixes that were made to the input are reported to the report writer.
+func formatDocuments(ctx context.Context, jsonFile []byte, options Options, report io.Writer) ([][]Token, error) {
+	log := logger(options)
+
+	// Options that were not read from flags, such as those of Reformat, have
+	// no input format and read JSON
+	if options.input == "csv" {
+		return csvDocuments(ctx, jsonFile, options, report)
+	}
+	if options.input != "" && options.input != "json" {
+		return decodedDocuments(ctx, jsonFile, options, report)
+	}
+
+	// Fix up almost-JSON before it is tokenized and report what was changed
+	if options.repair {
+		start := time.Now()
+		var repairs []string
+		jsonFile, repairs = repairJSON(jsonFile, options)
+		for _, repair := range repairs {
+			fmt.Fprintln(report, "Repaired "+repair)
+		}
+		fmt.Fprintf(report, "Made %d repair(s)\n", len(repairs))
+		log.Info("repair", "repairs", len(repairs), "duration", time.Since(start))
+	}
+
+	// Input that is not valid is shown as far as its first error instead of
+	// as well as it can be
+	if options.showErrors {
+		if _, _, err := checkInput(jsonFile, options); err != nil {
+			// Input that is only cut off is finished below instead
+			var syntaxError *SyntaxError
+			isCutOff := errors.As(err, &syntaxError) && syntaxError.Offset < 0
+			if !options.allowTruncated || !isCutOff {
+				return brokenDocuments(ctx, jsonFile, err, options)
+			}
+		}
+	}
+
+	start := time.Now()
+	tokenArray, err := tokenize(ctx, jsonFile, options) // Tokenize the JSON file
+	if err != nil {
+		return nil, err
+	}
+	log.Info("tokenize", "tokens", len(tokenArray), "duration", time.Since(start))
+
+	// Close what input that was cut off left open and report it
+	if options.allowTruncated {
+		var addedCount int
+		tokenArray, addedCount = closeTruncated(tokenArray)
+		if addedCount > 0 {
+			fmt.Fprintf(report, "Closed the truncated input with %d token(s)\n", addedCount)
+		}
+	}
+
+	// Drop commas that directly precede a closing bracket and report them
+	if options.fixTrailingCommas {
+		var fixedCount int
+		tokenArray, fixedCount = removeTrailingCommas(tokenArray)
+		fmt.Fprintf(report, "Fixed %d trailing comma(s)\n", fixedCount)
+	}
+
+	documents := splitDocuments(tokenArray) // Separate concatenated values
+	log.Debug("split", "documents", len(documents))
+
+	// Changes to the structure are made on the parse tree of each document,
+	// which is then turned back into tokens for printing
+	if isTreeNeeded(options) {
+		start := time.Now()
+		roots := make([]*Node, len(documents))
+		for i, document := range documents {
+			root, err := parseTokensContext(ctx, document)
+			if err != nil {
+				return nil, err
+			}
+			roots[i], err = transformTree(root, options, report)
+			if err != nil {
+				return nil, err
+			}
+			documents[i] = nodeTokens(roots[i])
+		}
+
+		// A schema describes all of the documents at once
+		if options.format == "jsonschema" {
+			documents = [][]Token{nodeTokens(inferSchemaDocument(roots, options))}
+		}
+
+		tokenCount := 0
+		for _, document := range documents {
+			tokenCount += len(document)
+		}
+		log.Info("transform", "documents", len(roots), "tokens", tokenCount, "duration", time.Since(start))
+	}
+
+	if options.preserveLayout {
+		for i, document := range documents {
+			documents[i] = preserveLayout(jsonFile, document)
+		}
+	}
+
+	return documents, nil
+}
+
+// Options carries the settings that were chosen on the command line
+type Options struct {
+	allowComments     bool              // Accept '//' and '/* */' comments and keep them
+	fixTrailingCommas bool              // Remove commas that come right before '}' or ']'
+	repair            bool              // Apply best-effort fixes to almost-JSON
+	showErrors        bool              // Show invalid input up to its first error
+	allowTruncated    bool              // Close what input that was cut off left open
+	preserveLayout    bool              // Keep the white space of the input as it is
+	sortArrayBy       string            // Sort arrays of objects by this dotted path
+	transform         string            // A jq-like expression that changes the document
+	renameKeysFile    string            // Rename keys as this mapping says
+	coerceTypes       bool              // Turn strings of numbers and booleans into them
+	substituteEnv     bool              // Replace ${VAR} in strings with environment variables
+	allowMissing      bool              // Leave in the placeholders of variables that are not set
+	pseudonymize      string            // Keys whose values are replaced with fakes, by commas
+	pruneNulls        bool              // Remove the members that are null
+	pruneEmpty        bool              // Remove the empty objects and arrays inside the document
+	sortKeys          bool              // Sort the members of every object by key
+	patchFile         string            // Apply this JSON Patch (RFC 6902) document
+	mergePatchFile    string            // Apply this JSON Merge Patch (RFC 7386) document
+	highlightChanges  bool              // Highlight what the merge patch changed
+	baselineFile      string            // Highlight what differs from this file
+	output            string            // What the diff command prints: tree, patch, or unified
+	ignorePaths       stringList        // Patterns of paths whose differences diff leaves out
+	tolerance         float64           // How far apart numbers can be and still be the same
+	arrayKey          string            // The member that matches up array elements in a diff
+	strategy          string            // How the merge command combines values
+	flatten           bool              // Turn the document into a single-level object
+	unflatten         bool              // Turn a single-level object back into a tree
+	pathStyle         string            // How flattened keys are written: dot or pointer
+	annotateTypes     bool              // Add a badge naming the type after each value
+	explain           bool              // Explain what each value is, for beginners
+	schemaFile        string            // Document the values with this JSON Schema
+	har               bool              // Show an HTTP Archive as its requests and responses
+	records           int               // How many records preview renders
+	input             string            // What the input is: json, msgpack, cbor, bson, or csv
+	headers           string            // The names of the CSV columns, by commas, if not the first row
+	delimiter         string            // What separates CSV fields, if not a comma or a tab for .tsv
+	noInferTypes      bool              // Keep every CSV field a string
+	schema            *Node             // The schema that was read from the file
+	protoDescriptor   string            // Check the document against a protobuf message
+	protoMessage      string            // The full name of the message the document is
+	proto             *protoDescriptors // The descriptor set that was read from the file
+	timestampForm     string            // Annotate times as local, utc, or relative, if not ""
+	showIndexes       bool              // Label each array element with its index
+	collapsible       bool              // Let objects and arrays be folded in the page
+	k8s               bool              // Arrange Kubernetes objects for reading
+	geoJSON           bool              // Summarize GeoJSON and draw a map of it above it
+	heatmap           bool              // Tint each container by its share of the document's size
+	sizeReport        int               // Print this many of the largest values instead, if not 0
+	duplicates        string            // Report repeated values, or also replace them: report or reference
+	preset            string            // A bundle of flags for a kind of document
+	format            string            // What is rendered: html or jsonschema
+	samples           bool              // Treat a top-level array as a list of samples
+	sample            int               // Only render this many elements of each array
+	sampleMode        string            // Which elements are rendered: first, last, random
+	sampleSeed        int64             // Seed for choosing random elements
+	maxRenderDepth    int               // Fold containers nested this deep, if not 0
+	anchors           bool              // Give each value an id from its JSON Pointer
+	keyboardNav       bool              // Add keys that move between sibling values
+	sourceMapFile     string            // Write where each rendered token came from here
+	theme             string            // The name of the colors of the page
+	colors            theme             // The theme that was loaded for the name
+	serve             string            // Serve formatted JSON over HTTP at this address
+	maxBodySize       int64             // The largest request body the server reads
+	rateLimit         int               // Requests a minute the server allows each client
+	requestTimeout    time.Duration     // How long the server gives each request
+	maxDepth          int               // How deeply input to the server can nest
+	clipboardIn       bool              // Read the JSON from the system clipboard
+	clipboardOut      bool              // Put the page on the system clipboard
+	open              bool              // Open the page in the browser instead of printing it
+	watch             bool              // Render the file again each time it changes
+	follow            bool              // Print each line of NDJSON as it is written
+	fields            string            // The fields of NDJSON records printed as columns, by commas
+	color             string            // When --format=ansi uses color: auto, always, never
+	verbose           bool              // Log the time each phase takes
+	debug             bool              // Also log the options and other details
+	logFormat         string            // How the log is written: text or json
+	logger            *slog.Logger      // Where the log goes, made from the above
+	analyze           bool              // Check the input and report on it instead
+	noProgress        bool              // Never show progress for large inputs
+	progress          *progressReporter // Shows how far along a large input is
+	inputSize         int64             // The size of the input, for the progress
+	timeout           time.Duration     // Give up on formatting after this long
+	verify            bool              // Check that printing keeps every value
+	styleHooks        styleHooks        // Markup that library users add to tokens
+	locale            string            // Write numbers and times as people do here
+	numberFormat      NumberFormat      // How numbers are written for people, if not nil
+	timeFormat        TimeFormat        // How times are written for people, if not nil
+	plugin            string            // The output plugin that renders the tokens
+	outputTemplate    string            // The text/template file that renders the values
+	fileName          string            // The name of the input, for output templates
+	reportFile        string            // Write the problems found in the input here
+	check             bool              // Make fmt check the files instead of writing them
+	exclude           stringList        // Patterns of files that fmt skips
+	flagArguments     []string          // The flags as given, to read again after a config
+	teeFile           string            // Copy the raw input here as it is read
+	outputDir         string            // Render each file to a page in this directory
+	printFriendly     bool              // Lay the page out in black on white for printing
+	embedRaw          bool              // Put the input in the page to view and download
+	assetsDir         string            // Write the styles and scripts here instead of in pages
+	assetsPath        string            // Where the page links to the assets directory
+	sideBySide        bool              // Show the input next to the formatted output
+	rawInput          []byte            // The input that --embed-raw and --side-by-side put in the page
+	fontFile          string            // The BDF font that --format=png draws in
+	scale             int               // Pixels drawn for each pixel of the font
+	maxMessages       int               // The most chat messages that are printed, if not 0
+}
+
+// NewOptions returns the options that the flags set, with the defaults for
+// every flag that is not given. It is how Go code that calls Format chooses
+// its options, such as NewOptions([]string{"--collapsible"}).
+func NewOptions(flags []string) (Options, error) {
+	options, _, err := readOptions(flags, flag.ContinueOnError)
+	return options, err
+}
+
+// parseOptions reads the flags in the arguments into Options and returns the
+// arguments that are left over after the flags. The program exits if a flag is
+// not valid.
+func parseOptions(arguments []string) (Options, []string) {
+	options, arguments, err := readOptions(arguments, flag.ExitOnError)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return options, arguments
+}
+
+// readOptions reads the flags in the arguments into Options and returns the
+// arguments that are left over after the flags, or an error if a flag is not
+// valid. Each call reads into a new set of flags, so it can be called more than
+// once.
+func readOptions(arguments []string, errorHandling flag.ErrorHandling) (Options, []string, error) {
+	var options Options
+	flags := flag.NewFlagSet(os.Args[0], errorHandling)
+	defineFlags(flags, &options)
+
+	if err := flags.Parse(arguments); err != nil {
+		return options, nil, err
+	}
+	options.flagArguments = arguments[:len(arguments)-flags.NArg()]
+
+	// A preset is read before the flags, so that the flags that are given win
+	if options.preset != "" {
+		if !isFlagChoice("preset", options.preset) {
+			return options, nil, errors.New("Unknown preset: " + options.preset)
+		}
+		preset, flagArguments := presets[options.preset], options.flagArguments
+		options = Options{}
+		flags = flag.NewFlagSet(os.Args[0], errorHandling)
+		defineFlags(flags, &options)
+		if err := flags.Parse(append(append([]string{}, preset...), arguments...)); err != nil {
+			return options, nil, err
+		}
+		options.flagArguments = flagArguments
+	}
+
+	if !isFlagChoice("format", options.format) {
+		return options, nil, errors.New("Unknown format: " + options.format)
+	}
+
+	if !isFlagChoice("color", options.color) {
+		return options, nil, errors.New("Unknown color mode: " + options.color)
+	}
+
+	if !isFlagChoice("log-format", options.logFormat) {
+		return options, nil, errors.New("Unknown log format: " + options.logFormat)
+	}
+
+	if options.timestampForm != "" && !isFlagChoice("normalize-timestamps", options.timestampForm) {
+		return options, nil, errors.New("Unknown timestamp form: " + options.timestampForm)
+	}
+
+	if !isFlagChoice("sample-mode", options.sampleMode) {
+		return options, nil, errors.New("Unknown sample mode: " + options.sampleMode)
+	}
+	if !isFlagChoice("path-style", options.pathStyle) {
+		return options, nil, errors.New("Unknown path style: " + options.pathStyle)
+	}
+	if !isFlagChoice("strategy", options.strategy) {
+		return options, nil, errors.New("Unknown merge strategy: " + options.strategy)
+	}
+
+	if !isFlagChoice("input", options.input) {
+		return options, nil, errors.New("Unknown input format: " + options.input)
+	}
+	if options.input != "json" && (options.repair || options.preserveLayout || options.har || options.analyze ||
+		options.reportFile != "" || isLintFormat(options.format)) {
+		return options, nil, errors.New("--repair, --preserve-layout, --har, --analyze, --report, and linting only read JSON input")
+	}
+	if options.input != "csv" && (options.headers != "" || options.delimiter != "" || options.noInferTypes) {
+		return options, nil, errors.New("--headers, --delimiter, and --no-infer-types only apply to --input=csv")
+	}
+	if options.delimiter != "" && options.delimiter != "tab" &&
+		(utf8.RuneCountInString(options.delimiter) != 1 || strings.ContainsAny(options.delimiter, "\"\r\n")) {
+		return options, nil, errors.New("--delimiter must be a single character other than a quote or line break, or tab")
+	}
+
+	if options.preserveLayout && isTreeNeeded(options) {
+		return options, nil, errors.New("--preserve-layout cannot be used with options that change the values")
+	}
+
+	if options.transform != "" {
+		if _, err := parseTransform(options.transform); err != nil {
+			return options, nil, err
+		}
+	}
+
+	if options.tolerance < 0 {
+		return options, nil, errors.New("--tolerance cannot be negative")
+	}
+	if options.records < 0 {
+		return options, nil, errors.New("--records cannot be negative")
+	}
+	if options.duplicates != "" && !isFlagChoice("duplicates", options.duplicates) {
+		return options, nil, errors.New("Unknown duplicates mode: " + options.duplicates)
+	}
+	if options.sizeReport < 0 {
+		return options, nil, errors.New("--size-report cannot be negative")
+	}
+
+	if options.scale < 1 {
+		return options, nil, errors.New("--scale must be at least 1")
+	}
+
+	if options.schemaFile != "" {
+		schema, err := readJSONFile(options.schemaFile, options)
+		if err != nil {
+			return options, nil, err
+		}
+		options.schema = schema
+	}
+
+	if options.protoDescriptor != "" {
+		descriptors, err := readProtoDescriptors(options.protoDescriptor)
+		if err != nil {
+			return options, nil, err
+		}
+		options.proto = descriptors
+		if _, ok := descriptors.messages[options.protoMessage]; options.protoMessage != "" && !ok {
+			return options, nil, errors.New("Unknown protobuf message: " + options.protoMessage)
+		}
+	}
+
+	if options.locale != "" {
+		l, ok := findLocale(options.locale)
+		if !ok {
+			return options, nil, errors.New("Unknown locale: " + options.locale)
+		}
+		options.numberFormat, options.timeFormat = l.formatNumber, l.formatTime
+	}
+
+	colors, err := loadTheme(options.theme)
+	if err != nil {
+		return options, nil, err
+	}
+	options.colors = colors
+
+	options.logger = newLogger(options)
+	logOptions(options, flags)
+
+	// Repaired output should be valid JSON, so trailing commas go too
+	if options.repair {
+		options.fixTrailingCommas = true
+	}
+
+	return options, flags.Args(), nil
+}
+
+// defineFlags adds every flag to the set, each of which is read into the
+// options. It is the one list of flags that parsing, help, and the generated
+// completion scripts all come from.
+func defineFlags(flags *flag.FlagSet, options *Options) {
+	flags.BoolVar(&options.allowComments, "allow-comments", false,
+		"accept // and /* */ comments (JSONC) and keep them in the output")
+	flags.BoolVar(&options.fixTrailingCommas, "fix-trailing-commas", false,
+		"remove trailing commas in objects and arrays and report how many were fixed")
+	flags.BoolVar(&options.repair, "repair", false,
+		"fix quotes, unquoted keys, Python literals, and trailing garbage, and report the repairs")
+	flags.BoolVar(&options.showErrors, "show-errors", false,
+		"render invalid input normally up to its first error, then the message and the rest of the input as it was written")
+	flags.BoolVar(&options.allowTruncated, "allow-truncated", false,
+		"close the strings, objects, and arrays that input which was cut off left open, and mark what was added")
+	flags.BoolVar(&options.preserveLayout, "preserve-layout", false,
+		"keep the white space and line breaks of the input as they are and only add color")
+	flags.StringVar(&options.transform, "transform", "",
+		"change the document with a small part of jq's language before rendering, such as 'del(.debug) | .items |= sort_by(.name)'")
+	flags.StringVar(&options.renameKeysFile, "rename-keys", "",
+		"rename keys as this JSON file maps them, such as {\"colour\": \"color\"} everywhere or {\"/server/addr\": \"address\"} at a path, and report how many")
+	flags.BoolVar(&options.substituteEnv, "substitute-env", false,
+		"replace ${VAR} and ${VAR:-default} in string values with environment variables, for reviewing templated configuration")
+	flags.BoolVar(&options.allowMissing, "allow-missing", false,
+		"with --substitute-env, leave in and report the placeholders of variables that are not set instead of failing")
+	flags.BoolVar(&options.coerceTypes, "coerce-types", false,
+		"turn strings that are only a number, \"true\", or \"false\" into numbers and booleans, and report each one")
+	flags.StringVar(&options.pseudonymize, "pseudonymize", "",
+		"replace the values of these keys, separated by commas, with fakes of the same shape that are the same for the same value, such as email,name")
+	flags.BoolVar(&options.pruneNulls, "prune-nulls", false,
+		"remove the members of objects that are null")
+	flags.BoolVar(&options.pruneEmpty, "prune-empty", false,
+		"remove the empty objects and arrays inside the document, including the ones that pruning leaves empty")
+	flags.StringVar(&options.sortArrayBy, "sort-array-by", "",
+		"sort arrays of objects by the member at this dotted path, such as user.id")
+	flags.BoolVar(&options.sortKeys, "sort-keys", false,
+		"sort the members of every object by key, so the output does not depend on the order of the input")
+	flags.StringVar(&options.patchFile, "patch", "",
+		"apply this JSON Patch (RFC 6902) file before rendering and highlight what it changed")
+	flags.StringVar(&options.mergePatchFile, "merge-patch", "",
+		"apply this JSON Merge Patch (RFC 7386) file before rendering")
+	flags.BoolVar(&options.highlightChanges, "highlight-changes", false,
+		"highlight the fields that --merge-patch added, changed, or removed")
+	flags.StringVar(&options.baselineFile, "baseline", "",
+		"highlight the values that were added, changed, or removed since this earlier version of the file")
+	flags.StringVar(&options.baselineFile, "diff-against", "",
+		"the same as --baseline, for --watch to show how the file differs from this one each time it changes")
+	flags.StringVar(&options.output, "output", "tree",
+		"what diff prints: tree (the second file with the differences highlighted), patch (RFC 6902), or unified (a line diff of the formatted files)")
+	flags.Var(&options.ignorePaths, "ignore-path",
+		"with diff, leave out the differences at or inside this JSON Pointer, where * matches any key and ** any number of keys; can be given more than once")
+	flags.Float64Var(&options.tolerance, "tolerance", 0,
+		"with diff or --baseline, treat numbers as the same if they differ by at most this much, absolutely or relative to their size, such as 1e-9")
+	flags.StringVar(&options.arrayKey, "array-key", "",
+		"with diff or --baseline, match up the objects in arrays by this member, such as id, and show the ones that moved")
+	flags.StringVar(&options.strategy, "strategy", "deep",
+		"how merge combines files: deep, last-wins (top-level members only), or concat (deep, joining arrays)")
+	flags.BoolVar(&options.flatten, "flatten", false,
+		"render the document as a single-level object keyed by the path of each value")
+	flags.BoolVar(&options.unflatten, "unflatten", false,
+		"turn a single-level object keyed by paths back into nested objects and arrays")
+	flags.StringVar(&options.pathStyle, "path-style", "dot",
+		"how --flatten writes paths: dot (a.0.b, with dots in keys escaped as \\.) or pointer (/a/0/b)")
+	flags.BoolVar(&options.annotateTypes, "annotate-types", false,
+		"show a badge with the type of each value, such as str, int, or obj{3}")
+	flags.BoolVar(&options.explain, "explain", false,
+		"explain what each value is after it, such as \"object with 3 members\" or \"array of 5 strings\", for beginners and documentation")
+	flags.StringVar(&options.schemaFile, "schema", "",
+		"document the values in the page with this JSON Schema: titles and descriptions next to them, and types and constraints in tooltips")
+	flags.StringVar(&options.protoDescriptor, "proto-descriptor", "",
+		"check the document against the protobuf JSON mapping of a message in this descriptor set, from protoc --descriptor_set_out, annotating each field with its type and flagging unknown fields and values of the wrong type")
+	flags.StringVar(&options.protoMessage, "proto-message", "",
+		"the full name of the message that --proto-descriptor checks the document as, such as pkg.Request, instead of the first message of the last file")
+	flags.IntVar(&options.records, "records", 10,
+		"how many records of an Avro data file preview renders after its schema")
+	flags.BoolVar(&options.har, "har", false,
+		"read the input as an HTTP Archive (HAR) and list its requests with their method, URL, and status, and render their bodies that are JSON")
+	flags.StringVar(&options.input, "input", "json",
+		"what the input is: json, or msgpack, cbor, or bson, whose values are rendered as JSON with binary data in base64 and the types JSON does not have annotated, or csv, which is rendered as an array with an object for each row")
+	flags.StringVar(&options.headers, "headers", "",
+		"the names of the columns of CSV input, by commas, for input that has no header row")
+	flags.StringVar(&options.delimiter, "delimiter", "",
+		"the character that separates the fields of CSV input, or tab (default a tab for .tsv files and a comma otherwise)")
+	flags.BoolVar(&options.noInferTypes, "no-infer-types", false,
+		"keep every field of CSV input a string instead of reading numbers, true and false, and empty fields as those values")
+	flags.StringVar(&options.timestampForm, "normalize-timestamps", "",
+		"annotate each ISO 8601 time and Unix epoch in seconds, milliseconds, microseconds, or nanoseconds with the time in local, utc, or relative form, such as 3h ago")
+	flags.BoolVar(&options.showIndexes, "show-indexes", false,
+		"label each array element with a faint [0], [1], ... marker")
+	flags.BoolVar(&options.k8s, "k8s", false,
+		"arrange Kubernetes objects for reading: label each with its kind, name, and apiVersion, put apiVersion, kind, metadata, spec, and status first, and fold managedFields")
+	flags.BoolVar(&options.geoJSON, "geojson", false,
+		"for GeoJSON, draw a small map of its geometries above it, annotate the coordinates of large geometries with how many there are, and report the features and coordinates")
+	flags.BoolVar(&options.heatmap, "heatmap", false,
+		"tint the background of each object and array in the page by how many bytes of the document it is, and show its path and size when hovered over")
+	flags.IntVar(&options.sizeReport, "size-report", 0,
+		"instead of rendering the document, print this many of its largest values with their size as compact JSON, their share of the document, and their JSON Pointer")
+	flags.StringVar(&options.duplicates, "duplicates", "",
+		"report the objects and arrays that are the same as one earlier in the document, or with reference, also render them empty with where they are the same as")
+	flags.StringVar(&options.preset, "preset", "",
+		"set the flags for a kind of document, which the flags given can override: cloud-cli (AWS, GCP, and Azure CLI output: sorted keys, errors and statuses highlighted, ARN lists folded, and encoded policies truncated) or package-json (package.json and composer.json keys in the conventional order instead of sorted, for fmt)")
+	flags.BoolVar(&options.collapsible, "collapsible", false,
+		"let objects and arrays be folded by clicking their opening bracket")
+	flags.StringVar(&options.format, "format", "html",
+		"what to render: html (the document), ansi (the document for a terminal), jsonschema (a JSON Schema inferred from the document), pdf, png, "+
+			"slack or discord (messages for a chat tool), github-annotations or sarif (the problems in the document, for CI), "+
+			"table (arrays of objects as tables that can be sorted by clicking a column, and other documents as html), "+
+			"or dot or mermaid (a Graphviz or Mermaid diagram of the objects and arrays in the document)")
+	flags.BoolVar(&options.samples, "samples", false,
+		"with --format=jsonschema, infer the schema of the elements of a top-level array")
+	flags.IntVar(&options.sample, "sample", 0,
+		"only render this many elements of each larger array, marking the ones left out")
+	flags.StringVar(&options.sampleMode, "sample-mode", "first",
+		"which elements --sample keeps: first, last, or random")
+	flags.Int64Var(&options.sampleSeed, "sample-seed", 1,
+		"seed for --sample-mode=random, so that the same elements are chosen every time")
+	flags.IntVar(&options.maxRenderDepth, "max-render-depth", 0,
+		"fold every object and array nested this many levels deep into {…} or […], which can be unfolded by clicking")
+	flags.BoolVar(&options.anchors, "anchors", false,
+		"give each value an id from its JSON Pointer, so that page.html#/items/2/name links to it")
+	flags.BoolVar(&options.keyboardNav, "keyboard-nav", false,
+		"add --anchors and let j/k move to the next/previous sibling value and h to the containing value")
+	flags.BoolVar(&options.check, "check", false,
+		"with fmt, only check that the files are formatted and leave them as they are")
+	flags.Var(&options.exclude, "exclude",
+		"with fmt, skip the files that match this pattern, in .gitignore syntax; can be given more than once")
+	flags.StringVar(&options.reportFile, "report", "",
+		"write the problems found in the input, such as duplicate keys, to this file as JSON")
+	flags.StringVar(&options.outputDir, "output-dir", "",
+		"render each file, and the .json files in directories, to its own page in this directory, with an index.html")
+	flags.StringVar(&options.teeFile, "tee", "",
+		"copy the raw input to this file as it is read, such as when reading standard input with -")
+	flags.StringVar(&options.sourceMapFile, "source-map", "",
+		"write a JSON map from each rendered line and column to the byte range it came from in the input")
+	flags.StringVar(&options.outputTemplate, "output-template", "",
+		"render with this Go text/template file, which gets the parsed values and tokens, instead of as a page")
+	flags.StringVar(&options.plugin, "plugin", "",
+		"render with this output plugin, a program that reads the tokens as JSON and prints the output")
+	flags.BoolVar(&options.printFriendly, "print-friendly", false,
+		"render the page in black on white and avoid page breaks inside small objects, for printing or PDF")
+	flags.StringVar(&options.assetsDir, "assets-dir", "",
+		"write the styles and scripts that pages need to this directory and link to them, instead of putting them in every page, which makes many pages smaller")
+	flags.BoolVar(&options.embedRaw, "embed-raw", false,
+		"put the input in the page, with buttons to view it and download it, so the page is all that needs to be shared")
+	flags.BoolVar(&options.sideBySide, "side-by-side", false,
+		"show the input as it was written next to the formatted output, in two panes that scroll together, for teaching and reviews")
+	flags.StringVar(&options.fontFile, "font", "",
+		"with --format=png, draw the text in this monospace BDF bitmap font instead of the built-in one")
+	flags.IntVar(&options.scale, "scale", 2,
+		"with --format=png, draw each pixel of the font as a square this many pixels wide")
+	flags.IntVar(&options.maxMessages, "max-messages", 0,
+		"with --format=slack or discord, print at most this many messages and say how much was left out")
+	flags.StringVar(&options.locale, "locale", "",
+		"write numbers, and the times that --normalize-timestamps and logs write out, as is usual in this locale, such as de-DE or auto, in the page, the terminal, and images only")
+	flags.StringVar(&options.theme, "theme", "pencil",
+		"the colors of the page: "+strings.Join(themeNames(), " or ")+", or the path of a theme file")
+	flags.StringVar(&options.serve, "serve", "",
+		"serve formatted JSON over HTTP at this address, such as :8080, instead of printing it")
+	flags.Int64Var(&options.maxBodySize, "max-body-size", 10<<20,
+		"with --serve, the largest request body in bytes that is read")
+	flags.IntVar(&options.rateLimit, "rate-limit", 120,
+		"with --serve, how many requests a minute each client address can make, or 0 for no limit")
+	flags.DurationVar(&options.requestTimeout, "request-timeout", 30*time.Second,
+		"with --serve, how long each request has to be read and answered")
+	flags.IntVar(&options.maxDepth, "max-depth", 1000,
+		"with --serve, how deeply the objects and arrays of a request can nest, or 0 for no limit")
+	flags.BoolVar(&options.clipboardIn, "clipboard-in", false,
+		"read the JSON from the system clipboard instead of a file")
+	flags.BoolVar(&options.clipboardOut, "clipboard-out", false,
+		"put the formatted result on the system clipboard instead of printing it")
+	flags.BoolVar(&options.watch, "watch", false,
+		"render the file again each time it changes, or with --serve reload its page, for a live view; add --baseline to see what drifted")
+	flags.BoolVar(&options.follow, "follow", false,
+		"keep reading the file or named pipe as it grows, like tail -f, and print each line of NDJSON for a terminal as soon as it is complete")
+	flags.StringVar(&options.fields, "fields", "",
+		"with --follow or logs, print only these fields of each record, separated by commas, such as time,level,msg,err, lined up in columns")
+	flags.BoolVar(&options.open, "open", false,
+		"when the output is not redirected, write it to a temporary file and open that in the default browser")
+	flags.BoolVar(&options.analyze, "analyze", false,
+		"only check that the input is valid and print a short report on it, without rendering it")
+	flags.BoolVar(&options.noProgress, "no-progress", false,
+		"never show progress on standard error, which is otherwise shown for inputs over 32 MB whose output is not a terminal")
+	flags.BoolVar(&options.verify, "verify", false,
+		"check that the output parses back to the same values before printing it, and fail if it does not")
+	flags.DurationVar(&options.timeout, "timeout", 0,
+		"give up on formatting after this long, such as 30s, rather than running as long as it takes")
+	flags.BoolVar(&options.verbose, "verbose", false,
+		"log how long reading, tokenizing, transforming, and rendering take to standard error")
+	flags.BoolVar(&options.debug, "debug", false,
+		"log what --verbose does along with the options that were set and other details")
+	flags.StringVar(&options.logFormat, "log-format", "text",
+		"how --verbose and --debug write the log: text or json (one object per line)")
+	flags.StringVar(&options.color, "color", "auto",
+		"when --format=ansi colors the text: auto (on terminals, unless NO_COLOR is set), always, or never")
+}
+
+// isTreeNeeded returns true if any of the options change the structure of the
+// documents, which requires them to be parsed
+func isTreeNeeded(options Options) bool {
+	return options.sortArrayBy != "" || options.transform != "" || options.renameKeysFile != "" || options.coerceTypes || options.substituteEnv ||
+		options.pseudonymize != "" || options.pruneNulls || options.pruneEmpty || options.patchFile != "" ||
+		options.mergePatchFile != "" || options.baselineFile != "" || options.flatten || options.unflatten || options.sortKeys ||
+		options.annotateTypes || options.explain || options.protoDescriptor != "" || options.k8s || options.preset != "" || options.geoJSON || options.duplicates != "" || options.timestampForm != "" || options.showIndexes || options.format == "jsonschema" ||
+		options.sample > 0
+}
+
+// transformTree applies every structural change chosen in the options to the
+// tree of a single document and returns the new tree, since some changes can
+// replace the whole document. What they changed is reported to the report
+// writer.
+func transformTree(root *Node, options Options, report io.Writer) (*Node, error) {
+	if options.patchFile != "" {
+		patch, err := readJSONFile(options.patchFile, options)
+		if err != nil {
+			return nil, err
+		}
+
+		root, err = applyPatch(root, patch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.mergePatchFile != "" {
+		patch, err := readJSONFile(options.mergePatchFile, options)
+		if err != nil {
+			return nil, err
+		}
+		root = applyMergePatch(root, patch, options.highlightChanges)
+	}
+
+	// Values that differ from the baseline are highlighted as diff would
+	// highlight them, before anything moves them around
+	if options.baselineFile != "" {
+		baseline, err := readJSONFile(options.baselineFile, options)
+		if err != nil {
+			return nil, err
+		}
+		root = highlightChanges(root, diffNodes(baseline, root, []string{}, options))
+	}
+
+	if options.renameKeysFile != "" {
+		mapping, err := readJSONFile(options.renameKeysFile, options)
+		if err != nil {
+			return nil, err
+		}
+		rules, err := readRenameRules(mapping)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(report, "Renamed %d key(s)\n", renameKeys(root, rules, []string{}))
 	}
 
-	tokenArray := getTokens(jsonFile) // Tokenize the JSON file
-	printHeader()                     // Print the HTML header
-	printTokens(tokenArray)           // Style and print each token
-	printFooter()                     // Print the HTML footer
+	if options.substituteEnv {
+		substituted, err := substituteEnv(root, []string{}, options.allowMissing, func(line string) {
+			fmt.Fprintln(report, line)
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(report, "Substituted %d placeholder(s)\n", substituted)
+	}
+
+	if options.coerceTypes {
+		coerced := coerceTypes(root, []string{})
+		for _, description := range coerced {
+			fmt.Fprintln(report, "Coerced "+description)
+		}
+		fmt.Fprintf(report, "Coerced %d value(s)\n", len(coerced))
+	}
+
+	if options.pseudonymize != "" {
+		keys := make(map[string]bool)
+		for _, key := range strings.Split(options.pseudonymize, ",") {
+			keys[strings.TrimSpace(key)] = true
+		}
+		fmt.Fprintf(report, "Pseudonymized %d value(s)\n", pseudonymizeValues(root, keys))
+	}
+
+	if options.pruneNulls || options.pruneEmpty {
+		fmt.Fprintf(report, "Pruned %d value(s)\n", pruneValues(root, options.pruneNulls, options.pruneEmpty))
+	}
+
+	if options.transform != "" {
+		filter, err := parseTransform(options.transform)
+		if err != nil {
+			return nil, err
+		}
+		if root, err = filter(root); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.sortArrayBy != "" {
+		sortArraysBy(root, strings.Split(options.sortArrayBy, "."))
+	}
+
+	if options.unflatten {
+		root = unflattenTree(root)
+	}
+	if options.flatten {
+		root = flattenTree(root, options.pathStyle)
+	}
+	if options.sortKeys {
+		sortKeys(root)
+	}
+	if options.preset == "package-json" && !orderPackageKeys(root, options.fileName) {
+		return nil, errors.New("--preset=package-json needs a document that is an object")
+	}
+	if isCloudPreset(options) {
+		fmt.Fprintf(report, "Marked %d cloud value(s)\n", markCloudValues(root, options.format != "html"))
+	}
+	if options.k8s {
+		fmt.Fprintf(report, "Arranged %d Kubernetes object(s)\n", arrangeK8s(root, options.format != "html"))
+	}
+
+	// Annotations describe the final document, so they are added last, but
+	// before sampling so that indexes and sizes match the whole document
+	if options.proto != nil {
+		problems, err := checkProto(root, options.proto, options.protoMessage)
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(report, "Protobuf "+problem)
+		}
+		fmt.Fprintf(report, "Found %d protobuf problem(s)\n", len(problems))
+	}
+	if options.annotateTypes {
+		annotateTypes(root)
+	}
+	if options.explain {
+		explainTree(root)
+	}
+	if options.timestampForm != "" {
+		fmt.Fprintf(report, "Annotated %d timestamp(s)\n", annotateTimestamps(root, options.timestampForm, options.timeFormat, time.Now()))
+	}
+	if options.showIndexes {
+		annotateIndexes(root)
+	}
+	if options.geoJSON && isGeoJSON(root) {
+		features, positions := summarizeGeoJSON(root)
+		fmt.Fprintf(report, "Found GeoJSON %s with %d feature(s) and %d coordinate(s)\n", geoType(root), features, positions)
+	}
+	if options.duplicates != "" {
+		duplicates := findDuplicates(root, options.duplicates == "reference")
+		for _, duplicate := range duplicates {
+			fmt.Fprintln(report, "Duplicate "+duplicate)
+		}
+		fmt.Fprintf(report, "Found %d duplicate value(s)\n", len(duplicates))
+	}
+	if options.sample > 0 {
+		sampleArrays(root, options.sample, options.sampleMode, rand.New(rand.NewSource(options.sampleSeed)))
+	}
+
+	return root, nil
 }
 
-// Token carries a kind (which is an ID) and the content of the token
+// Token carries a kind (which is an ID), the content of the token, and where it
+// was found
 type Token struct {
-	content string
-	kind    int
+	content   string
+	kind      TokenKind
+	offset    int // Where the token starts in the input, or -1 if it was added
+	highlight int // How the token stands out from the rest, or 0 if it does not
+}
+
+// Kind returns what kind of token it is
+func (token Token) Kind() TokenKind {
+	return token.kind
 }
 
+// Content returns the text of the token
+func (token Token) Content() string {
+	return token.content
+}
+
+// Offset returns where the token starts in the input, or -1 if it was added
+// rather than read from the input
+func (token Token) Offset() int {
+	return token.offset
+}
+
+// TokenKind is the kind of a token. The values are stable, and so are the
+// names that String returns for them.
+type TokenKind int
+
 // Token types are listed here for document readability, this idea taken
 // from http://www.cs.sfu.ca/CourseCentral/383/tjd/syntaxAndEBNF.html
 const (
 	// Parenthesis token types: '{', '}', '[', ']'
-	ObjectOpen  = 11
-	ObjectClose = 12
-	ArrayOpen   = 13
-	ArrayClose  = 14
+	ObjectOpen  TokenKind = 11
+	ObjectClose TokenKind = 12
+	ArrayOpen   TokenKind = 13
+	ArrayClose  TokenKind = 14
 
 	// Delimiter token types: ':', ','
-	DelimiterPair   = 21
-	DelimiterMember = 22
+	DelimiterPair   TokenKind = 21
+	DelimiterMember TokenKind = 22
 
 	// String token types, either string, escaped string, or the special case
 	// StringClose, which is for a single quote at the end of a string: '"'
-	StringRegular = 31
-	StringEscaped = 32
-	StringClose   = 33
+	StringRegular TokenKind = 31
+	StringEscaped TokenKind = 32
+	StringClose   TokenKind = 33
 
 	// Number token type
-	Number = 41
+	Number TokenKind = 41
 
 	// Literal token types: 'true', 'false', 'null'
-	LiteralBoolTrue  = 51
-	LiteralBoolFalse = 52
-	LiteralNull      = 53
+	LiteralBoolTrue  TokenKind = 51
+	LiteralBoolFalse TokenKind = 52
+	LiteralNull      TokenKind = 53
+
+	// Comment token type: '// ...' or '/* ... */', only with --allow-comments
+	Comment TokenKind = 61
+
+	// Annotation token type, which is never read from the input but is added
+	// next to values to describe them, such as the badges of --annotate-types
+	Annotation TokenKind = 71
+
+	// Error token types, which are only added by --show-errors: the message of
+	// the first error in the input, which is never read from it, and the rest
+	// of the input after the error, which is kept as it was written
+	ErrorMessage TokenKind = 81
+	Unparsed     TokenKind = 82
+
+	// White space token type, for the white space between tokens as it was
+	// written, which --preserve-layout keeps instead of the standard layout
+	WhiteSpace TokenKind = 91
+)
+
+// tokenKindNames are the names of the token kinds
+var tokenKindNames = map[TokenKind]string{
+	ObjectOpen:       "ObjectOpen",
+	ObjectClose:      "ObjectClose",
+	ArrayOpen:        "ArrayOpen",
+	ArrayClose:       "ArrayClose",
+	DelimiterPair:    "DelimiterPair",
+	DelimiterMember:  "DelimiterMember",
+	StringRegular:    "StringRegular",
+	StringEscaped:    "StringEscaped",
+	StringClose:      "StringClose",
+	Number:           "Number",
+	LiteralBoolTrue:  "LiteralBoolTrue",
+	LiteralBoolFalse: "LiteralBoolFalse",
+	LiteralNull:      "LiteralNull",
+	Comment:          "Comment",
+	Annotation:       "Annotation",
+	ErrorMessage:     "ErrorMessage",
+	Unparsed:         "Unparsed",
+	WhiteSpace:       "WhiteSpace",
+}
+
+// String returns the name of the kind, which is the name of its constant, or
+// TokenKind(n) if it is not a known kind
+func (kind TokenKind) String() string {
+	if name, ok := tokenKindNames[kind]; ok {
+		return name
+	}
+	return "TokenKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// ParseKind returns the kind with the name that String returns for it
+func ParseKind(name string) (TokenKind, error) {
+	for kind, kindName := range tokenKindNames {
+		if kindName == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown token kind %q", name)
+}
+
+// Highlight types mark the tokens of values that were added, changed, or
+// removed, such as the locations touched by a patch
+const (
+	HighlightAdded   = 1
+	HighlightChanged = 2
+	HighlightRemoved = 3
+
+	// HighlightSynthetic marks tokens that are not in the input but were
+	// added to finish it, such as the brackets that --allow-truncated closes
+	HighlightSynthetic = 4
+
+	// HighlightOurs and HighlightTheirs mark the two sides of a conflict that
+	// merge3 could not resolve
+	HighlightOurs   = 5
+	HighlightTheirs = 6
+
+	// HighlightExplanation marks the explanations of --explain, which are
+	// set in italics
+	HighlightExplanation = 7
+
+	// HighlightAlert marks values that need attention, such as the errors
+	// and failed statuses that --preset=cloud-cli finds
+	HighlightAlert = 8
 )
 
+// Tokenize splits the input into tokens. It accepts any bytes at all, which
+// makes it safe to point a fuzzer at: it never panics, it never reads past the
+// end of the input, and it always finishes, because every step consumes at
+// least one byte. Characters that cannot start a token are skipped, and a
+// string or number that is cut off by the end of the input ends there.
+func Tokenize(input []byte, options Options) []Token {
+	return getTokens(input, options)
+}
+
+// TokenizeContext is Tokenize that stops once the context is done, returning
+// the tokens found so far with the context's error, as FormatContext does
+func TokenizeContext(ctx context.Context, input []byte, options Options) ([]Token, error) {
+	return tokenize(ctx, input, options)
+}
+
 // getTokens returns an array of tokens from the file that is passed in
-func getTokens(jsonFile []byte) []Token {
+func getTokens(jsonFile []byte, options Options) []Token {
+	tokenArray, _ := tokenize(context.Background(), jsonFile, options)
+	return tokenArray
+}
+
+// tokenize is getTokens that stops with the context's error once the context
+// is done
+func tokenize(ctx context.Context, jsonFile []byte, options Options) ([]Token, error) {
 	tokenArray := make([]Token, 0) // In case the file is of 0 length
+	nextProgress := 0              // Where the progress is updated next
+	pool := newStringPool()        // Repeated keys share their memory
 
 	// Iterate over every character in the file
 	for i := 0; i < len(jsonFile); {
-		currentCharacter := string(jsonFile[i])
+		if i >= nextProgress {
+			if err := ctx.Err(); err != nil {
+				return tokenArray, err
+			}
+			options.progress.update("tokenize", int64(i), int64(len(jsonFile)))
+			nextProgress = i + 1<<16
+		}
+
+		currentCharacter := string(jsonFile[i : i+1])
 
 		// These are the default token characteristics
 		tokenContent := currentCharacter
-		tokenKind := 0
+		var tokenKind TokenKind
 		tokenLength := 1
 		isToken := true
 
@@ -77,6 +1248,7 @@ func getTokens(jsonFile []byte) []Token {
 		isString := false
 		isStringRegular := false
 		isNumber := false
+		isComment := false
 
 		// Check the previous token to determine whether or not this token is
 		// part of a previous string, which would restrict the possible types to
@@ -150,6 +1322,17 @@ func getTokens(jsonFile []byte) []Token {
 				tokenKind = LiteralNull
 				tokenContent = "null"
 				tokenLength = 4
+			case "/":
+				// Comments are only recognized when they are allowed; a lone
+				// '/' is ignored like any other invalid character
+				isNextCommentCharacter := i+1 < len(jsonFile) &&
+					(jsonFile[i+1] == '/' || jsonFile[i+1] == '*')
+				if options.allowComments && isNextCommentCharacter {
+					tokenKind = Comment
+					isComment = true
+				} else {
+					isToken = false
+				}
 			default:
 				// Ignore all whitespace and unreadable or invalid characters
 				isToken = false
@@ -167,21 +1350,25 @@ func getTokens(jsonFile []byte) []Token {
 				}
 			case "\\":
 				tokenKind = StringEscaped
-				currentCharacter = string(jsonFile[i+1])
+
+				// A file that is cut off can end right after the '\', in
+				// which case the escape is left empty
+				if i+1 >= len(jsonFile) {
+					break
+				}
+				currentCharacter = string(jsonFile[i+1 : i+2])
 
 				if currentCharacter == "u" {
 					// If the current escape character is \u followed by a 4 digit
-					// hex string, we add those characters to the string
-					for j := i + 1; j < (i + 6); j++ {
-						currentCharacter = string(jsonFile[j])
-						tokenContent += currentCharacter
+					// hex string, we add those characters to the string, as
+					// many of them as there are before the end of the file
+					for j := i + 1; j < (i+6) && j < len(jsonFile); j++ {
 						tokenLength++
 					}
 				} else {
 					// If the current escape character is not \u, we add
 					// whatever follows the '\' to our string. We benefit from
 					// the valid input here.
-					tokenContent += currentCharacter
 					tokenLength++
 				}
 			default:
@@ -191,20 +1378,19 @@ func getTokens(jsonFile []byte) []Token {
 		}
 
 		// Given that this token is a StringRegular, we add all digits until we
-		// reach an escape character or a quote indicating the end of the string
+		// reach an escape character or a quote indicating the end of the string,
+		// or the end of the file if it was cut off
 		if isStringRegular {
 			isStringFinished := false
-			for j := i + 1; !isStringFinished; j++ {
-				currentCharacter = string(jsonFile[j])
+			for j := i + 1; !isStringFinished && j < len(jsonFile); j++ {
+				currentCharacter = string(jsonFile[j : j+1])
 				switch currentCharacter {
 				case "\"":
-					tokenContent += currentCharacter
 					tokenLength++
 					isStringFinished = true
 				case "\\":
 					isStringFinished = true
 				default:
-					tokenContent += currentCharacter
 					tokenLength++
 				}
 			}
@@ -230,11 +1416,10 @@ func getTokens(jsonFile []byte) []Token {
 				return false
 			}
 
-			for j := i + 1; !isNumberFinished; j++ {
-				currentCharacter = string(jsonFile[j])
+			for j := i + 1; !isNumberFinished && j < len(jsonFile); j++ {
+				currentCharacter = string(jsonFile[j : j+1])
 
 				if validNextNumCharacter(currentCharacter) {
-					tokenContent += currentCharacter
 					tokenLength++
 				} else {
 					isNumberFinished = true
@@ -242,69 +1427,278 @@ func getTokens(jsonFile []byte) []Token {
 			}
 		}
 
+		// Given that this token is a comment, it runs until the end of the line
+		// or until its closing '*/'
+		if isComment {
+			end := commentEnd(jsonFile, i)
+			tokenContent = string(bytes.TrimRight(jsonFile[i:end], "\r"))
+			tokenLength = end - i
+		}
+
+		// Strings, escapes, and numbers are their bytes of the input, which
+		// are copied once instead of a character at a time
+		if isStringRegular || isNumber || tokenKind == StringEscaped {
+			tokenContent = string(jsonFile[i : i+tokenLength])
+		}
+
+		// A key shares its content with the other keys that are the same,
+		// which a large document repeats in every record
+		if tokenKind == DelimiterPair && len(tokenArray) > 0 {
+			key := &tokenArray[len(tokenArray)-1]
+			if key.kind == StringRegular && len(key.content) >= 2 && strings.HasSuffix(key.content, "\"") {
+				key.content = pool.intern(key.content)
+			}
+		}
+
 		// Only save the token if it is a valid token. Whitespace, invalid
 		// characters, and unknown characters will be flagged false.
 		if isToken {
-			newToken := Token{tokenContent, tokenKind}
+			newToken := Token{content: tokenContent, kind: tokenKind, offset: i}
 			tokenArray = append(tokenArray, newToken)
 		}
 
 		i += tokenLength
 	}
 
-	return tokenArray
+	options.progress.update("tokenize", int64(len(jsonFile)), int64(len(jsonFile)))
+	return tokenArray, nil
 }
 
-// printTokens iterates the array of tokens properly and prints them to standard
-// output. It calls extra functions to help with HTML styling, but tracks
+// commentEnd returns the offset just past the comment that starts at start,
+// which is the end of the line for '//' and the closing '*/' for '/*'
+func commentEnd(jsonFile []byte, start int) int {
+	if jsonFile[start+1] == '/' {
+		if newline := bytes.IndexByte(jsonFile[start:], '\n'); newline >= 0 {
+			return start + newline
+		}
+		return len(jsonFile)
+	}
+
+	if closing := bytes.Index(jsonFile[start+2:], []byte("*/")); closing >= 0 {
+		return start + 2 + closing + 2
+	}
+	return len(jsonFile)
+}
+
+// isStringEnd returns true if the token closes the string it is part of. A
+// StringRegular token that opens a string is only one character long when the
+// string continues with an escape character, so isOpening must be passed in to
+// tell it apart from the closing quote of a string.
+func isStringEnd(token Token, isOpening bool) bool {
+	switch token.kind {
+	case StringClose:
+		return true
+	case StringRegular:
+		content := token.content
+		if isOpening && len(content) == 1 {
+			return false
+		}
+		return content[len(content)-1] == '"'
+	}
+	return false
+}
+
+// splitDocuments divides the tokens into top-level values. Inputs such as the
+// output of loggers or 'jq -c' contain several JSON values one after another,
+// and each of them is rendered as its own block.
+func splitDocuments(tokenArray []Token) [][]Token {
+	documents := make([][]Token, 0)
+	depth := 0          // How many containers are currently open
+	isInString := false // Is the current token part of an unfinished string
+	start := 0          // Where the current document starts
+	previousStart := 0  // Where the previous document started
+
+	for i, token := range tokenArray {
+		isValueEnd := false
+
+		switch token.kind {
+		case ObjectOpen, ArrayOpen:
+			depth++
+		case ObjectClose, ArrayClose:
+			// An unmatched closing bracket does not open a new document
+			if depth > 0 {
+				depth--
+			}
+			isValueEnd = depth == 0
+		case StringRegular, StringEscaped, StringClose:
+			isOpening := !isInString
+			isInString = !isStringEnd(token, isOpening)
+			isValueEnd = !isInString && depth == 0
+		case Number, LiteralBoolTrue, LiteralBoolFalse, LiteralNull:
+			isValueEnd = depth == 0
+		}
+
+		if isValueEnd {
+			documents = append(documents, tokenArray[start:i+1])
+			previousStart = start
+			start = i + 1
+		}
+	}
+
+	// Anything left over belongs to an unfinished value, which is still shown.
+	// Comments after the last value stay with that value instead.
+	if start < len(tokenArray) {
+		isOnlyComments := len(documents) > 0
+		for _, token := range tokenArray[start:] {
+			if token.kind != Comment {
+				isOnlyComments = false
+			}
+		}
+
+		if isOnlyComments {
+			last := len(documents) - 1
+			documents[last] = tokenArray[previousStart:]
+		} else {
+			documents = append(documents, tokenArray[start:])
+		}
+	}
+
+	return documents
+}
+
+// removeTrailingCommas returns the tokens without any DelimiterMember that is
+// followed by the end of its object or array, along with how many commas were
+// removed. Comments between the comma and the bracket do not count.
+func removeTrailingCommas(tokenArray []Token) ([]Token, int) {
+	fixedArray := make([]Token, 0, len(tokenArray))
+	fixedCount := 0
+
+	for i, token := range tokenArray {
+		if token.kind == DelimiterMember {
+			var nextKind TokenKind
+			for j := i + 1; j < len(tokenArray); j++ {
+				if tokenArray[j].kind != Comment {
+					nextKind = tokenArray[j].kind
+					break
+				}
+			}
+
+			if nextKind == ObjectClose || nextKind == ArrayClose {
+				fixedCount++
+				continue
+			}
+		}
+
+		fixedArray = append(fixedArray, token)
+	}
+
+	return fixedArray, fixedCount
+}
+
+// printTokens iterates the array of tokens properly and prints them to the
+// writer. It calls extra functions to help with HTML styling, but tracks
 // indentation at this level.
-func printTokens(tokenArray []Token) {
-	indentationLevel := 0 // How many '\t' should be prepended
-	isToIndent := false   // Is this token to be indented
-	for _, token := range tokenArray {
-		fmt.Print(styleHTML(token, &indentationLevel, &isToIndent))
+func printTokens(ctx context.Context, w io.Writer, tokenArray []Token, decorations []Decoration, options Options) error {
+	colors := pageTheme(options)
+	state := printState{isLineStart: true}
+	for i, token := range tokenArray {
+		// Each level of nesting indents every line inside it further, so the
+		// context is checked on the way in as well
+		if i%4096 == 0 || token.kind == ObjectOpen || token.kind == ArrayOpen {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if token.offset >= 0 {
+			options.progress.update("render", int64(token.offset), options.inputSize)
+		}
+		fmt.Fprint(w, styleHTML(token, decorations[i], &state, colors, options.styleHooks))
 	}
+	return nil
+}
+
+// printState tracks the layout of the output from one token to the next
+type printState struct {
+	isLaidOut        bool      // Do the tokens carry the white space of the input
+	indentationLevel int       // How many '\t' should be prepended
+	isToIndent       bool      // Is this token to be indented
+	isLineStart      bool      // Is this token the first thing on its line
+	previousKind     TokenKind // The kind of the token printed before this one
+	tabs             string    // Tabs that indentation cuts the indents from
+}
+
+// indentation returns an indent of level tabs. The tabs are only written out
+// again when the nesting goes deeper than they reach, so that indenting each
+// token does not take longer the deeper it is.
+func (state *printState) indentation(level int) string {
+	if level <= 0 {
+		return ""
+	}
+	if len(state.tabs) < level {
+		state.tabs = strings.Repeat("\t", 2*level)
+	}
+	return state.tabs[:level]
 }
 
 // styleHTML calls other functions to help with HTML styling and combines their
 // outputs into a single string
-func styleHTML(token Token, indentationLevel *int, isToIndent *bool) string {
-	colorPre, colorPost := addColor(token)
-	whiteSpacePre, whiteSpacePost := addWhiteSpace(token, indentationLevel, isToIndent)
+func styleHTML(token Token, decoration Decoration, state *printState, colors theme, hooks styleHooks) string {
+	colorPre, colorPost := addColor(token, colors)
+	colorPre, colorPost = applyStyleHook(token, hooks, colorPre, colorPost)
+	whiteSpacePre, whiteSpacePost := addWhiteSpace(token, state)
 	escapedString := escapeString(token)
-	return whiteSpacePre + colorPre + escapedString + colorPost + whiteSpacePost
+	return whiteSpacePre + decoration.before + colorPre + escapedString + colorPost +
+		decoration.after + whiteSpacePost
 }
 
-// addColor outputs the <span> tags necessary to color each token. Most of the
-// base colors are taken from: https://github.com/reedes/vim-colors-pencil,
-// though the use of the colors is different
-func addColor(token Token) (string, string) {
+// addColor outputs the <span> tags necessary to color each token in the
+// colors of the theme
+func addColor(token Token, colors theme) (string, string) {
 	var colorPre, colorPost, color string
 	printInColor := true
 
 	switch token.kind {
 	case ObjectOpen, ObjectClose:
-		color = "#D75F5F"
+		color = colors.object
 	case ArrayOpen, ArrayClose:
-		color = "#10A778"
+		color = colors.array
 	case DelimiterPair:
-		color = "#005F87"
+		color = colors.pair
 	case DelimiterMember:
-		color = "#CCCCCC"
+		color = colors.member
 	case StringRegular, StringClose:
-		color = "#424242"
+		color = colors.text
 	case StringEscaped:
-		color = "#C30771"
+		color = colors.escape
 	case Number: // Number
-		color = "#6855DE"
+		color = colors.number
 	case LiteralBoolTrue, LiteralBoolFalse, LiteralNull: // Literals
-		color = "#20A5BA"
+		color = colors.literal
+	case Comment:
+		color = colors.comment
+	case Annotation:
+		color = colors.annotation + "; font-size:80%"
+	case ErrorMessage:
+		color = colors.background + "; background-color:" + colors.object + "; font-size:80%"
+	case Unparsed:
+		color = colors.comment + "; text-decoration:underline wavy " + colors.object
 	default:
 		printInColor = false
 	}
 
+	// Highlighted tokens also get a background color
+	var background string
+	switch token.highlight {
+	case HighlightAdded:
+		background = "; background-color:" + colors.added
+	case HighlightChanged:
+		background = "; background-color:" + colors.changed
+	case HighlightRemoved:
+		background = "; background-color:" + colors.removed + "; text-decoration:line-through"
+	case HighlightSynthetic:
+		background = "; outline:1px dashed " + colors.annotation
+	case HighlightOurs:
+		background = "; background-color:" + colors.added + "; outline:1px solid " + colors.array
+	case HighlightTheirs:
+		background = "; background-color:" + colors.changed + "; outline:1px solid " + colors.number
+	case HighlightExplanation:
+		background = "; font-style:italic"
+	case HighlightAlert:
+		background = "; background-color:" + colors.removed + "; font-weight:bold"
+	}
+
 	if printInColor {
-		colorPre = "<span style=\"color:" + color + "\">"
+		colorPre = "<span style=\"color:" + color + background + "\">"
 		colorPost = "</span>"
 	}
 
@@ -314,33 +1708,84 @@ func addColor(token Token) (string, string) {
 // addWhiteSpace adds white space before and after the token to ensure
 // consistent styling. Spacing direction is generally based on the spacing style
 // used at https://jsonformatter.curiousconcept.com
-func addWhiteSpace(token Token, indentationLevel *int, isToIndent *bool) (string, string) {
-	var whiteSpacePre, whiteSpacePost, indentString string
+func addWhiteSpace(token Token, state *printState) (string, string) {
+	var whiteSpacePre, whiteSpacePost, indentString, lineIndentString string
 
-	for i := 1; i < *indentationLevel; i++ {
-		indentString += "\t"
+	// A document that starts with white space from the input carries all of
+	// its own layout (see preserveLayout)
+	if token.kind == WhiteSpace && state.previousKind == 0 {
+		state.isLaidOut = true
 	}
+	if state.isLaidOut {
+		state.previousKind = token.kind
+		return "", ""
+	}
+
+	indentString = state.indentation(state.indentationLevel - 1)
+
+	// Top-level lines are not indented at all
+	lineIndentString = state.indentation(state.indentationLevel)
 
-	if *isToIndent {
-		whiteSpacePre = indentString + "\t"
+	if state.isToIndent {
+		whiteSpacePre = lineIndentString
 	}
 
-	*isToIndent = false
+	isLineStart := state.isToIndent || state.isLineStart
+	previousKind := state.previousKind
+	state.isToIndent = false
+	state.isLineStart = false
+	state.previousKind = token.kind
 
 	switch token.kind {
 	case ObjectOpen, ArrayOpen:
 		whiteSpacePost = "\n"
-		*indentationLevel++
-		*isToIndent = true
+		state.indentationLevel++
+		state.isToIndent = true
 	case ObjectClose, ArrayClose:
 		whiteSpacePre = "\n" + indentString
-		*indentationLevel--
+
+		// A comment has already finished the line before this token
+		if previousKind == Comment {
+			whiteSpacePre = indentString
+		}
+
+		state.indentationLevel--
 	case DelimiterPair:
 		whiteSpacePre = " "
 		whiteSpacePost = " "
 	case DelimiterMember:
 		whiteSpacePost = "\n"
-		*isToIndent = true
+		state.isToIndent = true
+	case Annotation:
+		// Annotations sit on the same line as the value they describe, either
+		// before it as a label or after it as a badge
+		if isLineStart {
+			whiteSpacePost = " "
+		} else {
+			whiteSpacePre = " "
+		}
+	case ErrorMessage:
+		// The message sits where the error is, just before the rest of the
+		// input, which is printed as it was written
+		if !isLineStart && previousKind != DelimiterPair {
+			whiteSpacePre = " "
+		}
+		whiteSpacePost = " "
+	case Comment:
+		// Comments are attached to the value that follows them, so each one
+		// sits on its own line at the indentation of that value. A block
+		// comment between a key and its value can stay on the key's line.
+		isBlockComment := strings.HasPrefix(token.content, "/*")
+		if previousKind == DelimiterPair && isBlockComment {
+			whiteSpacePost = " "
+			break
+		}
+
+		if !isLineStart {
+			whiteSpacePre = "\n" + lineIndentString
+		}
+		whiteSpacePost = "\n"
+		state.isToIndent = true
 	}
 
 	return whiteSpacePre, whiteSpacePost
@@ -372,22 +1817,177 @@ func escapeString(token Token) string {
 	return escapedString
 }
 
-// printHeader prints a standard HTML header, sets the background color, and
-// sets up the text styling
-func printHeader() {
-	fmt.Println("<!doctype html>")
-	fmt.Println("<html>")
-	fmt.Println("\t" + "<head>")
-	fmt.Println("\t\t" + "<title>Assignment 2 - Colorized JSON</title>")
-	fmt.Println("\t" + "</head>")
-	fmt.Println("\t" + "<body style=\"background-color:#F1F1F1\">")
-	fmt.Println("\t\t" + "<span style=\"font-family:monospace; tab-size:4; white-space:pre\">")
+// printPage prints a full HTML page with each top-level value as its own block
+func printPage(w io.Writer, documents [][]Token, options Options) {
+	printPageContext(context.Background(), w, documents, options)
+}
+
+// printPageContext is printPage that stops with the context's error once the
+// context is done, leaving the page unfinished
+func printPageContext(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	documents = localizeDocuments(documents, options)
+	printHeader(w, options) // Print the HTML header
+	if options.embedRaw && options.rawInput != nil {
+		printRawSource(w, options)
+	}
+	if isSideBySide(options) {
+		printSideBySideStart(w, options)
+	}
+
+	// Style and print each top-level value as its own block
+	for i, document := range documents {
+		if i > 0 {
+			printSeparator(w, options)
+		}
+		if err := printDocument(ctx, w, document, options); err != nil {
+			return err
+		}
+	}
+
+	if isSideBySide(options) {
+		printSideBySideEnd(w)
+	}
+	printFooter(w) // Print the HTML footer
+	return nil
+}
+
+// printText prints the tokens with the same layout as the HTML output but
+// without any markup, which gives plain, indented JSON
+func printText(w io.Writer, tokenArray []Token) {
+	fmt.Fprint(w, formatText(tokenArray))
+}
+
+// formatText returns the text that printText prints
+func formatText(tokenArray []Token) string {
+	var text strings.Builder
+	state := printState{isLineStart: true}
+	for _, token := range tokenArray {
+		whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+		text.WriteString(whiteSpacePre + token.content + whiteSpacePost)
+	}
+	text.WriteString("\n")
+	return text.String()
+}
+
+// isFoldable returns true if the options call for containers that can be
+// folded and unfolded in the page
+func isFoldable(options Options) bool {
+	return options.collapsible || options.maxRenderDepth > 0 || options.k8s
+}
+
+// printHeader prints a standard HTML header and sets the background color. The
+// styles and scripts for folding and anchors are only added when they are
+// needed.
+func printHeader(w io.Writer, options Options) {
+	fmt.Fprintln(w, "<!doctype html>")
+	fmt.Fprintln(w, "<html>")
+	fmt.Fprintln(w, "\t"+"<head>")
+	fmt.Fprintln(w, "\t\t"+"<title>Assignment 2 - Colorized JSON</title>")
+	if isFoldable(options) {
+		printAsset(w, "fold.css", options)
+		printAsset(w, "fold.js", options)
+	}
+	if isAnchored(options) {
+		printAsset(w, "anchor.css", options)
+	}
+	if options.keyboardNav {
+		printAsset(w, "anchor.js", options)
+	}
+	if options.embedRaw && options.rawInput != nil {
+		printAsset(w, "raw.js", options)
+	}
+	if isSideBySide(options) {
+		printAsset(w, "side-by-side.css", options)
+		printAsset(w, "side-by-side.js", options)
+	}
+	if options.printFriendly {
+		printAsset(w, "print.css", options)
+	}
+	if isCloudPreset(options) {
+		printAsset(w, "expander.css", options)
+		printAsset(w, "expander.js", options)
+	}
+	if options.format == "table" {
+		printAsset(w, "table.css", options)
+		printAsset(w, "table.js", options)
+	}
+	if options.watch && options.serve != "" {
+		printAsset(w, "watch.js", options)
+	}
+	fmt.Fprintln(w, "\t"+"</head>")
+	fmt.Fprintln(w, "\t"+"<body style=\"background-color:"+pageTheme(options).background+"\">")
+}
+
+// printDocument sets up the text styling for a single top-level value and
+// prints its tokens
+func printDocument(ctx context.Context, w io.Writer, tokenArray []Token, options Options) error {
+	if options.printFriendly {
+		return printFriendlyDocument(ctx, w, tokenArray, options)
+	}
+
+	// The map goes above the document, which has to be read again for it
+	if options.geoJSON {
+		if root, err := parseTokensContext(ctx, valueTokens(tokenArray)); err == nil && isGeoJSON(root) {
+			printGeoPreview(w, root, pageTheme(options))
+		}
+	}
+
+	decorations := make([]Decoration, len(tokenArray))
+	if isFoldable(options) {
+		folded := make(map[int]bool)
+		if options.k8s {
+			folded = k8sFolded(tokenArray)
+		}
+		var expanders []Decoration
+		if isCloudPreset(options) {
+			var arnLists map[int]bool
+			expanders, arnLists = cloudDecorations(tokenArray)
+			for open := range arnLists {
+				folded[open] = true
+			}
+		}
+		decorations = foldDecorations(tokenArray, options.maxRenderDepth, folded)
+		if expanders != nil {
+			decorations = wrapDecorations(expanders, decorations)
+		}
+	}
+	if options.heatmap {
+		decorations = wrapDecorations(heatmapDecorations(tokenArray, pageTheme(options)), decorations)
+	}
+	if isAnchored(options) {
+		// Anchors go around the folding markup so that a folded value can
+		// still be linked to
+		decorations = wrapDecorations(anchorDecorations(tokenArray), decorations)
+	}
+	if options.schema != nil {
+		decorations = wrapDecorations(schemaDecorations(tokenArray, options.schema, pageTheme(options)), decorations)
+	}
+
+	fmt.Fprintln(w, "\t\t"+"<span style=\"font-family:monospace; tab-size:4; white-space:pre\">")
+	if err := printTokens(ctx, w, tokenArray, decorations, options); err != nil {
+		return err
+	}
+	fmt.Fprint(w, "\n")
+	fmt.Fprintln(w, "\t\t"+"</span>")
+	return nil
+}
+
+// printSeparator prints the rule that separates two top-level values
+func printSeparator(w io.Writer, options Options) {
+	fmt.Fprintln(w, "\t\t"+"<hr style=\"border:none; border-top:1px dashed "+pageTheme(options).separator+"\">")
+}
+
+// pageTheme returns the theme that the options chose, which was loaded when
+// the options were read, in black on white for --print-friendly
+func pageTheme(options Options) theme {
+	if options.printFriendly {
+		return printFriendlyTheme(options.colors)
+	}
+	return options.colors
 }
 
 // printFooter prints a standard HTML footer
-func printFooter() {
-	fmt.Print("\n")
-	fmt.Println("\t\t" + "</span>")
-	fmt.Println("\t" + "</body>")
-	fmt.Println("</html>")
+func printFooter(w io.Writer) {
+	fmt.Fprintln(w, "\t"+"</body>")
+	fmt.Fprintln(w, "</html>")
 }
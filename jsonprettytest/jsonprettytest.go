@@ -0,0 +1,90 @@
+// Package jsonprettytest compares the output of json-pretty-printer against
+// golden files in Go tests, so that projects which render JSON with it can
+// keep their pages and terminal output as fixtures.
+//
+// The formatter is a command, so the helper runs it the way users do: the
+// program named by Program is looked up on the PATH and given the input on
+// its standard input. Run the tests with -update to write the golden files
+// from the current output instead of comparing against them.
+package jsonprettytest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Program is the formatter that is run, by name or path
+var Program = "json-pretty-printer"
+
+// update is -update, which writes the golden files instead of comparing
+var update = flag.Bool("update", false, "write the golden files of jsonprettytest from the current output")
+
+// AssertFormatted formats the input and fails the test if the output is not
+// the same as the golden file, or writes the golden file with -update. Golden
+// files that end in .ansi or .txt get the colored text for a terminal
+// (--format=ansi --color=always), and anything else gets the HTML page. Any
+// flags are passed to the formatter before the input, such as "--collapsible".
+func AssertFormatted(t testing.TB, input []byte, goldenPath string, flags ...string) {
+	t.Helper()
+
+	arguments := make([]string, 0, len(flags)+3)
+	switch filepath.Ext(goldenPath) {
+	case ".ansi", ".txt":
+		arguments = append(arguments, "--format=ansi", "--color=always")
+	}
+	arguments = append(arguments, flags...)
+	arguments = append(arguments, "-")
+
+	var output, stderr bytes.Buffer
+	command := exec.Command(Program, arguments...)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = &output
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		t.Fatalf("%s %s failed: %v\n%s", Program, strings.Join(arguments, " "), err, stderr.String())
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(goldenPath, output.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to write it)", err)
+	}
+	if !bytes.Equal(output.Bytes(), golden) {
+		line, want, got := firstDifference(string(golden), output.String())
+		t.Errorf("the output is not the same as %s from line %d:\nwant: %q\n got: %q\n(run the tests with -update if the change is expected)",
+			goldenPath, line, want, got)
+	}
+}
+
+// firstDifference returns the number of the first line that is not the same
+// in both texts, starting at 1, along with that line from each of them
+func firstDifference(want, got string) (int, string, string) {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; ; i++ {
+		var wantLine, gotLine string
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if wantLine != gotLine || i >= len(wantLines) || i >= len(gotLines) {
+			return i + 1, wantLine, gotLine
+		}
+	}
+}
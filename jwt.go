@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtTimeClaims are the claims of a JWT that are times, in seconds since the
+// Unix epoch
+var jwtTimeClaims = map[string]string{
+	"exp":       "expires",
+	"nbf":       "valid from",
+	"iat":       "issued",
+	"auth_time": "authenticated",
+}
+
+// runJWT decodes the JSON Web Token given as the argument, or read from the
+// file it names or from standard input with -, and renders its header and
+// payload as two documents. The times in the payload, such as exp and iat, are
+// annotated with the time in UTC and how long ago or from now it is. The
+// signature is not checked, which is said on stderr.
+func runJWT(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("jwt needs a token, a file with one, or - for standard input")
+	}
+
+	token := arguments[0]
+	if token == "-" {
+		input, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			panic(err)
+		}
+		token = string(input)
+	} else if input, err := ioutil.ReadFile(token); err == nil {
+		token = string(input)
+	}
+
+	documents, err := decodeJWT(token, options, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	if err := printOutput(ctx, os.Stdout, documents, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+	fmt.Fprintln(os.Stderr, "The signature was not verified")
+}
+
+// decodeJWT returns the header and payload of the token as documents, with the
+// options applied and the times of the payload annotated
+func decodeJWT(token string, options Options, now time.Time) ([][]Token, error) {
+	token = strings.TrimSpace(token)
+	token = strings.TrimPrefix(token, "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("A JWT has three parts separated by dots, not %d", len(parts))
+	}
+
+	documents := make([][]Token, 0, 2)
+	for i, name := range []string{"header", "payload"} {
+		// Tokens leave out the padding, but some encoders keep it
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[i], "="))
+		if err != nil {
+			return nil, fmt.Errorf("The %s is not base64url: %v", name, err)
+		}
+
+		root, err := parseTokens(getTokens(decoded, options))
+		if err != nil {
+			return nil, fmt.Errorf("The %s is not JSON: %v", name, err)
+		}
+		if root.kind != NodeObject {
+			return nil, errors.New("The " + name + " is not a JSON object")
+		}
+		if name == "payload" {
+			annotateJWTTimes(root, now)
+		}
+		if isTreeNeeded(options) {
+			if root, err = transformTree(root, options, ioutil.Discard); err != nil {
+				return nil, err
+			}
+		}
+		documents = append(documents, nodeTokens(root))
+	}
+	return documents, nil
+}
+
+// annotateJWTTimes annotates the time claims of the payload, such as "expires
+// 2026-01-02T15:04:05.000Z, in 3h", saying "expired" once exp has passed
+func annotateJWTTimes(payload *Node, now time.Time) {
+	for _, m := range payload.members {
+		verb, ok := jwtTimeClaims[stringValue(m.key)]
+		if !ok || m.value.kind != NodeNumber {
+			continue
+		}
+
+		moment := time.Unix(0, int64(numberValue(m.value)*1e9))
+		if verb == "expires" && !moment.After(now) {
+			verb = "expired"
+		}
+		m.value.annotation = verb + " " + formatTimestamp(moment, "utc", nil, now) + ", " + relativeTime(moment.Sub(now))
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strconv"
+)
+
+// k8sTopKeys are the members of a Kubernetes object in the order kubectl
+// shows them, which come before any others
+var k8sTopKeys = []string{"apiVersion", "kind", "metadata", "spec", "status"}
+
+// k8sMetadataKeys are the members of metadata that come first, since they say
+// which object it is
+var k8sMetadataKeys = []string{"name", "generateName", "namespace"}
+
+// arrangeK8s arranges the Kubernetes objects in the document, which is one
+// object or a List of them, for reading: each is labeled with its kind, name,
+// and apiVersion, its well-known members come first, and the managedFields of
+// its metadata, which are long and rarely read, come last. With hideManaged,
+// for output that cannot be folded, the managedFields are replaced with a
+// note of how many there were. It returns how many objects there were.
+func arrangeK8s(root *Node, hideManaged bool) int {
+	if !isK8sObject(root) {
+		return 0
+	}
+
+	count := 1
+	if items := member(root, "items"); items != nil && items.kind == NodeArray {
+		for _, item := range items.elements {
+			count += arrangeK8s(item, hideManaged)
+		}
+	}
+
+	root.label = k8sTitle(root)
+	moveMembersFirst(root, k8sTopKeys)
+	if metadata := member(root, "metadata"); metadata != nil && metadata.kind == NodeObject {
+		moveMembersFirst(metadata, k8sMetadataKeys)
+		for i, m := range metadata.members {
+			if stringValue(m.key) != "managedFields" {
+				continue
+			}
+			metadata.members = append(append(metadata.members[:i:i], metadata.members[i+1:]...), m)
+			if hideManaged && m.value.kind == NodeArray {
+				m.value.annotation = strconv.Itoa(len(m.value.elements)) + " entries hidden by --k8s"
+				m.value.elements = nil
+			}
+			break
+		}
+	}
+	return count
+}
+
+// isK8sObject returns true if the value is an object with the apiVersion and
+// kind of a Kubernetes object
+func isK8sObject(node *Node) bool {
+	apiVersion, kind := member(node, "apiVersion"), member(node, "kind")
+	return node.kind == NodeObject && apiVersion != nil && apiVersion.kind == NodeString && kind != nil && kind.kind == NodeString
+}
+
+// k8sTitle returns the kind, the namespace and name, and the apiVersion of the
+// object, such as "Deployment default/web apps/v1"
+func k8sTitle(object *Node) string {
+	title := stringValue(member(object, "kind"))
+	if metadata := member(object, "metadata"); metadata != nil {
+		name := member(metadata, "name")
+		if name == nil {
+			name = member(metadata, "generateName")
+		}
+		if name != nil && name.kind == NodeString {
+			title += " "
+			if namespace := member(metadata, "namespace"); namespace != nil && namespace.kind == NodeString {
+				title += stringValue(namespace) + "/"
+			}
+			title += stringValue(name)
+		}
+	}
+	return title + " " + stringValue(member(object, "apiVersion"))
+}
+
+// moveMembersFirst moves the members with the keys to the start of the object,
+// in the order of the keys, keeping the rest in their order after them. Only
+// the first member with a key that repeats is moved; the others stay with the
+// rest, so that nothing is dropped.
+func moveMembersFirst(object *Node, keys []string) {
+	members := make([]Member, 0, len(object.members))
+	isMoved := make([]bool, len(object.members))
+	for _, key := range keys {
+		for i, m := range object.members {
+			if stringValue(m.key) == key {
+				members = append(members, m)
+				isMoved[i] = true
+				break
+			}
+		}
+	}
+	for i, m := range object.members {
+		if !isMoved[i] {
+			members = append(members, m)
+		}
+	}
+	object.members = members
+}
+
+// k8sFolded returns the opening brackets of the managedFields of the
+// Kubernetes objects in the tokens, which start out folded in the page
+func k8sFolded(tokenArray []Token) map[int]bool {
+	folded := make(map[int]bool)
+	for _, span := range findValueSpans(tokenArray) {
+		path := span.path
+		if len(path) >= 2 && path[len(path)-1] == "managedFields" && path[len(path)-2] == "metadata" &&
+			tokenArray[span.start].kind == ArrayOpen {
+			folded[span.start] = true
+		}
+	}
+	return folded
+}
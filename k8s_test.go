@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMoveMembersFirstKeepsRepeatedKeys(t *testing.T) {
+	input := `{"spec":{},"kind":"Pod","metadata":{"name":"web"},"apiVersion":"v1","kind":"Pod2"}`
+	root, err := parseTokens(getTokens([]byte(input), Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moveMembersFirst(root, k8sTopKeys)
+
+	var keys, kinds []string
+	for _, m := range root.members {
+		keys = append(keys, stringValue(m.key))
+		if stringValue(m.key) == "kind" {
+			kinds = append(kinds, stringValue(m.value))
+		}
+	}
+	if got, want := strings.Join(keys, " "), "apiVersion kind metadata spec kind"; got != want {
+		t.Errorf("keys are %q, want %q", got, want)
+	}
+	if got, want := strings.Join(kinds, " "), "Pod Pod2"; got != want {
+		t.Errorf("kinds are %q, want %q", got, want)
+	}
+}
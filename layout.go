@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// layoutTabWidth is how many columns a tab takes in the text layout
+const layoutTabWidth = 4
+
+// textRun is a piece of a line of text that is all in the same style
+type textRun struct {
+	text       string
+	color      string // The color of the text, or "" for the default
+	background string // The color behind the text, or "" for none
+	isStruck   bool   // The text has a line through it, for removed values
+}
+
+// layoutText lays the documents out as lines of styled text with the same
+// layout as the page, for formats that draw the text themselves, such as PDF
+// and PNG. Tabs become spaces, lines longer than the given number of columns
+// are wrapped (0 never wraps), and documents are separated by a blank line.
+func layoutText(ctx context.Context, documents [][]Token, colors theme, columns int) ([][]textRun, error) {
+	lines := [][]textRun{{}}
+	column := 0
+
+	// addRun adds text in the style of the run to the lines, breaking lines at
+	// newlines and when the text reaches the last column
+	addRun := func(text string, style textRun) {
+		for _, character := range text {
+			var piece string
+			switch {
+			case character == '\n':
+				lines = append(lines, []textRun{})
+				column = 0
+				continue
+			case character == '\t':
+				piece = strings.Repeat(" ", layoutTabWidth-column%layoutTabWidth)
+			default:
+				piece = string(character)
+			}
+
+			if columns > 0 && column+utf8.RuneCountInString(piece) > columns && column > 0 {
+				lines = append(lines, []textRun{})
+				column = 0
+			}
+			column += utf8.RuneCountInString(piece)
+
+			// Characters in the same style join the run before them
+			line := lines[len(lines)-1]
+			if n := len(line); n > 0 && line[n-1].color == style.color &&
+				line[n-1].background == style.background && line[n-1].isStruck == style.isStruck {
+				line[n-1].text += piece
+			} else {
+				style.text = piece
+				lines[len(lines)-1] = append(line, style)
+			}
+		}
+	}
+
+	for d, document := range documents {
+		if d > 0 {
+			addRun("\n\n", textRun{})
+		}
+
+		state := printState{isLineStart: true}
+		for i, token := range document {
+			if i%4096 == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+
+			whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+			addRun(whiteSpacePre, textRun{})
+			addRun(token.content, textRun{
+				color:      tokenColor(token, colors),
+				background: highlightColor(token, colors),
+				isStruck:   token.highlight == HighlightRemoved,
+			})
+			addRun(whiteSpacePost, textRun{})
+		}
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// isLintFormat returns true if the format reports the problems in the input
+// for CI instead of rendering it
+func isLintFormat(format string) bool {
+	return format == "github-annotations" || format == "sarif"
+}
+
+// lintInput prints the problems in the input in the format that the options
+// chose: workflow commands that GitHub Actions shows as annotations on pull
+// requests, or a SARIF log that code scanning tools read. It returns false if
+// the input is not valid; everything else is only a warning.
+func lintInput(w io.Writer, jsonFile []byte, fileName string, options Options) bool {
+	problems := findProblems(jsonFile, options)
+
+	isValid := true
+	for _, p := range problems {
+		if problemLevel(p) == "error" {
+			isValid = false
+		}
+	}
+
+	if options.format == "sarif" {
+		fmt.Fprint(w, formatText(nodeTokens(buildSARIF(problems, fileName, jsonFile))))
+	} else {
+		printGitHubAnnotations(w, problems, fileName, jsonFile)
+	}
+	return isValid
+}
+
+// problemLevel returns how serious the problem is: error if it makes the
+// input invalid, as an invalid escape does, or warning
+func problemLevel(p problem) string {
+	if p.kind == "invalid" || p.kind == "invalid-escape" {
+		return "error"
+	}
+	return "warning"
+}
+
+// lineColumn returns the line and column of the offset in the input, both
+// starting at 1, with the column counted in characters
+func lineColumn(jsonFile []byte, offset int) (int, int) {
+	if offset > len(jsonFile) {
+		offset = len(jsonFile)
+	}
+	before := jsonFile[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return bytes.Count(before, []byte("\n")) + 1, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// printGitHubAnnotations prints a GitHub Actions workflow command for each
+// problem, such as '::error file=a.json,line=3,col=7,title=invalid::message'
+func printGitHubAnnotations(w io.Writer, problems []problem, fileName string, jsonFile []byte) {
+	// escapeData and escapeProperty escape the message and the properties
+	// the way the workflow command parser expects
+	escapeData := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace
+	escapeProperty := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace
+
+	for _, p := range problems {
+		properties := "file=" + escapeProperty(fileName)
+		if p.offset >= 0 {
+			line, column := lineColumn(jsonFile, p.offset)
+			properties += fmt.Sprintf(",line=%d,col=%d", line, column)
+		}
+		properties += ",title=" + escapeProperty(p.kind)
+
+		message := p.message
+		if p.path != nil {
+			message += " at " + formatPointer(p.path)
+		}
+		fmt.Fprintf(w, "::%s %s::%s\n", problemLevel(p), properties, escapeData(message))
+	}
+}
+
+// buildSARIF returns the problems as a SARIF 2.1.0 log with a single run, in
+// which each kind of problem is a rule
+func buildSARIF(problems []problem, fileName string, jsonFile []byte) *Node {
+	// object returns an object node with the members
+	object := func(members ...Member) *Node {
+		node := newObjectNode()
+		node.members = members
+		return node
+	}
+	// member returns a member with the key
+	member := func(key string, value *Node) Member {
+		return Member{newStringNode(key), value}
+	}
+
+	rules := newArrayNode()
+	results := newArrayNode()
+	hasRule := make(map[string]bool)
+
+	for _, p := range problems {
+		if !hasRule[p.kind] {
+			hasRule[p.kind] = true
+			rules.elements = append(rules.elements, object(member("id", newStringNode(p.kind))))
+		}
+
+		message := p.message
+		if p.path != nil {
+			message += " at " + formatPointer(p.path)
+		}
+
+		location := object(member("artifactLocation", object(member("uri", newStringNode(fileName)))))
+		if p.offset >= 0 {
+			line, column := lineColumn(jsonFile, p.offset)
+			location.members = append(location.members, member("region", object(
+				member("startLine", newNumberNode(strconv.Itoa(line))),
+				member("startColumn", newNumberNode(strconv.Itoa(column))))))
+		}
+
+		locations := newArrayNode()
+		locations.elements = append(locations.elements, object(member("physicalLocation", location)))
+
+		results.elements = append(results.elements, object(
+			member("ruleId", newStringNode(p.kind)),
+			member("level", newStringNode(problemLevel(p))),
+			member("message", object(member("text", newStringNode(message)))),
+			member("locations", locations)))
+	}
+
+	run := object(
+		member("tool", object(member("driver", object(
+			member("name", newStringNode(programName)),
+			member("rules", rules))))),
+		member("results", results))
+
+	runs := newArrayNode()
+	runs.elements = append(runs.elements, run)
+
+	return object(
+		member("version", newStringNode("2.1.0")),
+		member("$schema", newStringNode("https://json.schemastore.org/sarif-2.1.0.json")),
+		member("runs", runs))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// NumberFormat returns the text of a JSON number written for people to read,
+// such as with the separators of a locale
+type NumberFormat func(number string) string
+
+// TimeFormat returns a time written for people to read
+type TimeFormat func(moment time.Time) string
+
+// SetNumberFormat sets how numbers are written in the output that is meant to
+// be read, which is the page, the terminal, and PDF and PNG images. Output
+// that is meant to be read back, such as fmt, patches, schemas, chat code
+// blocks, templates, and plugins, always has the numbers as they are. It
+// replaces the format of --locale.
+func (options *Options) SetNumberFormat(format NumberFormat) {
+	options.numberFormat = format
+}
+
+// SetTimeFormat sets how the times that --normalize-timestamps and the logs
+// subcommand write out in local time or UTC are written. It replaces the
+// format of --locale.
+func (options *Options) SetTimeFormat(format TimeFormat) {
+	options.timeFormat = format
+}
+
+// locale is how numbers and times are written in a place
+type locale struct {
+	decimal    string // Between the whole and fractional parts of a number
+	group      string // Between each group of three digits of the whole part
+	timeLayout string // The layout of a time for time.Format
+}
+
+// locales lists the locales that --locale knows, by language and, where it
+// differs from the language, by region
+var locales = map[string]locale{
+	"en":    {".", ",", "2006-01-02 15:04:05"},
+	"en-us": {".", ",", "01/02/2006 3:04:05 PM"},
+	"en-gb": {".", ",", "02/01/2006 15:04:05"},
+	"de":    {",", ".", "02.01.2006 15:04:05"},
+	"de-ch": {".", "’", "02.01.2006 15:04:05"},
+	"fr":    {",", " ", "02/01/2006 15:04:05"},
+	"es":    {",", ".", "02/01/2006 15:04:05"},
+	"it":    {",", ".", "02/01/2006 15:04:05"},
+	"pt":    {",", ".", "02/01/2006 15:04:05"},
+	"nl":    {",", ".", "02-01-2006 15:04:05"},
+	"sv":    {",", " ", "2006-01-02 15:04:05"},
+	"pl":    {",", " ", "02.01.2006 15:04:05"},
+	"ru":    {",", " ", "02.01.2006 15:04:05"},
+	"ja":    {".", ",", "2006/01/02 15:04:05"},
+	"zh":    {".", ",", "2006/01/02 15:04:05"},
+}
+
+// findLocale returns the locale with the name, such as de, de-DE, or de_DE.UTF-8
+// as the environment writes it, falling back to its language. The name auto is
+// the locale of the environment, from LC_ALL, LC_NUMERIC, or LANG.
+func findLocale(name string) (locale, bool) {
+	if name == "auto" {
+		name = firstEnv("LC_ALL", "LC_NUMERIC", "LANG")
+	}
+
+	name = strings.ToLower(strings.Replace(name, "_", "-", -1))
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if l, ok := locales[name]; ok {
+		return l, true
+	}
+	l, ok := locales[strings.SplitN(name, "-", 2)[0]]
+	return l, ok
+}
+
+// firstEnv returns the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// formatNumber writes the text of a JSON number with the separators of the
+// locale. Numbers with an exponent only have their decimal separator changed.
+func (l locale) formatNumber(number string) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+	whole, rest := number, ""
+	if i := strings.IndexAny(number, ".eE"); i >= 0 {
+		whole, rest = number[:i], number[i:]
+	}
+	rest = strings.Replace(rest, ".", l.decimal, 1)
+
+	if len(whole) <= 3 || strings.ContainsAny(rest, "eE") {
+		return sign + whole + rest
+	}
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(l.group)
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + rest
+}
+
+// formatTime writes the time in the layout of the locale
+func (l locale) formatTime(moment time.Time) string {
+	return moment.Format(l.timeLayout)
+}
+
+// localizeDocuments returns the documents with their numbers written as the
+// number format says, or the documents as they are if there is none
+func localizeDocuments(documents [][]Token, options Options) [][]Token {
+	if options.numberFormat == nil {
+		return documents
+	}
+
+	localized := make([][]Token, len(documents))
+	for i, document := range documents {
+		localized[i] = make([]Token, len(document))
+		for j, token := range document {
+			if token.kind == Number {
+				token.content = options.numberFormat(token.content)
+			}
+			localized[i][j] = token
+		}
+	}
+	return localized
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// newLogger returns the logger that --verbose and --debug write to standard
+// error, as text or as JSON lines depending on --log-format. Without either
+// flag nothing is logged.
+func newLogger(options Options) *slog.Logger {
+	level := slog.LevelWarn
+	switch {
+	case options.debug:
+		level = slog.LevelDebug
+	case options.verbose:
+		level = slog.LevelInfo
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	if options.logFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, handlerOptions))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, handlerOptions))
+}
+
+// logger returns the logger in the options, or one that discards everything
+// if the options were not read from the command line
+func logger(options Options) *slog.Logger {
+	if options.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return options.logger
+}
+
+// logOptions logs every flag that was set, which is the first thing asked for
+// in a bug report
+func logOptions(options Options, flags *flag.FlagSet) {
+	attributes := make([]any, 0)
+	flags.Visit(func(f *flag.Flag) {
+		attributes = append(attributes, slog.String(f.Name, f.Value.String()))
+	})
+	logger(options).Debug("options", attributes...)
+}
+
+// countingWriter passes writes on and counts how many bytes were written
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+// Write writes to the underlying writer and adds to the count
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The keys that structured loggers, and journalctl -o json, commonly write
+// the envelope of a record under, each in the order they are looked for
+var (
+	logTimeKeys    = []string{"time", "timestamp", "ts", "@timestamp", "date", "__REALTIME_TIMESTAMP"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "levelname", "log.level", "PRIORITY"}
+	logMessageKeys = []string{"msg", "message", "MESSAGE", "@message"}
+)
+
+// syslogLevels names the numeric syslog priorities, which journald's PRIORITY
+// field holds
+var syslogLevels = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// logLevelColors maps the names of levels to the color of the theme that
+// records at that level are shown in, unless the theme file maps them itself
+var logLevelColors = map[string]string{
+	"emerg": "error", "alert": "error", "crit": "error", "critical": "error",
+	"fatal": "error", "panic": "error", "err": "error", "error": "error",
+	"warning": "warn", "warn": "warn",
+	"notice": "info", "info": "info",
+	"debug": "debug", "trace": "debug",
+}
+
+// logRecord is a structured log record split into its envelope and the rest
+// of its fields
+type logRecord struct {
+	time    string
+	level   string
+	message string
+	fields  *Node // An object with the members that are not in the envelope
+}
+
+// runLogs prints the structured log records in the file named in the
+// arguments, one JSON object to a line, for a terminal. The time, level, and
+// message of each record go on one line, and the rest of its fields are
+// expanded as highlighted JSON beneath it. Records without any of these keys
+// and lines that are not JSON are printed as --follow prints them. It reads
+// standard input if there is no file, and keeps reading as the file grows
+// with --follow. With --fields, records are rows of only those fields instead.
+func runLogs(options Options, arguments []string) {
+	if len(arguments) > 1 {
+		panic("logs reads one file, or standard input")
+	}
+	fileName := "-"
+	if len(arguments) == 1 {
+		fileName = arguments[0]
+	}
+
+	isColored := isColorEnabled(options.color, os.Stdout)
+	handle := func(line []byte) {
+		printLogRecord(os.Stdout, line, options, isColored)
+	}
+	if options.fields != "" {
+		handle = recordPrinter(options, isColored)
+	}
+
+	if options.follow {
+		if err := followLines(fileName, handle); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	input, _, err := readFileWithProgress(fileName, options)
+	if err != nil {
+		panic(err)
+	}
+	for _, line := range bytes.Split(input, []byte("\n")) {
+		handle(bytes.TrimRight(line, "\r"))
+	}
+}
+
+// printLogRecord prints a line of a structured log for runLogs
+func printLogRecord(w io.Writer, line []byte, options Options, isColored bool) {
+	record, ok := readLogRecord(parseRecord(line, options), options)
+	if !ok {
+		printRecord(w, line, options, isColored)
+		return
+	}
+
+	options.colors = levelTheme(pageTheme(options), record.level)
+	colors := options.colors
+	parts := make([]string, 0, 3)
+	if record.time != "" {
+		parts = append(parts, ansiRun(textRun{text: record.time, color: colors.comment}, isColored))
+	}
+	if record.level != "" {
+		parts = append(parts, ansiRun(textRun{text: fmt.Sprintf("%-5s", strings.ToUpper(record.level)), color: colors.annotation}, isColored))
+	}
+	if record.message != "" {
+		parts = append(parts, ansiRun(textRun{text: record.message, color: colors.text}, isColored))
+	}
+	fmt.Fprintln(w, strings.Join(parts, " "))
+
+	if len(record.fields.members) == 0 {
+		return
+	}
+	var fields bytes.Buffer
+	printANSI(context.Background(), &fields, [][]Token{nodeTokens(record.fields)}, options, isColored)
+	for _, fieldLine := range strings.Split(strings.TrimRight(fields.String(), "\n"), "\n") {
+		fmt.Fprintln(w, "    "+fieldLine)
+	}
+}
+
+// parseRecord returns the tree of a line of NDJSON with the options applied,
+// or nil if the line is not a single JSON value. The tree is parsed before it
+// is turned into tokens, since the annotations of the options cannot be parsed.
+func parseRecord(line []byte, options Options) *Node {
+	if options.repair {
+		line, _ = repairJSON(line, options)
+	} else if _, _, err := checkInput(line, options); err != nil {
+		return nil
+	}
+
+	tokenArray, err := tokenize(context.Background(), line, options)
+	if err != nil {
+		return nil
+	}
+	if options.fixTrailingCommas {
+		tokenArray, _ = removeTrailingCommas(tokenArray)
+	}
+	documents := splitDocuments(tokenArray)
+	if len(documents) != 1 {
+		return nil
+	}
+
+	root, err := parseTokens(documents[0])
+	if err != nil {
+		return nil
+	}
+	if isTreeNeeded(options) {
+		if root, err = transformTree(root, options, ioutil.Discard); err != nil {
+			return nil
+		}
+	}
+	return root
+}
+
+// readLogRecord splits an object into the envelope of a log record and the
+// rest of its fields. The level is under the theme's level-field, if it has
+// one, or one of the usual keys. It returns false if the value is not an
+// object or has none of the keys of an envelope. Journald's fields that start with two
+// underscores, such as __CURSOR, are only useful to journald and are left out.
+func readLogRecord(root *Node, options Options) (logRecord, bool) {
+	if root == nil || root.kind != NodeObject {
+		return logRecord{}, false
+	}
+
+	record := logRecord{fields: copyNode(root)}
+	record.fields.members = nil
+	used := make(map[string]bool)
+
+	// find returns the first of the keys that the object has
+	find := func(keys []string) (string, *Node) {
+		for _, key := range keys {
+			if value := member(root, key); value != nil && !used[key] {
+				used[key] = true
+				return key, value
+			}
+		}
+		return "", nil
+	}
+
+	if _, value := find(logTimeKeys); value != nil {
+		record.time = logTime(value, options.timestampForm, options.timeFormat)
+	}
+	levelKeys := logLevelKeys
+	if field := pageTheme(options).levelField; field != "" {
+		levelKeys = []string{field}
+	}
+	if key, value := find(levelKeys); value != nil {
+		record.level = logLevel(key, value)
+	}
+	if _, value := find(logMessageKeys); value != nil {
+		record.message = logText(value)
+	}
+	if len(used) == 0 {
+		return logRecord{}, false
+	}
+
+	for _, m := range root.members {
+		key := stringValue(m.key)
+		if !used[key] && !strings.HasPrefix(key, "__") {
+			record.fields.members = append(record.fields.members, m)
+		}
+	}
+	return record, true
+}
+
+// logText returns a value of the envelope as text: a string without its
+// quotes, and anything else as it is written
+func logText(value *Node) string {
+	if value.kind == NodeString {
+		return stringValue(value)
+	}
+	return valueText(value)
+}
+
+// logTime returns the time of a record as text. Times that are written as a
+// number since the Unix epoch, which are told apart by their size, are written
+// out in UTC, and with --normalize-timestamps every time is written the way it
+// asks. Other times are kept as they are.
+func logTime(value *Node, mode string, format TimeFormat) string {
+	text := logText(value)
+	moment, ok := timestampValue(value)
+	if !ok {
+		epoch, err := strconv.ParseFloat(text, 64)
+		if err != nil || epoch <= 0 {
+			return text
+		}
+		moment = epochTime(epoch)
+	} else if mode == "" && value.kind == NodeString {
+		return text
+	}
+	return formatTimestamp(moment, mode, format, time.Now())
+}
+
+// logLevel returns the name of the level of a record. Journald's PRIORITY is a
+// syslog priority, and levels written as numbers from 10 to 60, as pino and
+// bunyan write them, are named the way those loggers name them.
+func logLevel(key string, value *Node) string {
+	text := logText(value)
+	number, err := strconv.Atoi(text)
+	switch {
+	case err != nil:
+		return text
+	case key == "PRIORITY" && number >= 0 && number < len(syslogLevels):
+		return syslogLevels[number]
+	case number >= 10 && number <= 60 && number%10 == 0:
+		return []string{"trace", "debug", "info", "warn", "error", "fatal"}[number/10-1]
+	}
+	return text
+}
+
+// levelTheme returns the theme that a record at the level is shown in. Its
+// values are all in the color of the level, and the theme is returned as it is
+// for levels without a color.
+func levelTheme(colors theme, level string) theme {
+	name := strings.ToLower(level)
+	kind, ok := colors.levels[name]
+	if !ok {
+		kind = logLevelColors[name]
+	}
+
+	color := map[string]string{
+		"error": colors.levelError,
+		"warn":  colors.levelWarn,
+		"info":  colors.levelInfo,
+		"debug": colors.levelDebug,
+	}[kind]
+	if color == "" {
+		return colors
+	}
+
+	for _, c := range []*string{&colors.object, &colors.array, &colors.pair, &colors.member, &colors.text,
+		&colors.escape, &colors.number, &colors.literal, &colors.comment, &colors.annotation} {
+		*c = color
+	}
+	return colors
+}
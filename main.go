@@ -0,0 +1,56 @@
+package main
+
+import "os"
+
+// main runs the command-line program. The WebAssembly build leaves this file
+// out and uses the main in web/wasm_main.go instead.
+func main() {
+	// A subcommand, if there is one, comes before the flags
+	command := ""
+	arguments := os.Args[1:]
+	if len(arguments) > 0 && isCommand(arguments[0]) {
+		command = arguments[0]
+		arguments = arguments[1:]
+	}
+
+	options, arguments := parseOptions(arguments)
+
+	switch command {
+	case "diff":
+		runDiff(options, arguments)
+	case "merge":
+		runMerge(options, arguments)
+	case "merge3":
+		runMerge3(options, arguments)
+	case "import-theme":
+		runImportTheme(options, arguments)
+	case "completion":
+		runCompletion(options, arguments)
+	case "gen-man":
+		runGenMan(options, arguments)
+	case "fmt":
+		runFmt(options, arguments)
+	case "openapi":
+		runOpenAPI(options, arguments)
+	case "jwt":
+		runJWT(options, arguments)
+	case "preview":
+		runPreview(options, arguments)
+	case "db":
+		runDB(options, arguments)
+	case "terraform":
+		runTerraform(options, arguments)
+	case "logs":
+		runLogs(options, arguments)
+	default:
+		if options.serve != "" {
+			runServe(options, arguments)
+		} else if options.watch {
+			runWatch(options, arguments)
+		} else if options.follow {
+			runFollow(options, arguments)
+		} else {
+			runFormat(options, arguments)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// runGenMan prints a man page for the program in roff, generated from the
+// flag definitions and the list of subcommands so that it never has to be
+// maintained by hand. Packagers can save it as json-pretty-printer.1.
+func runGenMan(options Options, arguments []string) {
+	if len(arguments) != 0 {
+		panic("gen-man takes no arguments")
+	}
+	printManPage(os.Stdout)
+}
+
+// printManPage prints the man page
+func printManPage(w io.Writer) {
+	fmt.Fprintln(w, ".TH "+strings.ToUpper(programName)+" 1")
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, programName+` \- colorize JSON as HTML and reshape it on the way`)
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintln(w, ".B "+programName)
+	fmt.Fprintln(w, `[\fIflags\fR] \fIfile.json\fR`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, ".B "+programName)
+	fmt.Fprintln(w, `\fIcommand\fR [\fIflags\fR] [\fIarguments\fR...]`)
+
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, manEscape("Styles the JSON file as an HTML page with syntax highlighting and prints "+
+		"it to standard output. Concatenated JSON values are rendered one after another. "+
+		"The flags can reshape the JSON, such as by sorting, patching, or flattening it, "+
+		"and can render it in other formats, such as for a terminal or as a JSON Schema. "+
+		"Flags are given before the file."))
+
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, command := range commands {
+		fmt.Fprintln(w, ".TP")
+		if command.arguments == "" {
+			fmt.Fprintln(w, `\fB`+manEscape(command.name)+`\fR`)
+		} else {
+			fmt.Fprintln(w, `\fB`+manEscape(command.name)+`\fR \fI`+manEscape(command.arguments)+`\fR`)
+		}
+		fmt.Fprintln(w, manEscape(command.usage))
+	}
+
+	fmt.Fprintln(w, ".SH OPTIONS")
+	flags := flag.NewFlagSet(programName, flag.ContinueOnError)
+	defineFlags(flags, &Options{})
+	flags.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintln(w, ".TP")
+
+		name, usage := flag.UnquoteUsage(f)
+		if name == "" {
+			fmt.Fprintln(w, `\fB\-\-`+manEscape(f.Name)+`\fR`)
+		} else {
+			fmt.Fprintln(w, `\fB\-\-`+manEscape(f.Name)+`\fR \fI`+manEscape(name)+`\fR`)
+		}
+
+		if choices := flagChoices[f.Name]; choices != nil {
+			usage += " (one of " + strings.Join(choices, ", ") + ")"
+		}
+		switch f.DefValue {
+		case "", "false", "0", "0s":
+		default:
+			usage += " (default " + f.DefValue + ")"
+		}
+		fmt.Fprintln(w, manEscape(usage))
+	})
+
+	fmt.Fprintln(w, ".SH ENVIRONMENT")
+	fmt.Fprintln(w, ".TP")
+	fmt.Fprintln(w, `\fBNO_COLOR\fR`)
+	fmt.Fprintln(w, manEscape("If set to anything but an empty string, --format=ansi does not color its output unless --color=always is given."))
+
+	fmt.Fprintln(w, ".SH EXAMPLES")
+	for _, example := range []string{
+		programName + " input.json > output.html",
+		programName + " --collapsible --theme pencil-dark input.json > output.html",
+		programName + " --format=ansi input.json",
+		programName + " diff before.json after.json > diff.html",
+	} {
+		fmt.Fprintln(w, ".PP")
+		fmt.Fprintln(w, ".nf")
+		fmt.Fprintln(w, manEscape(example))
+		fmt.Fprintln(w, ".fi")
+	}
+}
+
+// manEscape makes text safe to use in roff. Backslashes and hyphens are
+// escaped, and a line that would start with a control character is guarded.
+func manEscape(text string) string {
+	text = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
@@ -0,0 +1,69 @@
+package main
+
+import "os"
+
+// runMerge combines the JSON files named in the arguments into one document,
+// with each file layered on top of the ones before it, and prints the result.
+// The --strategy flag decides how values that appear in several files are
+// combined.
+func runMerge(options Options, arguments []string) {
+	if len(arguments) < 1 {
+		panic("merge needs at least one filename")
+	}
+
+	var merged *Node
+	for _, fileName := range arguments {
+		root, err := readJSONFile(fileName, options)
+		if err != nil {
+			panic(err)
+		}
+
+		if merged == nil {
+			merged = root
+		} else {
+			merged = mergeNodes(merged, root, options.strategy)
+		}
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	if err := printOutput(ctx, os.Stdout, [][]Token{nodeTokens(merged)}, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+}
+
+// mergeNodes layers the overlay on top of the base and returns the result.
+// With the "deep" strategy, objects are merged member by member all the way
+// down and any other value in the overlay replaces the one in the base. The
+// "concat" strategy does the same but joins arrays together, and "last-wins"
+// only merges the members of the top-level objects.
+func mergeNodes(base, overlay *Node, strategy string) *Node {
+	switch {
+	case base.kind == NodeObject && overlay.kind == NodeObject:
+		merged := copyNode(base)
+		for _, m := range overlay.members {
+			key := stringValue(m.key)
+			value := m.value
+
+			previous := member(merged, key)
+			if previous != nil && strategy != "last-wins" {
+				value = mergeNodes(previous, value, strategy)
+			}
+
+			if previous == nil {
+				merged.members = append(merged.members, Member{copyNode(m.key), copyNode(value)})
+			} else {
+				addValue(merged, []string{key}, copyNode(value))
+			}
+		}
+		return merged
+	case base.kind == NodeArray && overlay.kind == NodeArray && strategy == "concat":
+		merged := copyNode(base)
+		for _, element := range overlay.elements {
+			merged.elements = append(merged.elements, copyNode(element))
+		}
+		return merged
+	}
+
+	return copyNode(overlay)
+}
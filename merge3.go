@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runMerge3 merges the changes that two files made to a common base, as a
+// version control system does with a JSON file that was changed on two
+// branches, and renders the result. A value that only one side changed takes
+// that side's value, and objects are merged member by member. Where both
+// sides changed a value in different ways, the merged document keeps ours,
+// highlighted, after a comment with theirs, highlighted differently. The
+// number of conflicts is printed on stderr, and the exit status is 1 if there
+// are any.
+func runMerge3(options Options, arguments []string) {
+	if len(arguments) != 3 {
+		panic("merge3 needs three filenames: base, ours, and theirs")
+	}
+
+	roots := make([]*Node, len(arguments))
+	for i, fileName := range arguments {
+		root, err := readJSONFile(fileName, options)
+		if err != nil {
+			panic(err)
+		}
+		roots[i] = root
+	}
+
+	merged, conflicts := mergeThreeWay(roots[0], roots[1], roots[2])
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	if err := printOutput(ctx, os.Stdout, [][]Token{nodeTokens(merged)}, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d conflict(s)\n", conflicts)
+	if conflicts > 0 {
+		os.Exit(1)
+	}
+}
+
+// mergeThreeWay merges the changes from base to ours and from base to theirs
+// and returns the result along with how many conflicts it has. A nil value is
+// one that is not there, such as a member that was removed. Arrays are merged
+// as a whole, since their elements cannot be matched up by index once either
+// side has inserted or removed one.
+func mergeThreeWay(base, ours, theirs *Node) (*Node, int) {
+	switch {
+	case isSameValue(ours, theirs), isSameValue(base, theirs):
+		return copyValue(ours), 0
+	case isSameValue(base, ours):
+		return copyValue(theirs), 0
+	}
+
+	// Objects on both sides are merged member by member, and so are objects
+	// that both sides added, as if they had been added empty
+	if ours != nil && theirs != nil && ours.kind == NodeObject && theirs.kind == NodeObject {
+		if base == nil || base.kind != NodeObject {
+			base = newObjectNode()
+		}
+		return mergeObjects(base, ours, theirs)
+	}
+
+	return markConflict(ours, theirs), 1
+}
+
+// mergeObjects merges the members of three objects. Members are kept in the
+// order of ours, followed by the members that only theirs added.
+func mergeObjects(base, ours, theirs *Node) (*Node, int) {
+	merged := copyNode(ours)
+	merged.members = nil
+	conflicts := 0
+
+	keys := make([]string, 0, len(ours.members)+len(theirs.members))
+	keyNodes := make(map[string]*Node)
+	for _, side := range []*Node{ours, theirs} {
+		for _, m := range side.members {
+			key := stringValue(m.key)
+			if _, ok := keyNodes[key]; !ok {
+				keys = append(keys, key)
+				keyNodes[key] = m.key
+			}
+		}
+	}
+
+	for _, key := range keys {
+		value, conflictCount := mergeThreeWay(member(base, key), member(ours, key), member(theirs, key))
+		conflicts += conflictCount
+		if value == nil {
+			continue
+		}
+
+		keyNode := copyNode(keyNodes[key])
+		if value.highlight == HighlightOurs || value.highlight == HighlightTheirs {
+			// The comment about theirs goes before the member, where it is
+			// out of the way of the value
+			keyNode.comments = append(keyNode.comments, value.comments...)
+			value.comments = nil
+		}
+		merged.members = append(merged.members, Member{keyNode, value})
+	}
+
+	return merged, conflicts
+}
+
+// markConflict returns the value that the merged document keeps where ours
+// and theirs changed it in different ways. Ours is kept when it is there,
+// with a comment that shows theirs, and theirs otherwise, with a comment that
+// ours removed it.
+func markConflict(ours, theirs *Node) *Node {
+	kept, keptHighlight, keptName := ours, HighlightOurs, "ours"
+	other, otherHighlight, otherName := theirs, HighlightTheirs, "theirs"
+	if ours == nil {
+		kept, keptHighlight, keptName = theirs, HighlightTheirs, "theirs"
+		other, otherHighlight, otherName = ours, HighlightOurs, "ours"
+	}
+
+	text := "removed"
+	if other != nil {
+		text = valueSummary(other)
+	}
+	comment := makeToken("/* conflict, "+otherName+": "+text+" */", Comment)
+	comment.highlight = otherHighlight
+
+	value := copyNode(kept)
+	value.highlight = keptHighlight
+	value.annotation = keptName
+	value.comments = append(value.comments, comment)
+	return value
+}
+
+// isSameValue returns true if both values are missing or both are there and
+// equal
+func isSameValue(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return nodesEqual(a, b)
+}
+
+// copyValue returns a copy of the value, or nil if it is missing
+func copyValue(node *Node) *Node {
+	if node == nil {
+		return nil
+	}
+	return copyNode(node)
+}
@@ -0,0 +1,65 @@
+package main
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7386) to the target and
+// returns the result. If isHighlighted is true, members that the patch adds
+// are highlighted as added, values that it changes are highlighted as
+// changed, and each member that it removes leaves a highlighted comment.
+func applyMergePatch(target *Node, patch *Node, isHighlighted bool) *Node {
+	return mergePatchAt(target, patch, []string{}, isHighlighted)
+}
+
+// mergePatchAt applies the part of a merge patch that belongs at the path
+func mergePatchAt(target *Node, patch *Node, path []string, isHighlighted bool) *Node {
+	if patch.kind != NodeObject {
+		replacement := copyNode(patch)
+		replacement.comments = nil
+		if isHighlighted && (target == nil || !nodesEqual(target, patch)) {
+			replacement.highlight = HighlightChanged
+		}
+		if target != nil {
+			replacement.comments = target.comments
+		}
+		return replacement
+	}
+
+	if target == nil || target.kind != NodeObject {
+		replaced := target
+		target = newObjectNode()
+		if replaced != nil {
+			target.comments = replaced.comments
+			if isHighlighted {
+				target.highlight = HighlightChanged
+			}
+		}
+	}
+
+	for _, m := range patch.members {
+		key := stringValue(m.key)
+		memberPath := append(append([]string{}, path...), key)
+
+		if m.value.kind == NodeNull {
+			removed, err := removeValue(target, []string{key})
+			if err == nil && isHighlighted {
+				markRemoval(target, memberPath, removed)
+			}
+			continue
+		}
+
+		current := member(target, key)
+		merged := mergePatchAt(current, m.value, memberPath, isHighlighted)
+
+		if current == nil {
+			if isHighlighted {
+				merged.highlight = HighlightAdded
+			}
+			keyNode := newStringNode(key)
+			keyNode.highlight = merged.highlight
+			target.members = append(target.members, Member{keyNode, merged})
+			continue
+		}
+
+		addValue(target, []string{key}, merged)
+	}
+
+	return target
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html"
+	"io/ioutil"
+	"strings"
+)
+
+// notebookFoldScript folds containers like foldScript, but only listens for
+// clicks once however many fragments a notebook displays, since a second
+// listener would unfold what the first one folded
+const notebookFoldScript = `if (!window.jsonPrettyFold) {
+	window.jsonPrettyFold = true;
+` + foldScript + `
+}`
+
+// RenderNotebookHTML renders a Go value as JSON in an HTML fragment for Go
+// notebooks, such as gophernotes in Jupyter, whose objects and arrays can be
+// folded by clicking their opening bracket. The value is marshalled with
+// encoding/json, so maps come out with their keys sorted. A value that cannot
+// be marshalled renders its error instead.
+func RenderNotebookHTML(v interface{}) string {
+	// HTML is escaped when the page is printed, so the JSON does not need it
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return notebookError(err)
+	}
+
+	options, err := NewOptions([]string{"--collapsible"})
+	if err != nil {
+		return notebookError(err)
+	}
+	documents, err := formatDocuments(context.Background(), input.Bytes(), options, ioutil.Discard)
+	if err != nil {
+		return notebookError(err)
+	}
+
+	var fragment strings.Builder
+	fragment.WriteString("<div style=\"background-color:" + options.colors.background + "; padding:4px 8px; overflow:auto\">\n")
+	fragment.WriteString("\t\t" + "<style>\n" + foldStyle + "\n\t\t</style>\n")
+	fragment.WriteString("\t\t" + "<script>\n" + notebookFoldScript + "\n\t\t</script>\n")
+	for _, document := range documents {
+		if err := printDocument(context.Background(), &fragment, document, options); err != nil {
+			return notebookError(err)
+		}
+	}
+	fragment.WriteString("</div>\n")
+	return fragment.String()
+}
+
+// notebookError returns a fragment that shows the error
+func notebookError(err error) string {
+	return "<pre style=\"color:#D75F5F\">" + html.EscapeString(err.Error()) + "</pre>\n"
+}
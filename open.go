@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// openExtensions are the file extensions of the formats that --open writes,
+// so that the system knows what to open them with
+var openExtensions = map[string]string{
+	"html":       ".html",
+	"jsonschema": ".json",
+	"pdf":        ".pdf",
+	"png":        ".png",
+	"sarif":      ".json",
+	"table":      ".html",
+	"dot":        ".dot",
+	"mermaid":    ".mmd",
+}
+
+// openCommand returns the command that opens the file with the program the
+// system uses for its type, which for a page is the default browser
+func openCommand(fileName string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", fileName)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", fileName)
+	}
+	return exec.Command("xdg-open", fileName)
+}
+
+// printToBrowser writes the output to a temporary file and opens it, for
+// --open. The file is left for the browser to read, in the system's directory
+// for temporary files.
+func printToBrowser(ctx context.Context, documents [][]Token, options Options) error {
+	extension, ok := openExtensions[options.format]
+	if !ok {
+		extension = ".txt"
+	}
+
+	file, err := ioutil.TempFile("", "json-pretty-*"+extension)
+	if err != nil {
+		return err
+	}
+	if options.assetsDir != "" {
+		options.assetsPath = assetsPathFrom(filepath.Dir(file.Name()), options)
+	}
+
+	err = printOutput(ctx, file, documents, options, false)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := openCommand(file.Name()).Start(); err != nil {
+		return fmt.Errorf("could not open %s: %v", file.Name(), err)
+	}
+	fmt.Fprintln(os.Stderr, "Opened "+file.Name())
+	return nil
+}
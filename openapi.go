@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// openAPIMethods are the operations a path of an OpenAPI spec can have, in the
+// order they are listed
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPIExample is an example body that an operation of a spec gives
+type openAPIExample struct {
+	name  string // Says which operation, body, and example it is
+	value *Node
+}
+
+// runOpenAPI reads an OpenAPI spec in JSON and renders every example of a
+// request or response body that its operations give, each under the method,
+// path, status, and media type it belongs to. The examples are all on one
+// page, after a table of contents, or with --output-dir on a page each along
+// with an index.html, the same way several files are.
+func runOpenAPI(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("openapi needs the file of an OpenAPI spec in JSON")
+	}
+	spec, err := readJSONFile(arguments[0], options)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+
+	examples := openAPIExamples(spec)
+	files := make([]formattedFile, len(examples))
+	for i, example := range examples {
+		text := formatText(nodeTokens(example.value))
+		files[i] = formattedFile{fileName: example.name, size: len(text)}
+		if options.embedRaw || options.sideBySide {
+			files[i].raw = []byte(text)
+		}
+		files[i].documents, files[i].err = formatDocuments(ctx, []byte(text), options, ioutil.Discard)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			exitOnError(ctxErr, options)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Found %d example(s)\n", len(examples))
+
+	if options.outputDir == "" {
+		if err := printFilesPage(ctx, os.Stdout, files, options); err != nil {
+			exitOnError(err, options)
+		}
+		return
+	}
+
+	pageNames := make([]string, len(files))
+	isTaken := make(map[string]bool)
+	for i, example := range examples {
+		pageNames[i] = examplePageName(example.name, isTaken)
+	}
+	writeFilePages(ctx, files, pageNames, options)
+}
+
+// openAPIExamples returns the examples of the request and response bodies of
+// every operation of the spec, in the order they are written. Bodies give an
+// example, named examples, or an example in their schema, which is where
+// OpenAPI 3 puts them, and responses of Swagger 2 give one for each media
+// type. Examples and schemas can be $refs within the spec.
+func openAPIExamples(spec *Node) []openAPIExample {
+	examples := make([]openAPIExample, 0)
+	paths := member(spec, "paths")
+	if paths == nil || paths.kind != NodeObject {
+		return examples
+	}
+
+	for _, pathMember := range paths.members {
+		path := resolveRef(spec, pathMember.value)
+		for _, method := range openAPIMethods {
+			operation := resolveRef(spec, member(path, method))
+			if operation == nil {
+				continue
+			}
+			operationName := strings.ToUpper(method) + " " + stringValue(pathMember.key)
+
+			if body := resolveRef(spec, member(operation, "requestBody")); body != nil {
+				examples = append(examples, contentExamples(spec, member(body, "content"), operationName+" request")...)
+			}
+
+			responses := member(operation, "responses")
+			if responses == nil || responses.kind != NodeObject {
+				continue
+			}
+			for _, response := range responses.members {
+				responseName := operationName + " response " + stringValue(response.key)
+				value := resolveRef(spec, response.value)
+				examples = append(examples, contentExamples(spec, member(value, "content"), responseName)...)
+
+				// Swagger 2 maps media types straight to examples
+				if swagger := member(value, "examples"); swagger != nil && swagger.kind == NodeObject {
+					for _, m := range swagger.members {
+						examples = append(examples, openAPIExample{responseName + " " + stringValue(m.key), m.value})
+					}
+				}
+			}
+		}
+	}
+	return examples
+}
+
+// contentExamples returns the examples of each media type of the content of a
+// request or response body
+func contentExamples(spec, content *Node, name string) []openAPIExample {
+	examples := make([]openAPIExample, 0)
+	if content == nil || content.kind != NodeObject {
+		return examples
+	}
+
+	for _, m := range content.members {
+		mediaName := name + " " + stringValue(m.key)
+		count := len(examples)
+		if example := member(m.value, "example"); example != nil {
+			examples = append(examples, openAPIExample{mediaName, example})
+		}
+		if named := member(m.value, "examples"); named != nil && named.kind == NodeObject {
+			for _, e := range named.members {
+				if value := member(resolveRef(spec, e.value), "value"); value != nil {
+					examples = append(examples, openAPIExample{mediaName + " " + stringValue(e.key), value})
+				}
+			}
+		}
+		if len(examples) == count {
+			if example := member(resolveRef(spec, member(m.value, "schema")), "example"); example != nil {
+				examples = append(examples, openAPIExample{mediaName, example})
+			}
+		}
+	}
+	return examples
+}
+
+// pageNameUnsafe matches what is left out of the name of a page
+var pageNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// examplePageName returns the name of the page for an example, made from its
+// name, such as get-pets-id-response-200-application-json.html, and numbered
+// if another example already took it
+func examplePageName(name string, isTaken map[string]bool) string {
+	base := strings.Trim(pageNameUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	pageName := base + ".html"
+	for i := 2; isTaken[pageName]; i++ {
+		pageName = fmt.Sprintf("%s-%d.html", base, i)
+	}
+	isTaken[pageName] = true
+	return pageName
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packageJSONKeys is the order of the members of a package.json that the
+// community tools, such as sort-package-json, agree on
+var packageJSONKeys = []string{
+	"$schema", "name", "displayName", "version", "private", "description", "categories", "keywords",
+	"homepage", "bugs", "repository", "funding", "license", "author", "maintainers", "contributors",
+	"publisher", "sideEffects", "type", "imports", "exports", "main", "module", "browser", "types",
+	"typesVersions", "typings", "style", "bin", "man", "directories", "files", "workspaces", "scripts",
+	"contributes", "activationEvents", "husky", "simple-git-hooks", "lint-staged", "config", "browserslist",
+	"prettier", "eslintConfig", "eslintIgnore", "stylelint", "ava", "jest", "mocha", "nyc", "resolutions",
+	"overrides", "dependencies", "devDependencies", "dependenciesMeta", "peerDependencies",
+	"peerDependenciesMeta", "optionalDependencies", "bundledDependencies", "bundleDependencies",
+	"extensionPack", "extensionDependencies", "packageManager", "engines", "volta", "os", "cpu",
+	"preferGlobal", "publishConfig", "icon", "galleryBanner", "preview",
+}
+
+// composerJSONKeys is the order of the members of a composer.json in the
+// Composer documentation
+var composerJSONKeys = []string{
+	"$schema", "name", "type", "description", "keywords", "homepage", "readme", "version", "time",
+	"license", "authors", "support", "funding", "require", "require-dev", "conflict", "replace",
+	"provide", "suggest", "autoload", "autoload-dev", "include-path", "target-dir",
+	"minimum-stability", "prefer-stable", "repositories", "config", "scripts", "scripts-descriptions",
+	"extra", "bin", "archive", "abandoned", "non-feature-branches",
+}
+
+// packageDependencyKeys are the members that list packages, which package
+// managers keep sorted by name
+var packageDependencyKeys = map[string]bool{
+	"dependencies": true, "devDependencies": true, "peerDependencies": true, "optionalDependencies": true,
+	"peerDependenciesMeta": true, "dependenciesMeta": true, "resolutions": true, "overrides": true,
+	"require": true, "require-dev": true, "conflict": true, "replace": true, "provide": true, "suggest": true,
+}
+
+// orderPackageKeys puts the members of a package.json, or of a composer.json
+// if the file is named that or has its members, in the conventional order,
+// with the members it does not know after them in the order they were in.
+// The lists of dependencies are sorted by name, which in a composer.json puts
+// php and its extensions first, as Composer's sort-packages does. It returns
+// false if the document is not an object.
+func orderPackageKeys(root *Node, fileName string) bool {
+	if root.kind != NodeObject {
+		return false
+	}
+
+	keys := packageJSONKeys
+	isComposer := filepath.Base(fileName) == "composer.json" ||
+		member(root, "require") != nil || member(root, "require-dev") != nil || member(root, "autoload") != nil
+	if isComposer {
+		keys = composerJSONKeys
+	}
+	moveMembersFirst(root, keys)
+
+	for _, m := range root.members {
+		if !packageDependencyKeys[stringValue(m.key)] || m.value.kind != NodeObject {
+			continue
+		}
+		members := m.value.members
+		sort.SliceStable(members, func(i, j int) bool {
+			a, b := stringValue(members[i].key), stringValue(members[j].key)
+			if isComposer && platformRank(a) != platformRank(b) {
+				return platformRank(a) < platformRank(b)
+			}
+			return a < b
+		})
+	}
+	return true
+}
+
+// platformRank returns where Composer sorts a package: PHP itself first, then
+// its variants, extensions, and libraries, then everything else
+func platformRank(name string) int {
+	switch {
+	case name == "php":
+		return 0
+	case strings.HasPrefix(name, "php-"):
+		return 1
+	case strings.HasPrefix(name, "ext-"):
+		return 2
+	case strings.HasPrefix(name, "lib-"):
+		return 3
+	}
+	return 4
+}
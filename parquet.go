@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// parquetMagic is how a Parquet file starts and ends
+var parquetMagic = []byte("PAR1")
+
+// Names of the enums in the Parquet footer, by their number
+var (
+	parquetTypes          = []string{"BOOLEAN", "INT32", "INT64", "INT96", "FLOAT", "DOUBLE", "BYTE_ARRAY", "FIXED_LEN_BYTE_ARRAY"}
+	parquetRepetitions    = []string{"REQUIRED", "OPTIONAL", "REPEATED"}
+	parquetConvertedTypes = []string{"UTF8", "MAP", "MAP_KEY_VALUE", "LIST", "ENUM", "DECIMAL", "DATE", "TIME_MILLIS",
+		"TIME_MICROS", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS", "UINT_8", "UINT_16", "UINT_32", "UINT_64", "INT_8",
+		"INT_16", "INT_32", "INT_64", "JSON", "BSON", "INTERVAL"}
+	parquetLogicalTypes = map[int]string{1: "STRING", 2: "MAP", 3: "LIST", 4: "ENUM", 5: "DECIMAL", 6: "DATE", 7: "TIME",
+		8: "TIMESTAMP", 10: "INTEGER", 11: "UNKNOWN", 12: "JSON", 13: "BSON", 14: "UUID", 15: "FLOAT16"}
+)
+
+// thriftStruct is a struct read with the Thrift compact protocol, as its
+// fields by their ids. Values are int64, float64, bool, []byte, []interface{},
+// or thriftStruct.
+type thriftStruct map[int]interface{}
+
+// readParquetFooter returns the metadata in the footer of a Parquet file: the
+// number of rows and row groups, the writer, the schema as a tree of fields,
+// and the key-value metadata, which is rendered as JSON where it is JSON.
+func readParquetFooter(data []byte) ([]*Node, error) {
+	if len(data) < 12 {
+		return nil, errTruncated
+	}
+	length := uint64(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if length > uint64(len(data)-12) {
+		return nil, fmt.Errorf("the footer says it has %d bytes", length)
+	}
+	r := &binaryReader{data: data[len(data)-8-int(length) : len(data)-8]}
+	metadata, err := readThriftStruct(r)
+	if err != nil {
+		return nil, fmt.Errorf("the footer: %v", err)
+	}
+
+	footer := newObjectNode()
+	add := func(key string, value *Node) {
+		footer.members = append(footer.members, Member{newStringNode(key), value})
+	}
+	if rows, ok := metadata[3].(int64); ok {
+		add("rows", newNumberNode(strconv.FormatInt(rows, 10)))
+	}
+	if groups, ok := metadata[4].([]interface{}); ok {
+		add("row_groups", newNumberNode(strconv.Itoa(len(groups))))
+	}
+	if writer, ok := metadata[6].([]byte); ok {
+		add("created_by", newStringNode(string(writer)))
+	}
+	if elements, ok := metadata[2].([]interface{}); ok && len(elements) > 0 {
+		schema, _ := parquetSchema(elements)
+		add("schema", schema)
+	}
+	if pairs, ok := metadata[5].([]interface{}); ok {
+		values := newObjectNode()
+		for _, pair := range pairs {
+			pair, _ := pair.(thriftStruct)
+			key, _ := pair[1].([]byte)
+			value, _ := pair[2].([]byte)
+			node, err := parseTokens(getTokens(value, Options{}))
+			if err != nil || len(value) == 0 {
+				node = newStringNode(string(value))
+			}
+			values.members = append(values.members, Member{newStringNode(string(key)), node})
+		}
+		add("metadata", values)
+	}
+	return []*Node{footer}, nil
+}
+
+// parquetSchema turns the schema elements, which are the tree of fields
+// written depth first with the number of children of each, into nested
+// objects, and returns the elements that are left
+func parquetSchema(elements []interface{}) (*Node, []interface{}) {
+	element, _ := elements[0].(thriftStruct)
+	elements = elements[1:]
+
+	field := newObjectNode()
+	add := func(key string, value *Node) {
+		field.members = append(field.members, Member{newStringNode(key), value})
+	}
+	name, _ := element[4].([]byte)
+	add("name", newStringNode(string(name)))
+	if kind, ok := element[1].(int64); ok {
+		add("type", newStringNode(parquetEnumName(parquetTypes, kind)))
+	}
+	if size, ok := element[2].(int64); ok {
+		add("type_length", newNumberNode(strconv.FormatInt(size, 10)))
+	}
+	if repetition, ok := element[3].(int64); ok {
+		add("repetition", newStringNode(parquetEnumName(parquetRepetitions, repetition)))
+	}
+	if logical, ok := element[10].(thriftStruct); ok {
+		for id := range logical {
+			if name, ok := parquetLogicalTypes[id]; ok {
+				add("logical_type", newStringNode(name))
+			}
+		}
+	} else if converted, ok := element[6].(int64); ok {
+		add("converted_type", newStringNode(parquetEnumName(parquetConvertedTypes, converted)))
+	}
+	if scale, ok := element[7].(int64); ok {
+		add("scale", newNumberNode(strconv.FormatInt(scale, 10)))
+	}
+	if precision, ok := element[8].(int64); ok {
+		add("precision", newNumberNode(strconv.FormatInt(precision, 10)))
+	}
+
+	if count, ok := element[5].(int64); ok {
+		children := newArrayNode()
+		for i := int64(0); i < count && len(elements) > 0; i++ {
+			var child *Node
+			child, elements = parquetSchema(elements)
+			children.elements = append(children.elements, child)
+		}
+		add("fields", children)
+	}
+	return field, elements
+}
+
+// parquetEnumName returns the name of a value of an enum, or its number if it
+// is one that is not known
+func parquetEnumName(names []string, value int64) string {
+	if value >= 0 && value < int64(len(names)) {
+		return names[value]
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// readThriftStruct reads a struct with the Thrift compact protocol
+func readThriftStruct(r *binaryReader) (thriftStruct, error) {
+	leave, err := r.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	fields := make(thriftStruct)
+	id := 0
+	for {
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return fields, nil
+		}
+
+		// The id is written as the difference from the last one if it fits
+		if delta := int(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			value, err := readAvroLong(r)
+			if err != nil {
+				return nil, err
+			}
+			id = int(value)
+		}
+
+		kind := header & 0x0f
+		switch kind {
+		case 1, 2:
+			fields[id] = kind == 1
+		default:
+			if fields[id], err = readThriftValue(r, kind); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readThriftValue reads a value of the type, other than a boolean field,
+// whose value is in its type
+func readThriftValue(r *binaryReader, kind byte) (interface{}, error) {
+	switch kind {
+	case 1, 2:
+		value, err := r.readByte()
+		return value == 1, err
+	case 3:
+		value, err := r.readByte()
+		return int64(int8(value)), err
+	case 4, 5, 6:
+		// Integers are zigzag varints, as in Avro
+		return readAvroLong(r)
+	case 7:
+		bits, err := r.readLittleEndian(8)
+		return math.Float64frombits(bits), err
+	case 8:
+		length, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		return r.read(length)
+	case 9, 10:
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		count := uint64(header >> 4)
+		if count == 15 {
+			if count, err = r.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+		if count > uint64(len(r.data)) {
+			return nil, fmt.Errorf("a list says it has %d elements", count)
+		}
+		leave, err := r.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		elements := make([]interface{}, 0, count)
+		for i := uint64(0); i < count; i++ {
+			element, err := readThriftValue(r, header&0x0f)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		return elements, nil
+	case 11:
+		count, err := r.readVarint()
+		if err != nil || count == 0 {
+			return []interface{}{}, err
+		}
+		if count > uint64(len(r.data)) {
+			return nil, fmt.Errorf("a map says it has %d entries", count)
+		}
+		types, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		// The entries are kept as a list of keys and values
+		entries := make([]interface{}, 0, 2*count)
+		for i := uint64(0); i < count; i++ {
+			for _, entryKind := range []byte{types >> 4, types & 0x0f} {
+				value, err := readThriftValue(r, entryKind)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, value)
+			}
+		}
+		return entries, nil
+	case 12:
+		return readThriftStruct(r)
+	}
+	return nil, errors.New("unknown Thrift type " + strconv.Itoa(int(kind)))
+}
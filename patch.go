@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// applyPatch applies a JSON Patch document (RFC 6902) to the tree and returns
+// the patched tree. Values that were added, copied, or moved are highlighted as
+// added, replaced values are highlighted as changed, and each removal leaves a
+// highlighted comment where the value used to be. The whole patch fails if any
+// of its operations fail, including a failed "test".
+func applyPatch(root *Node, patch *Node) (*Node, error) {
+	if patch.kind != NodeArray {
+		return nil, fmt.Errorf("a JSON Patch must be an array of operations")
+	}
+
+	var err error
+	for i, operation := range patch.elements {
+		root, err = applyPatchOperation(root, operation)
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %d: %v", i, err)
+		}
+	}
+
+	return root, nil
+}
+
+// applyPatchOperation applies a single operation object of a JSON Patch
+func applyPatchOperation(root *Node, operation *Node) (*Node, error) {
+	// stringMember returns the text of a string member of the operation
+	stringMember := func(key string) (string, error) {
+		value := member(operation, key)
+		if value == nil || value.kind != NodeString {
+			return "", fmt.Errorf("missing string %q", key)
+		}
+		return stringValue(value), nil
+	}
+
+	op, err := stringMember("op")
+	if err != nil {
+		return nil, err
+	}
+	pointer, err := stringMember("path")
+	if err != nil {
+		return nil, err
+	}
+	path, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "add", "replace", "test":
+		value := member(operation, "value")
+		if value == nil {
+			return nil, fmt.Errorf("missing \"value\"")
+		}
+		value = copyNode(value)
+		value.comments = nil
+
+		switch op {
+		case "add":
+			value.highlight = HighlightAdded
+			return addValue(root, path, value)
+		case "replace":
+			value.highlight = HighlightChanged
+			return replaceValue(root, path, value)
+		default:
+			current := lookupPath(root, path)
+			if current == nil || !nodesEqual(current, value) {
+				return nil, fmt.Errorf("test failed at %q", pointer)
+			}
+			return root, nil
+		}
+	case "remove":
+		removed, err := removeValue(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return nil, fmt.Errorf("cannot remove the whole document")
+		}
+		markRemoval(lookupPath(root, path[:len(path)-1]), path, removed)
+		return root, nil
+	case "move", "copy":
+		fromPointer, err := stringMember("from")
+		if err != nil {
+			return nil, err
+		}
+		from, err := parsePointer(fromPointer)
+		if err != nil {
+			return nil, err
+		}
+
+		value := lookupPath(root, from)
+		if value == nil {
+			return nil, fmt.Errorf("nothing to %s at %q", op, fromPointer)
+		}
+
+		if op == "move" {
+			if isPathPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("cannot move %q into itself", fromPointer)
+			}
+			if _, err := removeValue(root, from); err != nil {
+				return nil, err
+			}
+		}
+
+		value = copyNode(value)
+		value.highlight = HighlightAdded
+		return addValue(root, path, value)
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", op)
+}
+
+// addValue adds the value at the path the way the JSON Patch "add" operation
+// does: object members are created or replaced, array elements are inserted
+// before the given index or at the end for "-", and the empty path replaces
+// the whole document
+func addValue(root *Node, path []string, value *Node) (*Node, error) {
+	if len(path) == 0 {
+		value.comments = root.comments
+		value.trailingComments = root.trailingComments
+		return value, nil
+	}
+
+	parent := lookupPath(root, path[:len(path)-1])
+	key := path[len(path)-1]
+	if parent == nil {
+		return nil, fmt.Errorf("no parent for %q", formatPointer(path))
+	}
+
+	switch parent.kind {
+	case NodeObject:
+		for i, m := range parent.members {
+			if stringValue(m.key) == key {
+				parent.members[i].value = value
+				return root, nil
+			}
+		}
+
+		keyNode := newStringNode(key)
+		keyNode.highlight = value.highlight
+		parent.members = append(parent.members, Member{keyNode, value})
+	case NodeArray:
+		index := len(parent.elements)
+		if key != "-" {
+			var err error
+			index, err = strconv.Atoi(key)
+			if err != nil || index < 0 || index > len(parent.elements) {
+				return nil, fmt.Errorf("bad array index in %q", formatPointer(path))
+			}
+		}
+
+		parent.elements = append(parent.elements, nil)
+		copy(parent.elements[index+1:], parent.elements[index:])
+		parent.elements[index] = value
+	default:
+		return nil, fmt.Errorf("cannot add to a scalar at %q", formatPointer(path))
+	}
+
+	return root, nil
+}
+
+// replaceValue puts the value in place of the existing value at the path,
+// keeping its position within its object or array
+func replaceValue(root *Node, path []string, value *Node) (*Node, error) {
+	if lookupPath(root, path) == nil {
+		return nil, fmt.Errorf("nothing to replace at %q", formatPointer(path))
+	}
+
+	if len(path) > 0 {
+		parent := lookupPath(root, path[:len(path)-1])
+		if parent.kind == NodeArray {
+			index, _ := strconv.Atoi(path[len(path)-1])
+			parent.elements[index] = value
+			return root, nil
+		}
+	}
+
+	// Adding to an object replaces an existing member where it is
+	return addValue(root, path, value)
+}
+
+// removeValue removes the value at the path and returns it. The whole
+// document can not be removed, but it can be replaced, so removing the empty
+// path returns the document untouched.
+func removeValue(root *Node, path []string) (*Node, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+
+	parent := lookupPath(root, path[:len(path)-1])
+	key := path[len(path)-1]
+	if parent == nil {
+		return nil, fmt.Errorf("nothing to remove at %q", formatPointer(path))
+	}
+
+	switch parent.kind {
+	case NodeObject:
+		for i := len(parent.members) - 1; i >= 0; i-- {
+			if stringValue(parent.members[i].key) == key {
+				removed := parent.members[i].value
+				parent.members = append(parent.members[:i], parent.members[i+1:]...)
+				return removed, nil
+			}
+		}
+	case NodeArray:
+		index, err := strconv.Atoi(key)
+		if err == nil && index >= 0 && index < len(parent.elements) {
+			removed := parent.elements[index]
+			parent.elements = append(parent.elements[:index], parent.elements[index+1:]...)
+			return removed, nil
+		}
+	}
+
+	return nil, fmt.Errorf("nothing to remove at %q", formatPointer(path))
+}
+
+// markRemoval notes in the annotation of the container that a value was
+// removed from it, so that the removal can still be seen in the output.
+// Annotations are left out of plain JSON output, which a comment would not
+// be, so that output stays JSON.
+func markRemoval(parent *Node, path []string, removed *Node) {
+	note := "removed " + formatPointer(path) + ": " + valueSummary(removed)
+	parent.annotation = strings.TrimPrefix(parent.annotation+", "+note, ", ")
+}
+
+// valueSummary returns the value written on one line for a comment about it.
+// Large values are cut short, since the comment is only a reminder.
+func valueSummary(node *Node) string {
+	text := valueText(node)
+	if runes := []rune(text); len(runes) > 60 {
+		text = string(runes[:57]) + "..."
+	}
+	return text
+}
+
+// isPathPrefix returns true if the path starts with all of the prefix
+func isPathPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
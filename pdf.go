@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The PDF pages are A4 in points, with the text in Courier, one of the fonts
+// every PDF reader has, so nothing needs to be embedded
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+	pdfFontSize   = 9.0
+	pdfLineHeight = 11.0
+	pdfCharWidth  = pdfFontSize * 0.6 // Every Courier character is 600/1000 em
+)
+
+// printPDF renders the documents as a PDF with the colors of the theme, for
+// audit-ready documents in one step. Long lines are wrapped to the width of the
+// page. Courier only has the Latin-1 characters, so others are drawn as '?'.
+func printPDF(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	documents = localizeDocuments(documents, options)
+	colors := pageTheme(options)
+	width, height := pdfPageWidth-2*pdfMargin, pdfPageHeight-2*pdfMargin
+	columns := int(width / pdfCharWidth)
+	lines, err := layoutText(ctx, documents, colors, columns)
+	if err != nil {
+		return err
+	}
+
+	linesPerPage := int(height / pdfLineHeight)
+	pages := make([]string, 0)
+	for start := 0; start < len(lines); start += linesPerPage {
+		end := start + linesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, pdfPageContent(lines[start:end], colors))
+	}
+
+	return writePDF(w, pages)
+}
+
+// pdfPageContent returns the content stream that draws the lines on a page
+func pdfPageContent(lines [][]textRun, colors theme) string {
+	var content strings.Builder
+
+	// The page is filled with the background first
+	fmt.Fprintf(&content, "%s rg 0 0 %g %g re f\n", pdfColor(colors.background), pdfPageWidth, pdfPageHeight)
+
+	for i, line := range lines {
+		baseline := pdfPageHeight - pdfMargin - float64(i+1)*pdfLineHeight
+		x := pdfMargin
+		for _, run := range line {
+			width := float64(len([]rune(run.text))) * pdfCharWidth
+
+			if run.background != "" {
+				fmt.Fprintf(&content, "%s rg %.2f %.2f %.2f %g re f\n",
+					pdfColor(run.background), x, baseline-2.5, width, pdfLineHeight)
+			}
+
+			color := run.color
+			if color == "" {
+				color = colors.text
+			}
+			fmt.Fprintf(&content, "BT /F1 %g Tf %s rg %.2f %.2f Td (%s) Tj ET\n",
+				pdfFontSize, pdfColor(color), x, baseline, pdfString(run.text))
+
+			if run.isStruck {
+				fmt.Fprintf(&content, "%s RG 0.5 w %.2f %.2f m %.2f %.2f l S\n",
+					pdfColor(color), x, baseline+pdfFontSize*0.3, x+width, baseline+pdfFontSize*0.3)
+			}
+			x += width
+		}
+	}
+
+	return content.String()
+}
+
+// writePDF writes a PDF document with a page for each content stream
+func writePDF(w io.Writer, pages []string) error {
+	var pdf bytes.Buffer
+	offsets := make([]int, 0) // Where each object starts, for the cross-reference table
+
+	// addObject writes the next object and returns its number
+	addObject := func(body string) int {
+		offsets = append(offsets, pdf.Len())
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		return len(offsets)
+	}
+
+	pdf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1 to 3 are the catalog, the page tree, and the font, and the
+	// pages and their contents follow
+	pageNumbers := make([]string, len(pages))
+	for i := range pages {
+		pageNumbers[i] = strconv.Itoa(4+2*i) + " 0 R"
+	}
+	addObject("<< /Type /Catalog /Pages 2 0 R >>")
+	addObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageNumbers, " "), len(pages)))
+	addObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 5+2*i))
+		addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(pdf.Bytes())
+	return err
+}
+
+// pdfColor turns a CSS color such as #D75F5F into the 'red green blue' form
+// that PDF color operators take, with each part from 0 to 1
+func pdfColor(color string) string {
+	parts := strings.Split(ansiRGB(color), ";")
+	for i, part := range parts {
+		value, _ := strconv.Atoi(part)
+		parts[i] = strconv.FormatFloat(float64(value)/255, 'f', 3, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// pdfString escapes text for a PDF string in WinAnsiEncoding. Characters past
+// Latin-1 cannot be drawn in the standard fonts and become '?'.
+func pdfString(text string) string {
+	var escaped strings.Builder
+	for _, character := range text {
+		switch {
+		case character == '\\' || character == '(' || character == ')':
+			escaped.WriteString(`\` + string(character))
+		case character < 0x20:
+			escaped.WriteString(" ")
+		case character < 0x7F:
+			escaped.WriteRune(character)
+		case character >= 0xA0 && character <= 0xFF:
+			fmt.Fprintf(&escaped, "\\%03o", character)
+		default:
+			escaped.WriteString("?")
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// pluginHighlights are the names of the highlights in the plugin protocol
+var pluginHighlights = map[int]string{
+	HighlightAdded:       "added",
+	HighlightChanged:     "changed",
+	HighlightRemoved:     "removed",
+	HighlightSynthetic:   "synthetic",
+	HighlightOurs:        "ours",
+	HighlightTheirs:      "theirs",
+	HighlightExplanation: "explanation",
+	HighlightAlert:       "alert",
+}
+
+// runPlugin renders the documents with an output plugin, which is a separate
+// program that turns the tokens into any format it likes. The plugin is run
+// with the tokens as JSON on its standard input (see pluginInput), and what it
+// prints is the output. The name is looked up like any other program, and then
+// with the program name and a dash in front of it, so a plugin installed as
+// json-pretty-printer-confluence can be run as --plugin=confluence.
+func runPlugin(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	path, err := exec.LookPath(options.plugin)
+	if err != nil {
+		var prefixedErr error
+		path, prefixedErr = exec.LookPath(programName + "-" + options.plugin)
+		if prefixedErr != nil {
+			return fmt.Errorf("could not find the plugin %s: %v", options.plugin, err)
+		}
+	}
+
+	input := formatText(nodeTokens(pluginInput(documents)))
+
+	command := exec.CommandContext(ctx, path)
+	command.Stdin = bytes.NewReader([]byte(input))
+	command.Stdout = w
+	command.Stderr = os.Stderr
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("the plugin %s failed: %v", options.plugin, err)
+	}
+	return nil
+}
+
+// pluginInput returns the document that is sent to plugins. It has a version,
+// which only changes if the protocol stops being compatible, and the tokens of
+// each document. Every token has its kind (by the name TokenKind gives it),
+// its content, and the white space that goes before and after it in the
+// standard layout, so that plugins do not have to work out the layout again.
+// Tokens read from the input also have their byte offset in it, and
+// highlighted tokens have a highlight: added, changed, or removed.
+func pluginInput(documents [][]Token) *Node {
+	documentsNode := newArrayNode()
+
+	for _, document := range documents {
+		state := printState{isLineStart: true}
+		tokensNode := newArrayNode()
+
+		for _, token := range document {
+			whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+
+			tokenNode := newObjectNode()
+			tokenNode.members = append(tokenNode.members,
+				Member{newStringNode("kind"), newStringNode(token.kind.String())},
+				Member{newStringNode("content"), newStringNode(token.content)},
+				Member{newStringNode("before"), newStringNode(whiteSpacePre)},
+				Member{newStringNode("after"), newStringNode(whiteSpacePost)})
+			if token.offset >= 0 {
+				tokenNode.members = append(tokenNode.members,
+					Member{newStringNode("offset"), newNumberNode(strconv.Itoa(token.offset))})
+			}
+			if highlight, ok := pluginHighlights[token.highlight]; ok {
+				tokenNode.members = append(tokenNode.members,
+					Member{newStringNode("highlight"), newStringNode(highlight)})
+			}
+			tokensNode.elements = append(tokensNode.elements, tokenNode)
+		}
+
+		documentNode := newObjectNode()
+		documentNode.members = append(documentNode.members, Member{newStringNode("tokens"), tokensNode})
+		documentsNode.elements = append(documentsNode.elements, documentNode)
+	}
+
+	input := newObjectNode()
+	input.members = append(input.members,
+		Member{newStringNode("version"), newNumberNode("1")},
+		Member{newStringNode("documents"), documentsNode})
+	return input
+}
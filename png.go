@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// pngPadding is how many pixels of the background are left around the text,
+// before --scale
+const pngPadding = 8
+
+// pngMaxPixels is the largest image that is drawn, which takes 4 bytes a pixel
+const pngMaxPixels = 64 << 20
+
+// printPNG draws the documents as a PNG image in a monospace font and the
+// colors of the theme, for sharing in chat tools that mangle formatted text.
+// Each pixel of the font is drawn as a square of --scale pixels.
+func printPNG(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	documents = localizeDocuments(documents, options)
+	font := builtinFont()
+	if options.fontFile != "" {
+		var err error
+		if font, err = readBDFFont(options.fontFile); err != nil {
+			return err
+		}
+	}
+
+	colors := pageTheme(options)
+	lines, err := layoutText(ctx, documents, colors, 0)
+	if err != nil {
+		return err
+	}
+
+	columns := 0
+	for _, line := range lines {
+		length := 0
+		for _, run := range line {
+			length += utf8.RuneCountInString(run.text)
+		}
+		if length > columns {
+			columns = length
+		}
+	}
+
+	scale := options.scale
+	width := (columns*font.width + 2*pngPadding) * scale
+	height := (len(lines)*font.height + 2*pngPadding) * scale
+	if width*height > pngMaxPixels {
+		return fmt.Errorf("the image would be %dx%d pixels, which is too large; try a smaller --scale or document", width, height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// fill paints a rectangle, given in the pixels of the font
+	fill := func(x, y, w, h int, paint color.RGBA) {
+		for row := y * scale; row < (y+h)*scale; row++ {
+			for column := x * scale; column < (x+w)*scale; column++ {
+				img.SetRGBA(column, row, paint)
+			}
+		}
+	}
+
+	fill(0, 0, width/scale, height/scale, pngColor(colors.background))
+	for i, line := range lines {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		x, y := pngPadding, pngPadding+i*font.height
+		for _, run := range line {
+			length := utf8.RuneCountInString(run.text)
+			if run.background != "" {
+				fill(x, y, length*font.width, font.height, pngColor(run.background))
+			}
+
+			textColor := run.color
+			if textColor == "" {
+				textColor = colors.text
+			}
+			paint := pngColor(textColor)
+
+			for _, character := range run.text {
+				for row, bits := range font.glyph(character) {
+					for column := 0; column < font.width; column++ {
+						if bits&(1<<uint(column)) != 0 {
+							fill(x+column, y+row, 1, 1, paint)
+						}
+					}
+				}
+				x += font.width
+			}
+
+			if run.isStruck {
+				fill(x-length*font.width, y+font.height/2, length*font.width, 1, paint)
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// pngColor turns a CSS color such as #D75F5F into an opaque color
+func pngColor(cssColor string) color.RGBA {
+	parts := strings.Split(ansiRGB(cssColor), ";")
+	values := make([]uint8, len(parts))
+	for i, part := range parts {
+		value, _ := strconv.Atoi(part)
+		values[i] = uint8(value)
+	}
+	return color.RGBA{R: values[0], G: values[1], B: values[2], A: 255}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parsePointer splits a JSON Pointer (RFC 6901) such as "/items/0/a~1b" into
+// its unescaped reference tokens. The empty pointer refers to the whole
+// document and has no tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("JSON Pointer %q does not start with '/'", pointer)
+	}
+
+	segments := strings.Split(pointer[1:], "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+	}
+	return segments, nil
+}
+
+// formatPointer joins reference tokens into a JSON Pointer, escaping '~' and
+// '/' inside each of them
+func formatPointer(segments []string) string {
+	var pointer string
+	for _, segment := range segments {
+		pointer += "/" + strings.Replace(strings.Replace(segment, "~", "~0", -1), "/", "~1", -1)
+	}
+	return pointer
+}
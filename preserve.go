@@ -0,0 +1,32 @@
+package main
+
+// preserveLayout returns the tokens of a document with the white space that
+// was between them in the input, for --preserve-layout. Each gap becomes a
+// WhiteSpace token, and one always starts the document, which tells
+// addWhiteSpace to leave the standard layout out. Anything in a gap other
+// than white space, such as a trailing comma that was removed, is left out.
+func preserveLayout(jsonFile []byte, tokenArray []Token) []Token {
+	laidOut := make([]Token, 0, 2*len(tokenArray)+1)
+	laidOut = append(laidOut, makeToken("", WhiteSpace))
+
+	end := -1 // Where the last token read from the input ends
+	for _, token := range tokenArray {
+		if end >= 0 && token.offset >= end {
+			gap := make([]byte, 0, token.offset-end)
+			for _, character := range jsonFile[end:token.offset] {
+				if isWhiteSpace(character) {
+					gap = append(gap, character)
+				}
+			}
+			if len(gap) > 0 {
+				laidOut = append(laidOut, Token{content: string(gap), kind: WhiteSpace, offset: end})
+			}
+		}
+
+		laidOut = append(laidOut, token)
+		if token.offset >= 0 {
+			end = token.offset + len(token.content)
+		}
+	}
+	return laidOut
+}
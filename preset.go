@@ -0,0 +1,9 @@
+package main
+
+// presets are the flags that each --preset stands for. The flags given on the
+// command line are read after them, so they win. Presets can also turn on
+// behavior of their own, which checks options.preset.
+var presets = map[string][]string{
+	"cloud-cli":    {"--sort-keys", "--collapsible"},
+	"package-json": {},
+}
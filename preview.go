@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// runPreview renders the schema of an Avro data file or a Parquet file and the
+// first --records records of it, for a quick look at data without loading it
+// into anything. The schema is the first document and each record is one
+// after it. Parquet files only have their footer read, so only their schema
+// and metadata are shown.
+func runPreview(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("preview needs an Avro data file or a Parquet file")
+	}
+
+	data, err := ioutil.ReadFile(arguments[0])
+	if err != nil {
+		panic(err)
+	}
+
+	var roots []*Node
+	switch {
+	case bytes.HasPrefix(data, avroMagic):
+		roots, err = readAvroFile(data, options.records)
+	case bytes.HasPrefix(data, parquetMagic) && bytes.HasSuffix(data, parquetMagic):
+		roots, err = readParquetFooter(data)
+		fmt.Fprintln(os.Stderr, "Only the footer of Parquet files is read, so no records are shown")
+	default:
+		err = errors.New("not an Avro data file or a Parquet file")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", arguments[0], err)
+		os.Exit(1)
+	}
+
+	documents := make([][]Token, 0, len(roots))
+	for _, root := range roots {
+		if isTreeNeeded(options) {
+			if root, err = transformTree(root, options, os.Stderr); err != nil {
+				panic(err)
+			}
+		}
+		documents = append(documents, nodeTokens(root))
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	if err := printOutput(ctx, os.Stdout, documents, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+}
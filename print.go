@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printStyle is added to the page header by --print-friendly. Small
+// containers are kept on one page, links show where they go, and the page
+// prints without a background.
+const printStyle = `@media print {
+body { background-color:white !important; margin:0 }
+.print-keep { break-inside:avoid; page-break-inside:avoid }
+.print-document { orphans:3; widows:3 }
+a[href^="http"]::after { content:" (" attr(href) ")"; font-size:80% }
+}`
+
+// printKeepLines is the most lines a container can have for a page break to
+// be avoided inside it. Larger containers would leave big gaps on the page.
+const printKeepLines = 40
+
+// printFriendlyTheme returns the theme in black on white, which prints and
+// photocopies well. Highlights stay apart through their shades of gray and,
+// for removed values, the line through them.
+func printFriendlyTheme(colors theme) theme {
+	return theme{
+		name:       colors.name,
+		background: "#FFFFFF",
+		separator:  "#999999",
+		object:     "#000000",
+		array:      "#000000",
+		pair:       "#000000",
+		member:     "#000000",
+		text:       "#000000",
+		escape:     "#000000",
+		number:     "#000000",
+		literal:    "#000000",
+		comment:    "#555555",
+		annotation: "#555555",
+		added:      "#D0D0D0",
+		changed:    "#E8E8E8",
+		removed:    "#F0F0F0",
+	}
+}
+
+// printFriendlyDocument prints a single top-level value for --print-friendly.
+// The tokens are laid out a line at a time so that the lines of each small
+// container can be wrapped in a block that the page should not break inside.
+// Folding and anchors are left out, since they only work on a screen.
+func printFriendlyDocument(ctx context.Context, w io.Writer, tokenArray []Token, options Options) error {
+	colors := pageTheme(options)
+	state := printState{isLineStart: true}
+
+	lines := []string{""}
+	tokenLines := make([]int, len(tokenArray)) // The line each token is on
+
+	// addText adds text to the lines, starting a new line after each newline
+	addText := func(text string) {
+		parts := strings.Split(text, "\n")
+		lines[len(lines)-1] += parts[0]
+		lines = append(lines, parts[1:]...)
+	}
+
+	for i, token := range tokenArray {
+		if i%4096 == 0 || token.kind == ObjectOpen || token.kind == ArrayOpen {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+		colorPre, colorPost := addColor(token, colors)
+		colorPre, colorPost = applyStyleHook(token, options.styleHooks, colorPre, colorPost)
+
+		addText(whiteSpacePre)
+		tokenLines[i] = len(lines) - 1
+		// Newlines in the token itself, such as in a block comment, stay
+		// inside its line
+		lines[len(lines)-1] += colorPre + escapeString(token) + colorPost
+		addText(whiteSpacePost)
+	}
+
+	// Find the blocks to keep together, which nest like their containers
+	opens := make(map[int]int)  // How many blocks start at each line
+	closes := make(map[int]int) // How many blocks end at each line
+	for open, close := range matchBrackets(tokenArray) {
+		first, last := tokenLines[open], tokenLines[close]
+		if last > first && last-first < printKeepLines {
+			opens[first]++
+			closes[last]++
+		}
+	}
+
+	fmt.Fprintln(w, "\t\t"+"<div class=\"print-document\" style=\"font-family:monospace; tab-size:4; white-space:pre\">")
+	for i, line := range lines {
+		fmt.Fprint(w, strings.Repeat("<div class=\"print-keep\">", opens[i]))
+		fmt.Fprint(w, line)
+		fmt.Fprint(w, strings.Repeat("</div>", closes[i]))
+
+		// The edge of a block already ends the line
+		if i < len(lines)-1 && closes[i] == 0 && opens[i+1] == 0 {
+			fmt.Fprint(w, "\n")
+		}
+	}
+	fmt.Fprint(w, "\n")
+	fmt.Fprintln(w, "\t\t"+"</div>")
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// problemDepth is how deeply values can be nested before it is reported as a
+// problem. Many parsers refuse documents much deeper than this.
+const problemDepth = 64
+
+// numberPattern matches the text of a JSON number
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// problem is something worth a warning or an error that was found in the
+// input
+type problem struct {
+	kind     string // What sort of problem it is, such as duplicate-key
+	message  string
+	document int      // The top-level value it is in, counting from 1, or 0
+	path     []string // Where it is in the document, if it is in a value
+	offset   int      // Where it is in the input, or -1 if it is not known
+}
+
+// findProblems returns the problems in the input: whatever makes it invalid,
+// including each invalid escape, along with warnings for repairs that had to
+// be made, duplicate keys, numbers written as strings, and values nested
+// deeper than problemDepth. Offsets are in the repaired input
+// when --repair is on.
+func findProblems(jsonFile []byte, options Options) []problem {
+	problems := make([]problem, 0)
+
+	if options.repair {
+		var repairs []string
+		jsonFile, repairs = repairJSON(jsonFile, options)
+		for _, repair := range repairs {
+			offset := -1
+			fmt.Sscanf(repair, "offset %d:", &offset)
+			message := repair
+			if colon := strings.Index(repair, ": "); colon >= 0 {
+				message = repair[colon+2:]
+			}
+			problems = append(problems, problem{kind: "repair", message: message, offset: offset})
+		}
+	}
+
+	tokenArray := getTokens(jsonFile, options)
+	if options.fixTrailingCommas {
+		tokenArray, _ = removeTrailingCommas(tokenArray)
+	}
+
+	for _, token := range tokenArray {
+		if token.kind == StringEscaped && !isValidEscape(token.content) {
+			problems = append(problems, problem{kind: "invalid-escape",
+				message: token.content + " is not a valid escape", offset: token.offset})
+		}
+	}
+
+	// What makes the input invalid is found by checkInput, so that the
+	// problems agree with --analyze. An invalid escape is already listed.
+	documents := splitDocuments(tokenArray)
+	if _, _, err := checkInput(jsonFile, options); err != nil {
+		// Only the last document can run into the end of the input
+		offset := len(jsonFile)
+		var syntaxError *SyntaxError
+		if errors.As(err, &syntaxError) && syntaxError.Offset >= 0 {
+			offset = syntaxError.Offset
+		}
+
+		isListed := false
+		for _, p := range problems {
+			isListed = isListed || (p.kind == "invalid-escape" && p.offset == offset)
+		}
+		document := 0
+		for i, tokens := range documents {
+			if len(tokens) > 0 && tokens[0].offset <= offset {
+				document = i + 1
+			}
+		}
+		if !isListed {
+			problems = append(problems, problem{kind: "invalid", message: err.Error(), document: document, offset: offset})
+		}
+	}
+
+	// The documents that parse are checked for warnings
+	for i, document := range documents {
+		if root, err := parseTokens(document); err == nil {
+			problems = appendNodeProblems(problems, root, i+1, []string{})
+		}
+	}
+
+	return problems
+}
+
+// appendNodeProblems adds the problems of the node and the values inside it
+func appendNodeProblems(problems []problem, node *Node, document int, path []string) []problem {
+	offset := -1
+	if len(node.tokens) > 0 {
+		offset = node.tokens[0].offset
+	}
+
+	// Only the outermost value that is too deep is reported
+	if len(path) == problemDepth+1 {
+		return append(problems, problem{kind: "depth",
+			message:  fmt.Sprintf("nested more than %d deep", problemDepth),
+			document: document, path: path, offset: offset})
+	}
+
+	switch node.kind {
+	case NodeObject:
+		seen := make(map[string]bool, len(node.members))
+		for _, m := range node.members {
+			key := stringValue(m.key)
+			memberPath := append(append([]string{}, path...), key)
+			if seen[key] {
+				problems = append(problems, problem{kind: "duplicate-key",
+					message:  fmt.Sprintf("the key %q appears more than once", key),
+					document: document, path: memberPath, offset: m.key.tokens[0].offset})
+			}
+			seen[key] = true
+			problems = appendNodeProblems(problems, m.value, document, memberPath)
+		}
+	case NodeArray:
+		for i, element := range node.elements {
+			problems = appendNodeProblems(problems, element, document,
+				append(append([]string{}, path...), strconv.Itoa(i)))
+		}
+	case NodeString:
+		if text := stringValue(node); numberPattern.MatchString(text) {
+			problems = append(problems, problem{kind: "number-as-string",
+				message:  fmt.Sprintf("the string %q holds a number", text),
+				document: document, path: path, offset: offset})
+		}
+	}
+
+	return problems
+}
+
+// isValidEscape returns true if the StringEscaped token is an escape that JSON
+// allows
+func isValidEscape(content string) bool {
+	if len(content) < 2 {
+		return false
+	}
+	switch content[1] {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+		return len(content) == 2
+	case 'u':
+		if len(content) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(content[2:], 16, 16)
+		return err == nil
+	}
+	return false
+}
+
+// buildProblemsReport returns the problems as a document for CI pipelines.
+// Each problem has its kind, a message, and, when they are known, the document
+// it is in (counting from 1), the JSON Pointer of the value, and the byte
+// offset in the input.
+func buildProblemsReport(problems []problem, fileName string) *Node {
+	problemsNode := newArrayNode()
+	for _, p := range problems {
+		problemNode := newObjectNode()
+		problemNode.members = append(problemNode.members,
+			Member{newStringNode("kind"), newStringNode(p.kind)},
+			Member{newStringNode("message"), newStringNode(p.message)})
+		if p.document > 0 {
+			problemNode.members = append(problemNode.members,
+				Member{newStringNode("document"), newNumberNode(strconv.Itoa(p.document))})
+		}
+		if p.path != nil {
+			problemNode.members = append(problemNode.members,
+				Member{newStringNode("path"), newStringNode(formatPointer(p.path))})
+		}
+		if p.offset >= 0 {
+			problemNode.members = append(problemNode.members,
+				Member{newStringNode("offset"), newNumberNode(strconv.Itoa(p.offset))})
+		}
+		problemsNode.elements = append(problemsNode.elements, problemNode)
+	}
+
+	report := newObjectNode()
+	report.members = append(report.members,
+		Member{newStringNode("version"), newNumberNode("1")},
+		Member{newStringNode("source"), newStringNode(fileName)},
+		Member{newStringNode("problems"), problemsNode})
+	return report
+}
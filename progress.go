@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressThreshold is how large the input has to be, in bytes, before
+// progress is shown
+const progressThreshold = 32 << 20
+
+// progressReporter shows how far along reading, tokenizing, and rendering a
+// large input are on standard error, so that long runs do not look hung. On a
+// terminal it draws a bar that is redrawn in place; anywhere else it writes a
+// line every 10%. A nil reporter shows nothing.
+type progressReporter struct {
+	w          io.Writer
+	isTerminal bool
+	phase      string    // The phase being shown
+	done       int64     // The furthest point reached in the phase
+	total      int64     // Where the phase ends
+	percent    int       // The last percentage that was shown
+	shown      time.Time // When the bar was last drawn
+}
+
+// newProgressReporter returns a reporter for an input of the given size, or
+// nil if progress should not be shown. Progress is only shown for inputs of
+// at least progressThreshold bytes whose output is not going to a terminal,
+// where it would get mixed up with the output, and never with --no-progress.
+func newProgressReporter(options Options, size int64) *progressReporter {
+	if options.noProgress || size < progressThreshold || isTerminal(os.Stdout) {
+		return nil
+	}
+	return &progressReporter{w: os.Stderr, isTerminal: isTerminal(os.Stderr)}
+}
+
+// update records that done of the total bytes of the phase have been handled
+func (p *progressReporter) update(phase string, done, total int64) {
+	if p == nil || total <= 0 {
+		return
+	}
+
+	if phase != p.phase {
+		p.finish()
+		p.phase, p.done, p.percent, p.shown = phase, 0, -1, time.Time{}
+	}
+	p.total = total
+
+	// Rendering can jump back and forth in the input, such as after a sort,
+	// so only the furthest point counts
+	if done < p.done {
+		return
+	}
+	p.done = done
+	percent := int(done * 100 / total)
+
+	if p.isTerminal {
+		if time.Since(p.shown) < 100*time.Millisecond && done < total {
+			return
+		}
+		p.shown = time.Now()
+		bar := strings.Repeat("#", percent/5) + strings.Repeat(" ", 20-percent/5)
+		fmt.Fprintf(p.w, "\r%-8s [%s] %3d%% (%s / %s)", phase, bar, percent, formatBytes(done), formatBytes(total))
+	} else if percent/10 > p.percent/10 {
+		fmt.Fprintf(p.w, "%s %d%% (%s / %s)\n", phase, percent, formatBytes(done), formatBytes(total))
+	}
+	p.percent = percent
+}
+
+// finish shows the phase as complete, since rendering never quite reaches the
+// end of the input, and ends the line of the bar
+func (p *progressReporter) finish() {
+	if p == nil || p.phase == "" {
+		return
+	}
+	if p.done < p.total {
+		p.shown = time.Time{}
+		p.update(p.phase, p.total, p.total)
+	}
+	if p.isTerminal {
+		fmt.Fprintln(p.w)
+	}
+	p.phase = ""
+}
+
+// progressReader passes reads on and reports how much has been read
+type progressReader struct {
+	r        io.Reader
+	progress *progressReporter
+	read     int64
+	total    int64
+}
+
+// Read reads from the underlying reader and updates the progress
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	r.progress.update("read", r.read, r.total)
+	return n, err
+}
+
+// readFileWithProgress reads the whole file like ioutil.ReadFile and returns
+// a reporter for the rest of the run. The reading itself is shown if the file
+// is large enough. The file "-" is standard input.
+func readFileWithProgress(fileName string, options Options) ([]byte, *progressReporter, error) {
+	file := os.Stdin
+	if fileName != "-" {
+		var err error
+		file, err = os.Open(fileName)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// With --tee, the raw input is copied to a file as it is read, so it is
+	// kept even if formatting fails
+	var input io.Reader = file
+	if options.teeFile != "" {
+		tee, err := os.Create(options.teeFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer tee.Close()
+		input = io.TeeReader(file, tee)
+	}
+
+	// Pipes have no size, so their progress is never shown
+	progress := newProgressReporter(options, info.Size())
+	if progress == nil {
+		jsonFile, err := io.ReadAll(input)
+		return jsonFile, nil, err
+	}
+
+	jsonFile, err := io.ReadAll(&progressReader{r: input, progress: progress, total: info.Size()})
+	return jsonFile, progress, err
+}
+
+// formatBytes writes a number of bytes in the largest unit that keeps it at
+// least 1, such as 12.3 MB
+func formatBytes(count int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(count)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", count)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -0,0 +1,764 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field types of FieldDescriptorProto, as numbered in descriptor.proto
+const (
+	protoDouble   = 1
+	protoFloat    = 2
+	protoInt64    = 3
+	protoUint64   = 4
+	protoInt32    = 5
+	protoFixed64  = 6
+	protoFixed32  = 7
+	protoBool     = 8
+	protoString   = 9
+	protoGroup    = 10
+	protoMessage  = 11
+	protoBytes    = 12
+	protoUint32   = 13
+	protoEnum     = 14
+	protoSfixed32 = 15
+	protoSfixed64 = 16
+	protoSint32   = 17
+	protoSint64   = 18
+)
+
+// Labels of FieldDescriptorProto
+const (
+	protoRequired = 2
+	protoRepeated = 3
+)
+
+// protoTypeNames are the names of the scalar field types, as they are written
+// in .proto files
+var protoTypeNames = map[int]string{
+	protoDouble: "double", protoFloat: "float", protoInt64: "int64", protoUint64: "uint64",
+	protoInt32: "int32", protoFixed64: "fixed64", protoFixed32: "fixed32", protoBool: "bool",
+	protoString: "string", protoBytes: "bytes", protoUint32: "uint32", protoSfixed32: "sfixed32",
+	protoSfixed64: "sfixed64", protoSint32: "sint32", protoSint64: "sint64",
+}
+
+// protoWrappers are the well-known types that wrap a scalar, which is what
+// they are written as in JSON
+var protoWrappers = map[string]int{
+	"google.protobuf.DoubleValue": protoDouble,
+	"google.protobuf.FloatValue":  protoFloat,
+	"google.protobuf.Int64Value":  protoInt64,
+	"google.protobuf.UInt64Value": protoUint64,
+	"google.protobuf.Int32Value":  protoInt32,
+	"google.protobuf.UInt32Value": protoUint32,
+	"google.protobuf.BoolValue":   protoBool,
+	"google.protobuf.StringValue": protoString,
+	"google.protobuf.BytesValue":  protoBytes,
+}
+
+// protoSpecialTypes are the other well-known types that are written in JSON
+// in their own way
+var protoSpecialTypes = map[string]bool{
+	"google.protobuf.Timestamp": true,
+	"google.protobuf.Duration":  true,
+	"google.protobuf.FieldMask": true,
+	"google.protobuf.Struct":    true,
+	"google.protobuf.ListValue": true,
+	"google.protobuf.Value":     true,
+	"google.protobuf.Empty":     true,
+	"google.protobuf.Any":       true,
+}
+
+// protoDurationPattern matches a google.protobuf.Duration in JSON
+var protoDurationPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,9})?s$`)
+
+// protoPathPattern matches a path of a google.protobuf.FieldMask in JSON
+var protoPathPattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*(\.[a-z][a-zA-Z0-9]*)*$`)
+
+// protoDescriptors are the messages and enums of a descriptor set, by their
+// full names
+type protoDescriptors struct {
+	messages       map[string]*protoMessageType
+	enums          map[string]*protoEnumType
+	defaultMessage string // The first message of the last file
+}
+
+// protoMessageType is a message of a descriptor set
+type protoMessageType struct {
+	name       string
+	fields     []*protoField
+	oneofs     []string
+	isProto3   bool
+	isMapEntry bool
+}
+
+// protoField is a field of a message
+type protoField struct {
+	name       string
+	jsonName   string
+	label      int
+	kind       int
+	typeName   string // The full name of a message or enum type
+	oneof      int    // The index of its oneof, or -1
+	isOptional bool   // A proto3 field declared optional
+}
+
+// protoEnumType is an enum of a descriptor set
+type protoEnumType struct {
+	name   string
+	values map[string]int32
+}
+
+// protoRecord is a field of a protobuf message as it is on the wire
+type protoRecord struct {
+	number int
+	varint uint64 // The value of a varint field
+	data   []byte // The value of a length-delimited field
+}
+
+// readVarint returns the next base 128 varint
+func (r *binaryReader) readVarint() (uint64, error) {
+	var value uint64
+	for shift := uint(0); shift < 70; shift += 7 {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("a varint is longer than 10 bytes")
+}
+
+// protoRecords returns the fields of an encoded message, skipping the value
+// of fixed-size ones, which descriptors do not use
+func protoRecords(data []byte) ([]protoRecord, error) {
+	r := &binaryReader{data: data}
+	records := make([]protoRecord, 0)
+	for r.position < len(r.data) {
+		key, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		record := protoRecord{number: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			record.varint, err = r.readVarint()
+		case 1:
+			_, err = r.read(8)
+		case 2:
+			var length uint64
+			if length, err = r.readVarint(); err == nil {
+				record.data, err = r.read(length)
+			}
+		case 5:
+			_, err = r.read(4)
+		default:
+			err = fmt.Errorf("wire type %d is not supported", key&7)
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readProtoDescriptors reads a FileDescriptorSet, such as protoc writes with
+// --descriptor_set_out. With --include_imports, it has the types of the files
+// that are imported as well.
+func readProtoDescriptors(fileName string) (*protoDescriptors, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	files, err := protoRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	descriptors := &protoDescriptors{messages: make(map[string]*protoMessageType), enums: make(map[string]*protoEnumType)}
+	for _, file := range files {
+		if file.number != 1 {
+			continue
+		}
+		records, err := protoRecords(file.data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+
+		prefix, isProto3 := "", false
+		for _, record := range records {
+			switch record.number {
+			case 2:
+				prefix = string(record.data) + "."
+			case 12:
+				isProto3 = string(record.data) == "proto3"
+			}
+		}
+
+		isFirst := true
+		for _, record := range records {
+			switch record.number {
+			case 4:
+				name, err := descriptors.addMessage(record.data, prefix, isProto3)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", fileName, err)
+				}
+				if isFirst {
+					descriptors.defaultMessage, isFirst = name, false
+				}
+			case 5:
+				if err := descriptors.addEnum(record.data, prefix); err != nil {
+					return nil, fmt.Errorf("%s: %v", fileName, err)
+				}
+			}
+		}
+	}
+
+	if len(descriptors.messages) == 0 {
+		return nil, fmt.Errorf("%s: there are no messages in the descriptor set", fileName)
+	}
+	return descriptors, nil
+}
+
+// addMessage adds a DescriptorProto, with the messages and enums nested in it,
+// and returns its full name
+func (d *protoDescriptors) addMessage(data []byte, prefix string, isProto3 bool) (string, error) {
+	records, err := protoRecords(data)
+	if err != nil {
+		return "", err
+	}
+
+	message := &protoMessageType{isProto3: isProto3}
+	for _, record := range records {
+		if record.number == 1 {
+			message.name = prefix + string(record.data)
+		}
+	}
+	d.messages[message.name] = message
+
+	for _, record := range records {
+		switch record.number {
+		case 2:
+			field, err := readProtoField(record.data)
+			if err != nil {
+				return "", err
+			}
+			message.fields = append(message.fields, field)
+		case 3:
+			if _, err := d.addMessage(record.data, message.name+".", isProto3); err != nil {
+				return "", err
+			}
+		case 4:
+			if err := d.addEnum(record.data, message.name+"."); err != nil {
+				return "", err
+			}
+		case 7:
+			options, err := protoRecords(record.data)
+			if err != nil {
+				return "", err
+			}
+			for _, option := range options {
+				if option.number == 7 {
+					message.isMapEntry = option.varint != 0
+				}
+			}
+		case 8:
+			oneof, err := protoRecords(record.data)
+			if err != nil {
+				return "", err
+			}
+			name := ""
+			for _, part := range oneof {
+				if part.number == 1 {
+					name = string(part.data)
+				}
+			}
+			message.oneofs = append(message.oneofs, name)
+		}
+	}
+	return message.name, nil
+}
+
+// readProtoField reads a FieldDescriptorProto
+func readProtoField(data []byte) (*protoField, error) {
+	records, err := protoRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	field := &protoField{oneof: -1}
+	for _, record := range records {
+		switch record.number {
+		case 1:
+			field.name = string(record.data)
+		case 4:
+			field.label = int(record.varint)
+		case 5:
+			field.kind = int(record.varint)
+		case 6:
+			field.typeName = strings.TrimPrefix(string(record.data), ".")
+		case 9:
+			field.oneof = int(record.varint)
+		case 10:
+			field.jsonName = string(record.data)
+		case 17:
+			field.isOptional = record.varint != 0
+		}
+	}
+	if field.jsonName == "" {
+		field.jsonName = protoJSONName(field.name)
+	}
+	return field, nil
+}
+
+// addEnum adds an EnumDescriptorProto
+func (d *protoDescriptors) addEnum(data []byte, prefix string) error {
+	records, err := protoRecords(data)
+	if err != nil {
+		return err
+	}
+
+	enum := &protoEnumType{values: make(map[string]int32)}
+	for _, record := range records {
+		switch record.number {
+		case 1:
+			enum.name = prefix + string(record.data)
+		case 2:
+			value, err := protoRecords(record.data)
+			if err != nil {
+				return err
+			}
+			var name string
+			var number int32
+			for _, part := range value {
+				switch part.number {
+				case 1:
+					name = string(part.data)
+				case 2:
+					number = int32(part.varint)
+				}
+			}
+			enum.values[name] = number
+		}
+	}
+	d.enums[enum.name] = enum
+	return nil
+}
+
+// protoJSONName returns the lowerCamelCase name that protoc gives a field in
+// JSON
+func protoJSONName(name string) string {
+	var text strings.Builder
+	isUpper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			isUpper = true
+		case isUpper && r >= 'a' && r <= 'z':
+			text.WriteRune(r - 'a' + 'A')
+			isUpper = false
+		default:
+			text.WriteRune(r)
+			isUpper = false
+		}
+	}
+	return text.String()
+}
+
+// field returns the field of the message with the JSON or proto name, or nil
+func (m *protoMessageType) field(name string) *protoField {
+	for _, field := range m.fields {
+		if field.jsonName == name || field.name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// typeName returns the type of the field as it is written in a .proto file
+func (f *protoField) typeText() string {
+	if name, ok := protoTypeNames[f.kind]; ok {
+		return name
+	}
+	return f.typeName
+}
+
+// protoChecker checks a document against the protobuf JSON mapping and keeps
+// the problems it finds
+type protoChecker struct {
+	descriptors *protoDescriptors
+	problems    []string
+}
+
+// checkProto checks the document against the JSON mapping of the message and
+// returns the problems, with their paths. Each field is annotated with its
+// type, and fields that proto3 leaves out of JSON when they have their
+// default value are noted as such. Problems are highlighted in the document
+// with a comment that describes them, and unknown fields, which parsers
+// reject, are highlighted as removed.
+func checkProto(root *Node, descriptors *protoDescriptors, messageName string) ([]string, error) {
+	if messageName == "" {
+		messageName = descriptors.defaultMessage
+	}
+	checker := &protoChecker{descriptors: descriptors, problems: make([]string, 0)}
+	if !checker.checkWellKnown(root, messageName, []string{}) {
+		message, ok := descriptors.messages[messageName]
+		if !ok {
+			return nil, errors.New("Unknown protobuf message: " + messageName)
+		}
+		checker.checkMessage(root, message, []string{}, false)
+	}
+	root.annotation = messageName
+	return checker.problems, nil
+}
+
+// flag marks a value as a problem and adds it to the problems
+func (c *protoChecker) flag(node *Node, path []string, text string) {
+	node.highlight = HighlightChanged
+	comment := makeToken("/* "+text+" */", Comment)
+	comment.highlight = HighlightChanged
+	node.comments = append(node.comments, comment)
+	c.problems = append(c.problems, formatPointer(path)+": "+text)
+}
+
+// checkMessage checks an object against the fields of a message. An object
+// that is a google.protobuf.Any has its @type as well.
+func (c *protoChecker) checkMessage(node *Node, message *protoMessageType, path []string, isAny bool) {
+	if node.kind != NodeObject {
+		c.flag(node, path, "should be an object for "+message.name)
+		return
+	}
+
+	names := make(map[*protoField]string)
+	oneofs := make(map[int]string)
+	for _, m := range node.members {
+		key := stringValue(m.key)
+		if isAny && key == "@type" {
+			continue
+		}
+		memberPath := append(append([]string{}, path...), key)
+		comments := len(m.value.comments)
+
+		field := message.field(key)
+		switch {
+		case field == nil:
+			c.flag(m.value, memberPath, "unknown field of "+message.name)
+			m.key.highlight, m.value.highlight = HighlightRemoved, HighlightRemoved
+		case names[field] != "":
+			c.flag(m.value, memberPath, "sets the same field as "+names[field])
+		default:
+			names[field] = key
+			if field.oneof >= 0 && !field.isOptional && m.value.kind != NodeNull && field.oneof < len(message.oneofs) {
+				if other, ok := oneofs[field.oneof]; ok {
+					c.flag(m.value, memberPath, "is in oneof "+message.oneofs[field.oneof]+" with "+other+", which only one can be set in")
+				}
+				oneofs[field.oneof] = key
+			}
+			c.checkField(m.value, field, message, memberPath)
+		}
+
+		moveComments(m, comments)
+	}
+
+	for _, field := range message.fields {
+		if field.label == protoRequired && names[field] == "" {
+			c.flag(node, path, "is missing required field "+field.jsonName)
+		}
+	}
+}
+
+// moveComments moves the comments that were added to the value of a member,
+// after the first count, to before the member, where they are out of the way
+// of the value
+func moveComments(m Member, count int) {
+	m.key.comments = append(m.key.comments, m.value.comments[count:]...)
+	m.value.comments = m.value.comments[:count]
+}
+
+// checkField checks the value of a field, which is an array if it is
+// repeated and an object if it is a map
+func (c *protoChecker) checkField(node *Node, field *protoField, message *protoMessageType, path []string) {
+	entry := c.descriptors.messages[field.typeName]
+	isMap := field.kind == protoMessage && entry != nil && entry.isMapEntry && len(entry.fields) == 2
+
+	if node.kind == NodeNull && field.typeName != "google.protobuf.Value" {
+		node.annotation = field.typeText() + ", default"
+		return
+	}
+
+	switch {
+	case isMap:
+		key, value := entry.fields[0], entry.fields[1]
+		node.annotation = "map<" + key.typeText() + ", " + value.typeText() + ">"
+		if node.kind != NodeObject {
+			c.flag(node, path, "should be an object for a map")
+			return
+		}
+		for _, m := range node.members {
+			memberPath := append(append([]string{}, path...), stringValue(m.key))
+			comments := len(m.value.comments)
+			if problem := protoScalarProblem(m.key, key.kind, true); problem != "" {
+				c.flag(m.value, memberPath, "key "+problem)
+			}
+			c.checkValue(m.value, value, memberPath)
+			moveComments(m, comments)
+		}
+	case field.label == protoRepeated:
+		node.annotation = "repeated " + field.typeText()
+		if node.kind != NodeArray {
+			c.flag(node, path, "should be an array for a repeated field")
+			return
+		}
+		for i, element := range node.elements {
+			elementPath := append(append([]string{}, path...), strconv.Itoa(i))
+			if element.kind == NodeNull && field.typeName != "google.protobuf.Value" {
+				c.flag(element, elementPath, "null is not allowed in a repeated field")
+				continue
+			}
+			c.checkValue(element, field, elementPath)
+		}
+	default:
+		c.checkValue(node, field, path)
+		// Fields with implicit presence are left out of JSON when they are
+		// the default, so a parser cannot tell them from missing ones
+		if message.isProto3 && field.kind != protoMessage && field.oneof < 0 && !field.isOptional && c.isDefault(node, field) {
+			node.annotation += ", default"
+		}
+	}
+}
+
+// checkValue checks a single value of the field's type and annotates it with
+// the type
+func (c *protoChecker) checkValue(node *Node, field *protoField, path []string) {
+	switch field.kind {
+	case protoMessage, protoGroup:
+		node.annotation = field.typeName
+		if c.checkWellKnown(node, field.typeName, path) {
+			return
+		}
+		if message, ok := c.descriptors.messages[field.typeName]; ok {
+			c.checkMessage(node, message, path, false)
+		} else {
+			node.annotation += ", not in the descriptor set"
+		}
+	case protoEnum:
+		node.annotation = "enum " + field.typeName
+		enum, ok := c.descriptors.enums[field.typeName]
+		switch {
+		case !ok:
+			node.annotation += ", not in the descriptor set"
+		case node.kind == NodeString:
+			if _, ok := enum.values[stringValue(node)]; !ok {
+				c.flag(node, path, "is not a value of enum "+field.typeName)
+			}
+		default:
+			if problem := protoScalarProblem(node, protoInt32, false); problem != "" {
+				c.flag(node, path, "should be the name or number of a value of enum "+field.typeName)
+			}
+		}
+	default:
+		node.annotation = field.typeText()
+		if problem := protoScalarProblem(node, field.kind, false); problem != "" {
+			c.flag(node, path, problem)
+		}
+	}
+}
+
+// checkWellKnown checks a value of one of the well-known types that JSON
+// writes in their own way, returning false for other types
+func (c *protoChecker) checkWellKnown(node *Node, name string, path []string) bool {
+	if kind, ok := protoWrappers[name]; ok {
+		if problem := protoScalarProblem(node, kind, false); problem != "" && node.kind != NodeNull {
+			c.flag(node, path, problem)
+		}
+		return true
+	}
+	if !protoSpecialTypes[name] {
+		return false
+	}
+
+	problem := ""
+	switch name {
+	case "google.protobuf.Timestamp":
+		if _, err := time.Parse(time.RFC3339Nano, stringValue(node)); node.kind != NodeString || err != nil {
+			problem = "should be an RFC 3339 time, such as 1972-01-01T10:00:20.021Z"
+		}
+	case "google.protobuf.Duration":
+		if node.kind != NodeString || !protoDurationPattern.MatchString(stringValue(node)) {
+			problem = "should be a duration in seconds, such as 1.5s"
+		}
+	case "google.protobuf.FieldMask":
+		if node.kind != NodeString {
+			problem = "should be a string of comma-separated paths"
+		} else if text := stringValue(node); text != "" {
+			for _, fieldPath := range strings.Split(text, ",") {
+				if !protoPathPattern.MatchString(fieldPath) {
+					problem = "path " + fieldPath + " should be in lowerCamelCase"
+				}
+			}
+		}
+	case "google.protobuf.Struct":
+		if node.kind != NodeObject {
+			problem = "should be an object"
+		}
+	case "google.protobuf.ListValue":
+		if node.kind != NodeArray {
+			problem = "should be an array"
+		}
+	case "google.protobuf.Value":
+	case "google.protobuf.Empty":
+		if node.kind != NodeObject || len(node.members) > 0 {
+			problem = "should be {}"
+		}
+	case "google.protobuf.Any":
+		c.checkAny(node, path)
+	}
+
+	if problem != "" {
+		c.flag(node, path, problem)
+	}
+	return true
+}
+
+// checkAny checks a google.protobuf.Any, whose @type names the message that
+// its other members are. A well-known type with a JSON form of its own is in
+// a value member instead.
+func (c *protoChecker) checkAny(node *Node, path []string) {
+	typeURL := member(node, "@type")
+	if node.kind != NodeObject || typeURL == nil || typeURL.kind != NodeString {
+		c.flag(node, path, "should be an object with an @type")
+		return
+	}
+	name := stringValue(typeURL)
+	name = name[strings.LastIndex(name, "/")+1:]
+	typeURL.annotation = name
+
+	if _, ok := protoWrappers[name]; ok || protoSpecialTypes[name] {
+		value := member(node, "value")
+		if value == nil || len(node.members) != 2 {
+			c.flag(node, path, "should have only @type and value for "+name)
+			return
+		}
+		c.checkWellKnown(value, name, append(append([]string{}, path...), "value"))
+		return
+	}
+
+	message, ok := c.descriptors.messages[name]
+	if !ok {
+		typeURL.annotation += ", not in the descriptor set"
+		return
+	}
+	c.checkMessage(node, message, path, true)
+}
+
+// protoScalarProblem returns what is wrong with a value for a scalar type, or
+// "" if nothing is. Map keys are always strings, so with isKey the value is
+// read from the string.
+func protoScalarProblem(node *Node, kind int, isKey bool) string {
+	text := rawText(node)
+	if node.kind == NodeString {
+		text = stringValue(node)
+	}
+
+	switch kind {
+	case protoString:
+		if node.kind != NodeString {
+			return "should be a string"
+		}
+	case protoBool:
+		if isKey && (text == "true" || text == "false") {
+			return ""
+		}
+		if node.kind != NodeBool || isKey {
+			return "should be true or false"
+		}
+	case protoBytes:
+		if node.kind != NodeString || !isProtoBase64(text) {
+			return "should be base64"
+		}
+	case protoDouble, protoFloat:
+		if node.kind == NodeString && (text == "NaN" || text == "Infinity" || text == "-Infinity") {
+			return ""
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if (node.kind != NodeNumber && node.kind != NodeString) || err != nil {
+			return "should be a number"
+		}
+		if kind == protoFloat && math.Abs(value) > math.MaxFloat32 {
+			return "is out of range for float"
+		}
+	default:
+		bits, isSigned := 32, true
+		switch kind {
+		case protoInt64, protoSint64, protoSfixed64:
+			bits = 64
+		case protoUint64, protoFixed64:
+			bits, isSigned = 64, false
+		case protoUint32, protoFixed32:
+			isSigned = false
+		}
+
+		number, ok := new(big.Float).SetPrec(256).SetString(text)
+		if (node.kind != NodeNumber && node.kind != NodeString) || !ok || !number.IsInt() {
+			return "should be an integer"
+		}
+		integer, _ := number.Int(nil)
+		low, high := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		if isSigned {
+			high.Rsh(high, 1)
+			low.Neg(high)
+		}
+		if integer.Cmp(low) < 0 || integer.Cmp(high) >= 0 {
+			return "is out of range for " + protoTypeNames[kind]
+		}
+		if bits == 64 && node.kind == NodeNumber && new(big.Int).Abs(integer).Cmp(big.NewInt(1<<53)) > 0 {
+			return "loses precision in JavaScript as a number; 64-bit integers should be strings"
+		}
+	}
+	return ""
+}
+
+// isProtoBase64 returns true if the text is base64, in either alphabet and
+// with or without padding, which the JSON mapping accepts for bytes
+func isProtoBase64(text string) bool {
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if _, err := encoding.DecodeString(text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isDefault returns true if the value is the default of a scalar or enum
+// field: zero, false, empty, or the value of the enum numbered zero
+func (c *protoChecker) isDefault(node *Node, field *protoField) bool {
+	switch node.kind {
+	case NodeNumber:
+		return numberValue(node) == 0
+	case NodeBool:
+		return rawText(node) == "false"
+	case NodeString:
+		if enum, ok := c.descriptors.enums[field.typeName]; ok && field.kind == protoEnum {
+			number, ok := enum.values[stringValue(node)]
+			return ok && number == 0
+		}
+		if field.kind == protoString || field.kind == protoBytes {
+			return stringValue(node) == ""
+		}
+		value, err := strconv.ParseFloat(stringValue(node), 64)
+		return err == nil && value == 0
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package main
+
+// pruneValues removes the members of every object that are null, with
+// pruneNulls, and the members and elements that are empty objects or arrays,
+// with pruneEmpty. It works from the inside out, so a container that is only
+// left empty by pruning is pruned as well. It returns how many values it
+// removed. The document itself is never removed.
+func pruneValues(node *Node, pruneNulls, pruneEmpty bool) int {
+	pruned := 0
+
+	// isPruned returns true if the value is removed, once it has been pruned
+	isPruned := func(value *Node, isMember bool) bool {
+		pruned += pruneValues(value, pruneNulls, pruneEmpty)
+		switch {
+		case pruneNulls && isMember && value.kind == NodeNull:
+			return true
+		case pruneEmpty && value.kind == NodeObject && len(value.members) == 0:
+			return true
+		case pruneEmpty && value.kind == NodeArray && len(value.elements) == 0:
+			return true
+		}
+		return false
+	}
+
+	members := node.members[:0]
+	for _, m := range node.members {
+		if isPruned(m.value, true) {
+			pruned++
+		} else {
+			members = append(members, m)
+		}
+	}
+	node.members = members
+
+	elements := node.elements[:0]
+	for _, element := range node.elements {
+		if isPruned(element, false) {
+			pruned++
+		} else {
+			elements = append(elements, element)
+		}
+	}
+	node.elements = elements
+
+	return pruned
+}
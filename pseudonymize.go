@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"strings"
+	"unicode"
+)
+
+// pseudonymizeValues replaces the values of the members with the given keys,
+// and every string and number inside them, with fake values of the same shape,
+// and returns how many it replaced. Letters become other letters of the same
+// case and digits other digits, while punctuation, such as the '@' and '.' of
+// an email address, stays, so the values still look real. Each fake value is
+// made from a hash of the real one, so the same value always gets the same
+// fake, in every document and every run, and records can still be matched up.
+func pseudonymizeValues(node *Node, keys map[string]bool) int {
+	replaced := 0
+	for _, m := range node.members {
+		if keys[stringValue(m.key)] {
+			replaced += pseudonymizeAll(m.value)
+		} else {
+			replaced += pseudonymizeValues(m.value, keys)
+		}
+	}
+	for _, element := range node.elements {
+		replaced += pseudonymizeValues(element, keys)
+	}
+	return replaced
+}
+
+// pseudonymizeAll replaces every string and number in the value with a fake
+// and returns how many it replaced
+func pseudonymizeAll(node *Node) int {
+	replaced := 0
+	for _, m := range node.members {
+		replaced += pseudonymizeAll(m.value)
+	}
+	for _, element := range node.elements {
+		replaced += pseudonymizeAll(element)
+	}
+
+	comments := node.comments
+	switch node.kind {
+	case NodeString:
+		*node = *newStringNode(pseudonym(stringValue(node), false))
+	case NodeNumber:
+		// Only the digits before an exponent change, so the fake is about as
+		// large as the real number
+		text := rawText(node)
+		exponent := strings.IndexAny(text, "eE")
+		if exponent < 0 {
+			exponent = len(text)
+		}
+		*node = *newNumberNode(pseudonym(text[:exponent], true) + text[exponent:])
+	default:
+		return replaced
+	}
+	node.comments = comments
+	return replaced + 1
+}
+
+// pseudonym returns a fake of the text with the same shape, made from a hash
+// of it. A number keeps a leading digit that is not 0, so that it is still a
+// number.
+func pseudonym(text string, isNumber bool) string {
+	hash := sha256.Sum256([]byte(text))
+	random := hash[:]
+	nextByte := func() int {
+		if len(random) == 0 {
+			hash = sha256.Sum256(hash[:])
+			random = hash[:]
+		}
+		b := random[0]
+		random = random[1:]
+		return int(b)
+	}
+
+	var fake strings.Builder
+	isFirstDigit := true
+	for _, character := range text {
+		switch {
+		case unicode.IsDigit(character):
+			if isNumber && isFirstDigit && character != '0' {
+				fake.WriteRune(rune('1' + nextByte()%9))
+			} else {
+				fake.WriteRune(rune('0' + nextByte()%10))
+			}
+			isFirstDigit = false
+		case unicode.IsUpper(character):
+			fake.WriteRune(rune('A' + nextByte()%26))
+		case unicode.IsLetter(character):
+			fake.WriteRune(rune('a' + nextByte()%26))
+		default:
+			fake.WriteRune(character)
+		}
+	}
+	return fake.String()
+}
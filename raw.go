@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+)
+
+// rawScript shows and hides the raw input of --embed-raw and downloads it
+// under the name of the input file
+const rawScript = `function toggleRaw() {
+	var raw = document.getElementById("json-pretty-raw");
+	raw.hidden = !raw.hidden;
+}
+function downloadRaw() {
+	var raw = document.getElementById("json-pretty-raw");
+	var link = document.createElement("a");
+	link.href = URL.createObjectURL(new Blob([raw.value], {type: "application/json"}));
+	link.download = raw.getAttribute("data-file-name");
+	link.click();
+	setTimeout(function () { URL.revokeObjectURL(link.href); }, 0);
+}`
+
+// printRawSource prints the input of --embed-raw at the top of the page, in a
+// text area that is hidden until "View raw" is clicked, with a button that
+// downloads it, so the page is all that needs to be shared. The buttons run
+// rawScript, which printHeader adds.
+func printRawSource(w io.Writer, options Options) {
+	fileName := filepath.Base(options.fileName)
+	if options.fileName == "" || options.fileName == "-" || options.fileName == "clipboard" {
+		fileName = "document.json"
+	}
+
+	fmt.Fprintln(w, "\t\t"+"<div style=\"font-family:sans-serif; margin-bottom:8px\">")
+	fmt.Fprintln(w, "\t\t\t"+"<button type=\"button\" onclick=\"toggleRaw()\">View raw</button>")
+	fmt.Fprintln(w, "\t\t\t"+"<button type=\"button\" onclick=\"downloadRaw()\">Download</button>")
+	fmt.Fprintln(w, "\t\t"+"</div>")
+
+	// A text area drops a newline right after its tag, so one is added to
+	// keep any that the input starts with
+	fmt.Fprintln(w, "\t\t"+"<textarea id=\"json-pretty-raw\" data-file-name=\""+html.EscapeString(fileName)+"\" "+
+		"readonly hidden spellcheck=\"false\" style=\"box-sizing:border-box; width:100%; height:20em; font-family:monospace\">\n"+
+		html.EscapeString(string(options.rawInput))+"</textarea>")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// renameRule is a key that --rename-keys renames, either wherever it is or
+// only at the paths that a pattern matches
+type renameRule struct {
+	pattern []string // The JSON Pointer the member must match, or nil for any
+	key     string   // The key to rename, when there is no pattern
+	newKey  *Node
+}
+
+// readRenameRules reads the mapping of --rename-keys, an object from each key
+// to its new name. A key that starts with '/' is a JSON Pointer to the members
+// it renames, whose segments can have the wildcards of --ignore-path, and any
+// other key is renamed wherever it is.
+func readRenameRules(mapping *Node) ([]renameRule, error) {
+	if mapping.kind != NodeObject {
+		return nil, fmt.Errorf("--rename-keys: the mapping must be an object, not %s", valueSummary(mapping))
+	}
+
+	rules := make([]renameRule, 0, len(mapping.members))
+	for _, m := range mapping.members {
+		if m.value.kind != NodeString {
+			return nil, fmt.Errorf("--rename-keys: the new name of %q is not a string", stringValue(m.key))
+		}
+
+		rule := renameRule{key: stringValue(m.key), newKey: newStringNode(stringValue(m.value))}
+		if strings.HasPrefix(rule.key, "/") {
+			pattern, err := parsePointer(rule.key)
+			if err != nil {
+				return nil, fmt.Errorf("--rename-keys: %v", err)
+			}
+			rule.pattern = pattern
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// renameKeys renames the keys of every object in the tree that the rules
+// match and returns how many it renamed. A rule with a path wins over one for
+// the key anywhere, and otherwise the first rule that matches wins. Paths are
+// matched with the keys as they were before any were renamed.
+func renameKeys(node *Node, rules []renameRule, path []string) int {
+	renamed := 0
+
+	for _, m := range node.members {
+		key := stringValue(m.key)
+		memberPath := append(append([]string{}, path...), key)
+		renamed += renameKeys(m.value, rules, memberPath)
+
+		if newKey := matchRenameRules(rules, key, memberPath); newKey != nil {
+			comments := m.key.comments
+			*m.key = *copyNode(newKey)
+			m.key.comments = comments
+			renamed++
+		}
+	}
+	for i, element := range node.elements {
+		renamed += renameKeys(element, rules, append(append([]string{}, path...), strconv.Itoa(i)))
+	}
+
+	return renamed
+}
+
+// matchRenameRules returns the new key for the member at the path, or nil if
+// no rule renames it
+func matchRenameRules(rules []renameRule, key string, path []string) *Node {
+	for _, rule := range rules {
+		if rule.pattern != nil && matchGlobSegments(rule.pattern, path) {
+			return rule.newKey
+		}
+	}
+	for _, rule := range rules {
+		if rule.pattern == nil && rule.key == key {
+			return rule.newKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// repairJSON applies best-effort fixes to almost-JSON so that it can be
+// tokenized as JSON. Single-quoted strings become double-quoted, unquoted keys
+// and bare words are quoted, Python-style True/False/None become JSON literals,
+// and garbage after the last top-level value is dropped. Comments are kept if
+// they are allowed and removed otherwise. It returns the repaired file and a
+// description of every repair that was made.
+func repairJSON(jsonFile []byte, options Options) ([]byte, []string) {
+	var repaired bytes.Buffer
+	repairs := make([]string, 0)
+
+	// addRepair records a repair along with where it was made in the input
+	addRepair := func(offset int, description string) {
+		repairs = append(repairs, fmt.Sprintf("offset %d: %s", offset, description))
+	}
+
+	depth := 0                    // How many containers are currently open
+	isAfterTopLevelValue := false // Has a top-level value been completed
+
+	for i := 0; i < len(jsonFile); {
+		character := jsonFile[i]
+
+		// Once a top-level value is complete, whatever follows must be the
+		// start of another value or the rest of the file is thrown away
+		if depth == 0 && isAfterTopLevelValue && !isWhiteSpace(character) &&
+			!isRepairableValueStart(jsonFile[i:]) && !isCommentStart(jsonFile[i:]) {
+			addRepair(i, fmt.Sprintf("removed %d byte(s) of trailing garbage", len(jsonFile)-i))
+			break
+		}
+
+		switch {
+		case character == '"' || character == '\'':
+			end, content := readQuotedString(jsonFile, i)
+			repaired.Write(content)
+			if character == '\'' {
+				addRepair(i, "converted single-quoted string to double quotes")
+			}
+			i = end
+			isAfterTopLevelValue = depth == 0
+		case isCommentStart(jsonFile[i:]):
+			end := commentEnd(jsonFile, i)
+			if options.allowComments {
+				repaired.Write(jsonFile[i:end])
+			} else {
+				addRepair(i, "removed comment")
+			}
+			i = end
+		case isIdentifierCharacter(character) && !isDigit(character):
+			end := i
+			for end < len(jsonFile) && isIdentifierCharacter(jsonFile[end]) {
+				end++
+			}
+			word := string(jsonFile[i:end])
+
+			switch word {
+			case "true", "false", "null":
+				repaired.WriteString(word)
+			case "True", "False", "None":
+				literal := map[string]string{"True": "true", "False": "false", "None": "null"}[word]
+				repaired.WriteString(literal)
+				addRepair(i, "replaced "+word+" with "+literal)
+			default:
+				// Unquoted keys and bare words both become strings
+				repaired.WriteString("\"" + word + "\"")
+				if isFollowedByColon(jsonFile[end:]) {
+					addRepair(i, "quoted key "+word)
+				} else {
+					addRepair(i, "quoted bare word "+word)
+				}
+			}
+
+			i = end
+			isAfterTopLevelValue = depth == 0
+		case character == '{' || character == '[':
+			depth++
+			repaired.WriteByte(character)
+			i++
+		case character == '}' || character == ']':
+			if depth > 0 {
+				depth--
+			}
+			repaired.WriteByte(character)
+			i++
+			isAfterTopLevelValue = depth == 0
+		case character == '-' || isDigit(character):
+			// Numbers are copied whole so that an exponent is not mistaken
+			// for a bare word
+			end := i + 1
+			for end < len(jsonFile) && isNumberCharacter(jsonFile[end]) {
+				end++
+			}
+			repaired.Write(jsonFile[i:end])
+			i = end
+			isAfterTopLevelValue = depth == 0
+		default:
+			repaired.WriteByte(character)
+			i++
+		}
+	}
+
+	return repaired.Bytes(), repairs
+}
+
+// readQuotedString reads the single- or double-quoted string that starts at
+// start and returns the offset just past it along with the string written with
+// double quotes. An unfinished string is closed at the end of the file.
+func readQuotedString(jsonFile []byte, start int) (int, []byte) {
+	quote := jsonFile[start]
+	content := []byte{'"'}
+
+	i := start + 1
+	for i < len(jsonFile) && jsonFile[i] != quote {
+		character := jsonFile[i]
+		switch {
+		case character == '\\' && i+1 < len(jsonFile):
+			// An escaped single quote is not a valid JSON escape
+			if jsonFile[i+1] == '\'' {
+				content = append(content, '\'')
+			} else {
+				content = append(content, character, jsonFile[i+1])
+			}
+			i += 2
+			continue
+		case character == '"':
+			// Only reachable inside a single-quoted string
+			content = append(content, '\\', '"')
+		default:
+			content = append(content, character)
+		}
+		i++
+	}
+
+	content = append(content, '"')
+	return i + 1, content
+}
+
+// isRepairableValueStart returns true if the text starts with something that
+// repairJSON can turn into a JSON value
+func isRepairableValueStart(text []byte) bool {
+	switch character := text[0]; {
+	case character == '{', character == '[', character == '"', character == '\'':
+		return true
+	case character == '-', isDigit(character):
+		return true
+	}
+
+	for _, literal := range []string{"true", "false", "null", "True", "False", "None"} {
+		if bytes.HasPrefix(text, []byte(literal)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommentStart returns true if the text starts with '//' or '/*'
+func isCommentStart(text []byte) bool {
+	return bytes.HasPrefix(text, []byte("//")) || bytes.HasPrefix(text, []byte("/*"))
+}
+
+// isFollowedByColon returns true if the next character that is not white space
+// is ':'
+func isFollowedByColon(text []byte) bool {
+	trimmed := bytes.TrimLeft(text, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == ':'
+}
+
+// isIdentifierCharacter returns true for characters that can be part of an
+// unquoted key such as 'user_name' or '$ref'
+func isIdentifierCharacter(character byte) bool {
+	return character == '_' || character == '$' || isDigit(character) ||
+		(character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z')
+}
+
+// isNumberCharacter returns true for every character that can appear in a
+// JSON number
+func isNumberCharacter(character byte) bool {
+	switch character {
+	case '-', '+', 'e', 'E', '.':
+		return true
+	}
+	return isDigit(character)
+}
+
+// isDigit returns true for '0' through '9'
+func isDigit(character byte) bool {
+	return character >= '0' && character <= '9'
+}
+
+// isWhiteSpace returns true for the white space characters allowed by JSON
+func isWhiteSpace(character byte) bool {
+	switch character {
+	case ' ', '\t', '\r', '\n':
+		return true
+	}
+	return false
+}
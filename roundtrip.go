@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"strings"
+)
+
+// Reformat returns the input as plain, indented JSON, laid out the same way as
+// the HTML output. It guarantees that the output parses back to the same
+// values as the input: numbers are equal by value, strings by their decoded
+// text, and objects by their members. Each top-level value of the input is
+// kept as its own value in the output. An error is returned if the input is
+// not valid JSON, and the output is checked before it is returned, so a broken
+// guarantee is an error too rather than different data.
+func Reformat(input []byte) ([]byte, error) {
+	return formatJSON(context.Background(), input, Options{})
+}
+
+// formatJSON returns the input as plain, indented JSON with every option that
+// changes the values applied, as Reformat does. The input has to be valid, once
+// it is repaired if the options ask for it, so that nothing is dropped.
+func formatJSON(ctx context.Context, jsonFile []byte, options Options) ([]byte, error) {
+	if err := validateInput(jsonFile, options); err != nil {
+		return nil, err
+	}
+
+	documents, err := formatDocuments(ctx, jsonFile, options, ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyDocuments(documents, options); err != nil {
+		return nil, err
+	}
+
+	var output strings.Builder
+	for _, document := range documents {
+		output.WriteString(formatText(valueTokens(document)))
+	}
+	return []byte(output.String()), nil
+}
+
+// verifyDocuments checks that printing the documents does not change them.
+// The text layout of each document must parse back to the same values, and
+// the HTML escaping of each token must decode back to the token. It returns an
+// error describing the first document that does not survive.
+func verifyDocuments(documents [][]Token, options Options) error {
+	for i, document := range documents {
+		document = valueTokens(document)
+		want, err := parseTokens(document)
+		if err != nil {
+			return fmt.Errorf("document %d is not valid before printing: %v", i+1, err)
+		}
+
+		text := []byte(formatText(document))
+		tokenArray := getTokens(text, options)
+		if offset := skippedByte(text, tokenArray); offset >= 0 {
+			return fmt.Errorf("document %d prints an unexpected %q at offset %d", i+1, text[offset:offset+1], offset)
+		}
+		got, err := parseTokens(tokenArray)
+		if err != nil {
+			return fmt.Errorf("document %d is not valid after printing: %v", i+1, err)
+		}
+		if !nodesEqual(want, got) {
+			return fmt.Errorf("document %d has different values after printing", i+1)
+		}
+
+		for _, token := range document {
+			if html.UnescapeString(escapeString(token)) != token.content {
+				return fmt.Errorf("document %d has a token that HTML changes: %q", i+1, token.content)
+			}
+		}
+	}
+	return nil
+}
+
+// valueTokens returns the tokens without annotations, such as the badges of
+// --annotate-types, which describe the values but are not part of them, and
+// without the white space that --preserve-layout keeps
+func valueTokens(tokenArray []Token) []Token {
+	values := make([]Token, 0, len(tokenArray))
+	for _, token := range tokenArray {
+		if token.kind != Annotation && token.kind != WhiteSpace {
+			values = append(values, token)
+		}
+	}
+	return values
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestReformatPlainObject(t *testing.T) {
+	output, err := Reformat([]byte(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(output), "{\n\t\"a\" : 1\n}\n"; got != want {
+		t.Errorf("Reformat returned %q, want %q", got, want)
+	}
+}
+
+func TestReformatInvalidTokens(t *testing.T) {
+	for _, input := range []string{`[01.2.3e]`, `[--5]`, `["\q"]`, `["\u12zz"]`, "[\"a\tb\"]"} {
+		if output, err := Reformat([]byte(input)); err == nil {
+			t.Errorf("Reformat(%q) returned %q, want an error", input, output)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// sampleArrays shortens every array with more than count elements down to
+// count of them, chosen by the mode: the "first" or "last" elements, or
+// "random" ones kept in their original order. Each run of elements that was
+// left out is replaced by a comment saying how many elements are missing, so
+// the shape of huge documents can be seen without rendering all of them.
+func sampleArrays(node *Node, count int, mode string, random *rand.Rand) {
+	if node.kind == NodeArray && len(node.elements) > count {
+		total := len(node.elements)
+
+		var kept []int
+		switch mode {
+		case "last":
+			for i := total - count; i < total; i++ {
+				kept = append(kept, i)
+			}
+		case "random":
+			kept = random.Perm(total)[:count]
+			sort.Ints(kept)
+		default:
+			for i := 0; i < count; i++ {
+				kept = append(kept, i)
+			}
+		}
+
+		elements := make([]*Node, 0, count)
+		next := 0 // The index after the last element that was kept
+		for _, index := range kept {
+			element := node.elements[index]
+			if index > next {
+				element.comments = append(element.comments, omittedComment(index-next))
+			}
+			elements = append(elements, element)
+			next = index + 1
+		}
+		if next < total {
+			node.closingComments = append(node.closingComments, omittedComment(total-next))
+		}
+
+		node.elements = elements
+	}
+
+	for _, m := range node.members {
+		sampleArrays(m.value, count, mode, random)
+	}
+	for _, element := range node.elements {
+		sampleArrays(element, count, mode, random)
+	}
+}
+
+// omittedComment returns the comment that stands in for elements that were
+// left out of a sampled array
+func omittedComment(omitted int) Token {
+	comment := makeToken("/* … "+strconv.Itoa(omitted)+" element(s) omitted by --sample */", Comment)
+	comment.highlight = HighlightChanged
+	return comment
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns for the string formats that schema inference can recognize
+var schemaFormats = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"date-time", regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})$`)},
+	{"date", regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)},
+	{"email", regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)},
+	{"uri", regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://\S+$`)},
+	{"uuid", regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)},
+	{"ipv4", regexp.MustCompile(`^(\d{1,3}\.){3}\d{1,3}$`)},
+}
+
+// Strings only become an enum when there are enough samples to tell that the
+// same few values keep coming back
+const (
+	schemaEnumMinimumSamples = 4
+	schemaEnumMaximumValues  = 5
+)
+
+// inferSchemaDocument infers a JSON Schema that the documents all fit. When
+// there is more than one document, such as with NDJSON input, each of them is a
+// sample; with --samples, the elements of a single top-level array are the
+// samples instead.
+func inferSchemaDocument(roots []*Node, options Options) *Node {
+	samples := roots
+	if options.samples && len(roots) == 1 && roots[0].kind == NodeArray {
+		samples = roots[0].elements
+	}
+
+	schema := inferSchema(samples)
+	schema.members = append([]Member{{newStringNode("$schema"),
+		newStringNode("https://json-schema.org/draft/2020-12/schema")}}, schema.members...)
+	return schema
+}
+
+// inferSchema returns the schema of a value, given every sample of that value.
+// A value seen with several types gets a list of types along with the keywords
+// that belong to each of them.
+func inferSchema(samples []*Node) *Node {
+	schema := newObjectNode()
+
+	// addKeyword adds a member to the schema
+	addKeyword := func(keyword string, value *Node) {
+		schema.members = append(schema.members, Member{newStringNode(keyword), value})
+	}
+
+	// Group the samples by their JSON Schema type, in the order first seen
+	typeNames := make([]string, 0)
+	samplesByType := make(map[string][]*Node)
+	for _, sample := range samples {
+		typeName := schemaTypeName(sample)
+		if _, ok := samplesByType[typeName]; !ok {
+			typeNames = append(typeNames, typeName)
+		}
+		samplesByType[typeName] = append(samplesByType[typeName], sample)
+	}
+
+	// Integers are numbers too, so the two types are seen as one
+	if _, ok := samplesByType["integer"]; ok {
+		if _, ok := samplesByType["number"]; ok {
+			samplesByType["number"] = append(samplesByType["number"], samplesByType["integer"]...)
+			delete(samplesByType, "integer")
+			for i, typeName := range typeNames {
+				if typeName == "integer" {
+					typeNames = append(typeNames[:i], typeNames[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	switch len(typeNames) {
+	case 0:
+		return schema
+	case 1:
+		addKeyword("type", newStringNode(typeNames[0]))
+	default:
+		types := newArrayNode()
+		for _, typeName := range typeNames {
+			types.elements = append(types.elements, newStringNode(typeName))
+		}
+		addKeyword("type", types)
+	}
+
+	if objects := samplesByType["object"]; len(objects) > 0 {
+		properties := newObjectNode()
+		required := newArrayNode()
+
+		// Keys are listed in the order they were first seen
+		keys := make([]string, 0)
+		valuesByKey := make(map[string][]*Node)
+		for _, object := range objects {
+			for _, m := range object.members {
+				key := stringValue(m.key)
+				if _, ok := valuesByKey[key]; !ok {
+					keys = append(keys, key)
+				}
+				valuesByKey[key] = append(valuesByKey[key], m.value)
+			}
+		}
+
+		for _, key := range keys {
+			properties.members = append(properties.members, Member{newStringNode(key), inferSchema(valuesByKey[key])})
+
+			isInEveryObject := true
+			for _, object := range objects {
+				if member(object, key) == nil {
+					isInEveryObject = false
+				}
+			}
+			if isInEveryObject {
+				required.elements = append(required.elements, newStringNode(key))
+			}
+		}
+
+		addKeyword("properties", properties)
+		if len(required.elements) > 0 {
+			addKeyword("required", required)
+		}
+	}
+
+	if arrays := samplesByType["array"]; len(arrays) > 0 {
+		elements := make([]*Node, 0)
+		for _, array := range arrays {
+			elements = append(elements, array.elements...)
+		}
+		if len(elements) > 0 {
+			addKeyword("items", inferSchema(elements))
+		}
+	}
+
+	if texts := samplesByType["string"]; len(texts) > 0 {
+		if format := inferStringFormat(texts); format != "" {
+			addKeyword("format", newStringNode(format))
+		} else if enum := inferEnum(texts); enum != nil {
+			addKeyword("enum", enum)
+		}
+	}
+
+	return schema
+}
+
+// schemaTypeName returns the JSON Schema type of a value. Numbers without a
+// fraction or exponent are integers.
+func schemaTypeName(node *Node) string {
+	switch node.kind {
+	case NodeObject:
+		return "object"
+	case NodeArray:
+		return "array"
+	case NodeString:
+		return "string"
+	case NodeNumber:
+		if strings.ContainsAny(rawText(node), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case NodeBool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// inferStringFormat returns the format that every one of the strings has, or
+// the empty string if they do not share one
+func inferStringFormat(texts []*Node) string {
+	for _, format := range schemaFormats {
+		isMatch := true
+		for _, text := range texts {
+			if !format.pattern.MatchString(stringValue(text)) {
+				isMatch = false
+				break
+			}
+		}
+		if isMatch {
+			return format.name
+		}
+	}
+	return ""
+}
+
+// inferEnum returns the distinct strings as an enum candidate if there are
+// many samples but only a few different values, or nil otherwise
+func inferEnum(texts []*Node) *Node {
+	if len(texts) < schemaEnumMinimumSamples {
+		return nil
+	}
+
+	enum := newArrayNode()
+	isSeen := make(map[string]bool)
+	for _, text := range texts {
+		value := stringValue(text)
+		if !isSeen[value] {
+			isSeen[value] = true
+			enum.elements = append(enum.elements, newStringNode(value))
+		}
+	}
+
+	if len(enum.elements) > schemaEnumMaximumValues || len(enum.elements) == len(texts) {
+		return nil
+	}
+	return enum
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// schemaMaxRefs is how many $refs in a row are followed before giving up,
+// which stops a schema that refers to itself from looping forever
+const schemaMaxRefs = 32
+
+// schemaDecorations documents the values in the tokens with the parts of the
+// JSON Schema of --schema that describe them, which turns an example document
+// into documentation of an API response. The title and description of each
+// value are shown after it, or after the opening bracket of a container, and
+// its type and constraints are in a tooltip over the value.
+func schemaDecorations(tokenArray []Token, schema *Node, colors theme) []Decoration {
+	decorations := make([]Decoration, len(tokenArray))
+
+	for _, span := range findValueSpans(tokenArray) {
+		part, isRequired := schemaAt(schema, span.path)
+		if part == nil {
+			continue
+		}
+
+		details := schemaDetails(part, isRequired)
+		if len(details) > 0 {
+			decorations[span.start].before += `<span style="cursor:help" title="` +
+				strings.Replace(html.EscapeString(strings.Join(details, "\n")), "\n", "&#10;", -1) + `">`
+			decorations[span.end].after = `</span>` + decorations[span.end].after
+		}
+
+		if doc := schemaDoc(part); doc != "" {
+			docEnd := span.end
+			if kind := tokenArray[span.start].kind; kind == ObjectOpen || kind == ArrayOpen {
+				docEnd = span.start
+			}
+			decorations[docEnd].after += ` <span style="color:` + colors.annotation + `; font-size:80%; font-style:italic">` +
+				html.EscapeString(doc) + `</span>`
+		}
+	}
+
+	return decorations
+}
+
+// schemaAt returns the part of the schema that describes the value at the
+// path, and whether the object the value is in requires it, or nil if the
+// schema does not describe the value
+func schemaAt(schema *Node, path []string) (*Node, bool) {
+	part, isRequired := resolveRef(schema, schema), false
+	for _, segment := range path {
+		if part == nil {
+			return nil, false
+		}
+		part, isRequired = childSchema(schema, part, segment)
+	}
+	return part, isRequired
+}
+
+// childSchema returns the part of the schema that describes the member or
+// element of a value that the segment of a path names, looking in the
+// subschemas of allOf, anyOf, and oneOf if the schema itself does not say
+func childSchema(root, schema *Node, segment string) (*Node, bool) {
+	candidates := []*Node{schema}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if list := member(schema, keyword); list != nil && list.kind == NodeArray {
+			candidates = append(candidates, list.elements...)
+		}
+	}
+
+	for _, candidate := range candidates {
+		candidate = resolveRef(root, candidate)
+		if candidate == nil || candidate.kind != NodeObject {
+			continue
+		}
+
+		if properties := member(candidate, "properties"); properties != nil && properties.kind == NodeObject {
+			if property := member(properties, segment); property != nil {
+				return resolveRef(root, property), isRequiredKey(candidate, segment)
+			}
+		}
+		if patterns := member(candidate, "patternProperties"); patterns != nil && patterns.kind == NodeObject {
+			for _, m := range patterns.members {
+				if pattern, err := regexp.Compile(stringValue(m.key)); err == nil && pattern.MatchString(segment) {
+					return resolveRef(root, m.value), false
+				}
+			}
+		}
+		if additional := member(candidate, "additionalProperties"); additional != nil && additional.kind == NodeObject {
+			return resolveRef(root, additional), false
+		}
+
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			continue
+		}
+		if prefix := member(candidate, "prefixItems"); prefix != nil && prefix.kind == NodeArray && index < len(prefix.elements) {
+			return resolveRef(root, prefix.elements[index]), false
+		}
+		if items := member(candidate, "items"); items != nil {
+			if items.kind == NodeArray && index < len(items.elements) {
+				return resolveRef(root, items.elements[index]), false
+			} else if items.kind == NodeObject {
+				return resolveRef(root, items), false
+			}
+		}
+	}
+	return nil, false
+}
+
+// resolveRef follows the $ref of a schema, if it has one, to the part of the
+// whole schema that it points to. Only references within the schema, which
+// start with #, can be followed.
+func resolveRef(root, schema *Node) *Node {
+	for i := 0; i < schemaMaxRefs && schema != nil; i++ {
+		ref := member(schema, "$ref")
+		if ref == nil || ref.kind != NodeString || !strings.HasPrefix(stringValue(ref), "#") {
+			return schema
+		}
+		path, err := parsePointer(strings.TrimPrefix(stringValue(ref), "#"))
+		if err != nil {
+			return nil
+		}
+		schema = lookupPath(root, path)
+	}
+	return schema
+}
+
+// isRequiredKey returns true if the schema lists the key as required
+func isRequiredKey(schema *Node, key string) bool {
+	if required := member(schema, "required"); required != nil && required.kind == NodeArray {
+		for _, element := range required.elements {
+			if element.kind == NodeString && stringValue(element) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaDoc returns the title and description of a schema, for people to read
+func schemaDoc(schema *Node) string {
+	parts := make([]string, 0, 2)
+	for _, keyword := range []string{"title", "description"} {
+		if value := member(schema, keyword); value != nil && value.kind == NodeString {
+			parts = append(parts, stringValue(value))
+		}
+	}
+	return strings.Join(parts, ": ")
+}
+
+// schemaDetails returns the type and constraints of a schema, one to a line,
+// such as "type: string" and "format: email"
+func schemaDetails(schema *Node, isRequired bool) []string {
+	details := make([]string, 0)
+	if value := member(schema, "type"); value != nil {
+		if value.kind == NodeArray {
+			types := make([]string, len(value.elements))
+			for i, element := range value.elements {
+				types[i] = logText(element)
+			}
+			details = append(details, "type: "+strings.Join(types, " or "))
+		} else {
+			details = append(details, "type: "+logText(value))
+		}
+	}
+	if isRequired {
+		details = append(details, "required")
+	}
+
+	for _, keyword := range []string{"format", "const", "default", "minimum", "exclusiveMinimum", "maximum", "exclusiveMaximum",
+		"multipleOf", "minLength", "maxLength", "pattern", "minItems", "maxItems", "uniqueItems", "minProperties", "maxProperties"} {
+		if value := member(schema, keyword); value != nil && (keyword == "format" || keyword == "pattern") {
+			details = append(details, keyword+": "+logText(value))
+		} else if value != nil {
+			details = append(details, keyword+": "+valueText(value))
+		}
+	}
+	if value := member(schema, "enum"); value != nil && value.kind == NodeArray {
+		values := make([]string, len(value.elements))
+		for i, element := range value.elements {
+			values[i] = valueText(element)
+		}
+		details = append(details, "one of: "+strings.Join(values, ", "))
+	}
+	if value := member(schema, "deprecated"); value != nil && value.kind == NodeBool && rawText(value) == "true" {
+		details = append(details, "deprecated")
+	}
+	return details
+}
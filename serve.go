@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serveTypes lists the media types that the server can answer with, in the
+// order they are preferred when the client accepts several equally
+var serveTypes = []string{"text/html", "application/json", "text/plain"}
+
+// runServe formats JSON over HTTP at the address given by --serve instead of
+// printing it. A POST formats the JSON in the request body, and a GET formats
+// the file named in the arguments, if there is one, as it is at the time of
+// the request, and with --watch its page reloads when the file changes. The
+// Accept header picks the response: an HTML page, or the formatted JSON as
+// application/json or text/plain. A ?theme= query parameter chooses the colors
+// of the page.
+//
+// So that the server can be exposed on a network, request bodies are capped by
+// --max-body-size, each client address gets --rate-limit requests a minute,
+// and every request must be read and answered within --request-timeout.
+func runServe(options Options, arguments []string) {
+	fileName := ""
+	if len(arguments) > 0 {
+		fileName = arguments[0]
+	}
+
+	limiter := newRateLimiter(options.rateLimit)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait := limiter.take(clientAddress(r)); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, options.maxBodySize)
+		serveFormat(w, r, options, fileName)
+	})
+
+	server := &http.Server{
+		Addr:              options.serve,
+		Handler:           handler,
+		ReadHeaderTimeout: options.requestTimeout,
+		ReadTimeout:       options.requestTimeout,
+		WriteTimeout:      options.requestTimeout,
+		IdleTimeout:       2 * options.requestTimeout,
+	}
+
+	fmt.Fprintln(os.Stderr, "Serving formatted JSON at "+options.serve)
+	if err := server.ListenAndServe(); err != nil {
+		panic(err)
+	}
+}
+
+// serveFormat answers a single request for runServe
+func serveFormat(w http.ResponseWriter, r *http.Request, options Options, fileName string) {
+	w.Header().Set("Vary", "Accept")
+
+	var input []byte
+	var err error
+	switch {
+	case r.Method == http.MethodPost || r.Method == http.MethodPut:
+		input, err = ioutil.ReadAll(r.Body)
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && fileName != "":
+		// The page of --watch asks for the version of the file with HEAD,
+		// to reload when it changes
+		if info, statErr := os.Stat(fileName); statErr == nil {
+			w.Header().Set("ETag", "\""+fileVersion(info)+"\"")
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		input, err = ioutil.ReadFile(fileName)
+	case r.Method == http.MethodGet:
+		http.Error(w, "POST the JSON to format", http.StatusBadRequest)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("The body is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if name := r.URL.Query().Get("theme"); name != "" {
+		// Only the built-in themes can be chosen, so that a request cannot
+		// read files on the server
+		colors, ok := findTheme(name)
+		if !ok {
+			http.Error(w, "Unknown theme: "+name, http.StatusBadRequest)
+			return
+		}
+		options.theme = name
+		options.colors = colors
+	}
+
+	// Every line inside deeply nested input is indented that deeply, so the
+	// output grows with the square of the nesting
+	if depth := inputDepth(input); options.maxDepth > 0 && depth > options.maxDepth {
+		http.Error(w, fmt.Sprintf("The body nests %d levels deep, more than %d", depth, options.maxDepth), http.StatusBadRequest)
+		return
+	}
+
+	mediaType := negotiateType(r.Header.Get("Accept"), serveTypes)
+	if mediaType == "" {
+		http.Error(w, "Can only answer with "+strings.Join(serveTypes, ", "), http.StatusNotAcceptable)
+		return
+	}
+
+	// Formatting is cancelled when the client goes away or the request runs
+	// out of time, so that an adversarial input cannot keep the server busy
+	ctx, cancel := context.WithTimeout(r.Context(), options.requestTimeout)
+	defer cancel()
+
+	documents, err := formatDocuments(ctx, input, options, ioutil.Discard)
+	if errors.Is(err, context.DeadlineExceeded) {
+		http.Error(w, "Formatting took longer than "+options.requestTimeout.String(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The answer is sent in chunks as it is printed rather than all at once,
+	// so a large page starts rendering in the browser right away
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	stream := &streamWriter{w: w, controller: http.NewResponseController(w)}
+	switch mediaType {
+	case "text/html":
+		// The answer has already started, so running out of time can only
+		// cut it short
+		printPageContext(ctx, stream, documents, options)
+	default:
+		// The JSON and plain text answers are the same indented JSON; there
+		// are never any terminal colors or annotations in them
+		for _, document := range documents {
+			printText(stream, valueTokens(document))
+		}
+	}
+	stream.flush()
+}
+
+// streamChunkSize is how many bytes streamWriter collects before it sends them
+const streamChunkSize = 16 << 10
+
+// streamWriter passes writes on to a response and sends them to the client
+// every streamChunkSize bytes, instead of leaving the whole answer to be sent
+// when the handler returns
+type streamWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	pending    int // Bytes written since the last flush
+}
+
+// Write writes to the response and flushes it once enough has been written
+func (s *streamWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.pending += n
+	if err == nil && s.pending >= streamChunkSize {
+		err = s.flush()
+	}
+	return n, err
+}
+
+// flush sends everything written so far to the client. A response that
+// cannot be flushed is simply sent at the end.
+func (s *streamWriter) flush() error {
+	s.pending = 0
+	if err := s.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// negotiateType returns the first of the offered media types that the Accept
+// header allows, going by the quality each type is given. Types the header
+// does not mention are not allowed, but a missing header allows everything.
+// It returns "" if none of the types are allowed.
+func negotiateType(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	// quality returns how much the header wants the media type, from 0 to 1,
+	// using the most specific range that matches it
+	quality := func(mediaType string) float64 {
+		best, bestSpecificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			fields := strings.Split(part, ";")
+			mediaRange := strings.ToLower(strings.TrimSpace(fields[0]))
+
+			specificity := -1
+			switch {
+			case mediaRange == mediaType:
+				specificity = 2
+			case mediaRange == strings.SplitN(mediaType, "/", 2)[0]+"/*":
+				specificity = 1
+			case mediaRange == "*/*":
+				specificity = 0
+			}
+			if specificity <= bestSpecificity {
+				continue
+			}
+
+			q := 1.0
+			for _, parameter := range fields[1:] {
+				parameter = strings.TrimSpace(parameter)
+				if strings.HasPrefix(parameter, "q=") {
+					if value, err := strconv.ParseFloat(parameter[2:], 64); err == nil {
+						q = value
+					}
+				}
+			}
+			best, bestSpecificity = q, specificity
+		}
+		return best
+	}
+
+	candidates := make([]string, len(offered))
+	copy(candidates, offered)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return quality(candidates[i]) > quality(candidates[j])
+	})
+
+	if quality(candidates[0]) <= 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+// clientAddress returns the IP address that the request came from
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter gives each client address a bucket of requests that refills at
+// a steady rate, so that a client can make a short burst of requests but not
+// keep up more than the rate
+type rateLimiter struct {
+	perMinute int // 0 turns the limit off
+	mutex     sync.Mutex
+	buckets   map[string]*rateBucket
+}
+
+// rateBucket is how many requests a single client can still make, as of when
+// it was last updated
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter returns a limiter that allows perMinute requests a minute from
+// each client address
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{perMinute: perMinute, buckets: make(map[string]*rateBucket)}
+}
+
+// take uses up one request for the client and returns 0, or returns how long
+// the client has to wait if it has no requests left
+func (l *rateLimiter) take(client string) time.Duration {
+	if l.perMinute <= 0 {
+		return 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	capacity := float64(l.perMinute)
+	perSecond := capacity / 60
+
+	// Full buckets are forgotten so that the map does not keep growing with
+	// every address that has ever made a request
+	if len(l.buckets) > 10000 {
+		for address, bucket := range l.buckets {
+			if bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond >= capacity {
+				delete(l.buckets, address)
+			}
+		}
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &rateBucket{tokens: capacity, updated: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+	}
+	bucket.tokens--
+	return 0
+}
+
+// inputDepth returns how deeply the objects and arrays of the input nest,
+// counting the brackets that are not in strings
+func inputDepth(input []byte) int {
+	depth, deepest := 0, 0
+	isString := false
+	for i := 0; i < len(input); i++ {
+		switch character := input[i]; {
+		case isString && character == '\\':
+			i++
+		case character == '"':
+			isString = !isString
+		case isString:
+		case character == '{' || character == '[':
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+		case character == '}' || character == ']':
+			depth--
+		}
+	}
+	return deepest
+}
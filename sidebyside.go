@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+// sideBySideStyle lays out the panes of --side-by-side next to each other,
+// filling the window, each scrolling on its own
+const sideBySideStyle = `.json-pretty-panes {
+	display: flex;
+	position: fixed;
+	top: 0; right: 0; bottom: 0; left: 0;
+}
+.json-pretty-pane {
+	flex: 1 1 50%;
+	overflow: auto;
+	margin: 0;
+	padding: 8px;
+}`
+
+// sideBySideScript keeps the panes of --side-by-side scrolled to the same
+// place, as a fraction of how far each can scroll, since the input and the
+// formatted output are rarely the same length. The scroll that following the
+// other pane causes is ignored, so that the panes do not keep moving each
+// other.
+const sideBySideScript = `document.addEventListener("DOMContentLoaded", function () {
+	var panes = [document.getElementById("json-pretty-raw-pane"), document.getElementById("json-pretty-formatted-pane")];
+	var isFollowing = [false, false];
+	panes.forEach(function (pane, i) {
+		var other = panes[1 - i];
+		pane.addEventListener("scroll", function () {
+			if (isFollowing[i]) {
+				isFollowing[i] = false;
+				return;
+			}
+			var range = pane.scrollHeight - pane.clientHeight;
+			var fraction = range > 0 ? pane.scrollTop / range : 0;
+			var target = Math.round(fraction * (other.scrollHeight - other.clientHeight));
+			if (Math.abs(other.scrollTop - target) > 1) {
+				isFollowing[1 - i] = true;
+				other.scrollTop = target;
+			}
+		});
+	});
+});`
+
+// printSideBySideStart starts the two panes of --side-by-side: the input as it
+// was written on the left, and on the right the formatted documents, which
+// are printed after it until printSideBySideEnd
+func printSideBySideStart(w io.Writer, options Options) {
+	colors := pageTheme(options)
+	fmt.Fprintln(w, "\t\t"+"<div class=\"json-pretty-panes\">")
+	fmt.Fprintln(w, "\t\t"+"<pre id=\"json-pretty-raw-pane\" class=\"json-pretty-pane\" "+
+		"style=\"tab-size:4; color:"+colors.text+"; border-right:1px solid "+colors.separator+"\">"+
+		html.EscapeString(string(options.rawInput))+"</pre>")
+	fmt.Fprintln(w, "\t\t"+"<div id=\"json-pretty-formatted-pane\" class=\"json-pretty-pane\">")
+}
+
+// printSideBySideEnd ends the panes that printSideBySideStart started
+func printSideBySideEnd(w io.Writer) {
+	fmt.Fprintln(w, "\t\t"+"</div>")
+	fmt.Fprintln(w, "\t\t"+"</div>")
+}
+
+// isSideBySide returns true if the page shows the input next to the output
+func isSideBySide(options Options) bool {
+	return options.sideBySide && options.rawInput != nil
+}
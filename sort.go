@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+// sortArraysBy sorts every array of objects in the tree by the value found at
+// the path inside each object, such as ["user", "id"] for "user.id". Numbers
+// are compared by value and come before strings, which are compared by text;
+// objects that do not have the path are moved to the end. Objects that compare
+// equal keep their order.
+func sortArraysBy(node *Node, path []string) {
+	for _, m := range node.members {
+		sortArraysBy(m.value, path)
+	}
+	for _, element := range node.elements {
+		sortArraysBy(element, path)
+	}
+
+	if node.kind != NodeArray || !isArrayOfObjects(node) {
+		return
+	}
+
+	sort.SliceStable(node.elements, func(i, j int) bool {
+		return compareSortValues(lookupPath(node.elements[i], path), lookupPath(node.elements[j], path)) < 0
+	})
+}
+
+// sortKeys sorts the members of every object in the tree by their decoded key,
+// in code point order, so that the output does not depend on the order the
+// input was written in. Members with the same key keep their order.
+func sortKeys(node *Node) {
+	for _, m := range node.members {
+		sortKeys(m.value)
+	}
+	for _, element := range node.elements {
+		sortKeys(element)
+	}
+
+	sort.SliceStable(node.members, func(i, j int) bool {
+		return stringValue(node.members[i].key) < stringValue(node.members[j].key)
+	})
+}
+
+// isArrayOfObjects returns true if the array has elements and all of them are
+// objects
+func isArrayOfObjects(node *Node) bool {
+	for _, element := range node.elements {
+		if element.kind != NodeObject {
+			return false
+		}
+	}
+	return len(node.elements) > 0
+}
+
+// lookupPath follows the path of keys (or array indexes) down from the node and
+// returns the value it ends at, or nil if the path does not exist
+func lookupPath(node *Node, path []string) *Node {
+	for _, segment := range path {
+		switch node.kind {
+		case NodeObject:
+			node = member(node, segment)
+		case NodeArray:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node.elements) {
+				return nil
+			}
+			node = node.elements[index]
+		default:
+			return nil
+		}
+
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// compareSortValues orders two values for sortArraysBy, returning a negative
+// number if a comes first, a positive number if b comes first, and 0 if they
+// are equal. Missing values are nil.
+func compareSortValues(a, b *Node) int {
+	// sortRank places numbers before strings, strings before other values,
+	// and missing values last
+	sortRank := func(node *Node) int {
+		switch {
+		case node == nil:
+			return 3
+		case node.kind == NodeNumber:
+			return 0
+		case node.kind == NodeString:
+			return 1
+		}
+		return 2
+	}
+
+	rankA, rankB := sortRank(a), sortRank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+
+	switch rankA {
+	case 0:
+		valueA, valueB := numberValue(a), numberValue(b)
+		if valueA < valueB {
+			return -1
+		} else if valueA > valueB {
+			return 1
+		}
+	case 1:
+		valueA, valueB := stringValue(a), stringValue(b)
+		if valueA < valueB {
+			return -1
+		} else if valueA > valueB {
+			return 1
+		}
+	}
+	return 0
+}
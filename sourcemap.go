@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// buildSourceMap returns a document that links every rendered token back to
+// the bytes it was read from in the input file, so that tools can jump from the
+// formatted view to the raw source. Each mapping gives the document the token
+// is in, its line and column in that document as rendered (both starting at 1,
+// with columns counted in characters and an indenting tab counted as one), its
+// length in characters, and its start and end byte offsets in the input.
+// Tokens that were not read from the input, such as annotations and the values
+// added by a patch, have no mapping.
+func buildSourceMap(documents [][]Token, fileName string) *Node {
+	mappings := newArrayNode()
+
+	for d, document := range documents {
+		state := printState{isLineStart: true}
+		line, column := 1, 1
+
+		// advance moves the position past the rendered text
+		advance := func(text string) {
+			for _, character := range text {
+				if character == '\n' {
+					line++
+					column = 1
+				} else {
+					column++
+				}
+			}
+		}
+
+		for _, token := range document {
+			whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+			advance(whiteSpacePre)
+
+			if token.offset >= 0 {
+				mapping := newObjectNode()
+				for _, field := range []struct {
+					key   string
+					value int
+				}{
+					{"document", d},
+					{"line", line},
+					{"column", column},
+					{"length", utf8.RuneCountInString(token.content)},
+					{"start", token.offset},
+					{"end", token.offset + len(token.content)},
+				} {
+					mapping.members = append(mapping.members,
+						Member{newStringNode(field.key), newNumberNode(strconv.Itoa(field.value))})
+				}
+				mappings.elements = append(mappings.elements, mapping)
+			}
+
+			advance(token.content + whiteSpacePost)
+		}
+	}
+
+	sourceMap := newObjectNode()
+	sourceMap.members = append(sourceMap.members,
+		Member{newStringNode("version"), newNumberNode("1")},
+		Member{newStringNode("source"), newStringNode(fileName)},
+		Member{newStringNode("mappings"), mappings})
+	return sourceMap
+}
@@ -0,0 +1,52 @@
+package main
+
+// StyleHook returns markup to print before and after a token in the HTML
+// output, such as a badge, a link, or a marker for analytics. The markup is
+// printed as it is, without escaping.
+type StyleHook func(token Token) (prefix, suffix string)
+
+// styleHook is a hook that was registered for a kind of token
+type styleHook struct {
+	hook      StyleHook
+	isReplace bool // The markup takes the place of the token's colors
+}
+
+// AddStyleHook registers a hook for tokens of the kind. Its markup is printed
+// around the colors the token already has. A kind has at most one hook, so a
+// hook replaces the one that was registered for the kind before it. Hooks only
+// apply to the HTML output.
+func (options *Options) AddStyleHook(kind TokenKind, hook StyleHook) {
+	options.setStyleHook(kind, styleHook{hook: hook})
+}
+
+// ReplaceStyleHook registers a hook for tokens of the kind, like AddStyleHook,
+// except that its markup is printed instead of the colors the token would have
+func (options *Options) ReplaceStyleHook(kind TokenKind, hook StyleHook) {
+	options.setStyleHook(kind, styleHook{hook: hook, isReplace: true})
+}
+
+// styleHooks are the hooks that were registered, by the kind of token
+type styleHooks map[TokenKind]styleHook
+
+// setStyleHook registers the hook for the kind
+func (options *Options) setStyleHook(kind TokenKind, hook styleHook) {
+	if options.styleHooks == nil {
+		options.styleHooks = make(styleHooks)
+	}
+	options.styleHooks[kind] = hook
+}
+
+// applyStyleHook returns the markup to print before and after the token, given
+// its colors, once the hook for its kind, if there is one, has been applied
+func applyStyleHook(token Token, hooks styleHooks, colorPre, colorPost string) (string, string) {
+	hook, ok := hooks[token.kind]
+	if !ok {
+		return colorPre, colorPost
+	}
+
+	prefix, suffix := hook.hook(token)
+	if hook.isReplace {
+		return prefix, suffix
+	}
+	return prefix + colorPre, colorPost + suffix
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// placeholderPattern matches ${VAR} and ${VAR:-default} in strings
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// substituteEnv replaces the ${VAR} placeholders in every string value of the
+// tree with the environment variable, or with the default of ${VAR:-default}
+// if it is not set. It returns how many placeholders it replaced. A variable
+// that is not set and has no default is an error, unless allowMissing is true,
+// in which case the placeholder is left in and reported.
+func substituteEnv(node *Node, path []string, allowMissing bool, report func(string)) (int, error) {
+	substituted := 0
+
+	for _, m := range node.members {
+		count, err := substituteEnv(m.value, append(append([]string{}, path...), stringValue(m.key)), allowMissing, report)
+		if err != nil {
+			return 0, err
+		}
+		substituted += count
+	}
+	for i, element := range node.elements {
+		count, err := substituteEnv(element, append(append([]string{}, path...), strconv.Itoa(i)), allowMissing, report)
+		if err != nil {
+			return 0, err
+		}
+		substituted += count
+	}
+
+	if node.kind != NodeString || !placeholderPattern.MatchString(stringValue(node)) {
+		return substituted, nil
+	}
+
+	var missing error
+	text := placeholderPattern.ReplaceAllStringFunc(stringValue(node), func(placeholder string) string {
+		parts := placeholderPattern.FindStringSubmatch(placeholder)
+		if value, ok := os.LookupEnv(parts[1]); ok {
+			substituted++
+			return value
+		}
+		if parts[2] != "" {
+			substituted++
+			return parts[3]
+		}
+
+		if !allowMissing {
+			if missing == nil {
+				missing = fmt.Errorf("--substitute-env: %s is not set, for %s at %q (use --allow-missing to leave it in)", parts[1], placeholder, formatPointer(path))
+			}
+			return placeholder
+		}
+		report(fmt.Sprintf("Left %s at %q, since %s is not set", placeholder, formatPointer(path), parts[1]))
+		return placeholder
+	})
+	if missing != nil {
+		return 0, missing
+	}
+
+	comments := node.comments
+	*node = *newStringNode(text)
+	node.comments = comments
+	return substituted, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+)
+
+const tableStyle = `.json-table { border-collapse:collapse; font-family:monospace; tab-size:4 }
+.json-table th, .json-table td { border:1px solid var(--table-border); padding:2px 6px; text-align:left; vertical-align:top; white-space:pre }
+.json-table th { cursor:pointer; user-select:none }
+.json-table th[data-order="ascending"]::after { content:" \25B2" }
+.json-table th[data-order="descending"]::after { content:" \25BC" }`
+
+const tableScript = `document.addEventListener("click", function (event) {
+	var header = event.target.closest(".json-table th");
+	if (!header) {
+		return;
+	}
+	var table = header.closest("table");
+	var column = header.cellIndex;
+	var order = header.dataset.order === "ascending" ? "descending" : "ascending";
+	Array.prototype.forEach.call(header.parentNode.cells, function (cell) {
+		delete cell.dataset.order;
+	});
+	header.dataset.order = order;
+
+	// Numbers sort as numbers and the rest as text, with empty cells last
+	// either way
+	var value = function (row) {
+		var cell = row.cells[column];
+		return cell.dataset.sort !== undefined ? cell.dataset.sort : "";
+	};
+	var rows = Array.prototype.slice.call(table.tBodies[0].rows);
+	rows.sort(function (a, b) {
+		var x = value(a), y = value(b);
+		if (x === "" || y === "") {
+			return (x === "") - (y === "");
+		}
+		var compared = isFinite(x) && isFinite(y) ? x - y : x.localeCompare(y, undefined, {numeric: true});
+		return order === "ascending" ? compared : -compared;
+	});
+	rows.forEach(function (row) {
+		table.tBodies[0].appendChild(row);
+	});
+});`
+
+// printTable prints a page with each document that is an array of objects as
+// a table, with a row for each object and a column for each key, which can
+// be sorted by clicking its header. Values that are objects or arrays are
+// printed in their cells as they are in the document. Documents that are not
+// arrays of objects are printed as usual.
+func printTable(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	printHeader(w, options)
+	for i, document := range documents {
+		if i > 0 {
+			printSeparator(w, options)
+		}
+
+		root, err := parseTokensContext(ctx, valueTokens(document))
+		if err != nil || !isTable(root) {
+			err = printDocument(ctx, w, localizeDocuments([][]Token{document}, options)[0], options)
+		} else {
+			err = printTableDocument(ctx, w, root, options)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	printFooter(w)
+	return nil
+}
+
+// isTable returns true if the node is an array of objects, which is printed
+// as a table
+func isTable(node *Node) bool {
+	if node.kind != NodeArray || len(node.elements) == 0 {
+		return false
+	}
+	for _, element := range node.elements {
+		if element.kind != NodeObject {
+			return false
+		}
+	}
+	return true
+}
+
+// tableColumns returns every key of the objects in the array, in the order
+// they are first seen
+func tableColumns(node *Node) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, element := range node.elements {
+		for _, m := range element.members {
+			if key := stringValue(m.key); !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	return columns
+}
+
+// printTableDocument prints an array of objects as a table. The first column
+// numbers the rows, so that sorting by it puts them back in order.
+func printTableDocument(ctx context.Context, w io.Writer, root *Node, options Options) error {
+	colors := pageTheme(options)
+	columns := tableColumns(root)
+
+	fmt.Fprintln(w, "\t\t"+"<table class=\"json-table\" style=\"--table-border:"+colors.separator+"\">")
+	fmt.Fprint(w, "\t\t\t"+"<thead><tr><th style=\"color:"+colors.annotation+"\">#</th>")
+	for _, column := range columns {
+		fmt.Fprint(w, "<th style=\"color:"+colors.text+"\">"+html.EscapeString(column)+"</th>")
+	}
+	fmt.Fprintln(w, "</tr></thead>")
+
+	fmt.Fprintln(w, "\t\t\t"+"<tbody>")
+	for i, row := range root.elements {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\t\t\t\t"+"<tr><td data-sort=\"%d\" style=\"color:%s\">%d</td>", i, colors.annotation, i)
+		for _, column := range columns {
+			value := member(row, column)
+			if value == nil {
+				fmt.Fprint(w, "<td></td>")
+				continue
+			}
+			fmt.Fprint(w, "<td data-sort=\""+html.EscapeString(tableSortValue(value))+"\">")
+			tokenArray := localizeDocuments([][]Token{nodeTokens(value)}, options)[0]
+			if err := printTokens(ctx, w, tokenArray, make([]Decoration, len(tokenArray)), options); err != nil {
+				return err
+			}
+			fmt.Fprint(w, "</td>")
+		}
+		fmt.Fprintln(w, "</tr>")
+	}
+	fmt.Fprintln(w, "\t\t\t"+"</tbody>")
+	fmt.Fprintln(w, "\t\t"+"</table>")
+	return nil
+}
+
+// tableSortValue returns what a cell is sorted by: the text of a string, the
+// number of a number, and nothing for null and containers, which sort last
+func tableSortValue(node *Node) string {
+	switch node.kind {
+	case NodeString:
+		return stringValue(node)
+	case NodeNumber:
+		return strconv.FormatFloat(numberValue(node), 'g', -1, 64)
+	case NodeBool:
+		return rawText(node)
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateData is what an output template is executed with
+type templateData struct {
+	File      string             // The name of the input file
+	Documents []templateDocument // Each top-level value of the input
+}
+
+// templateDocument is a single top-level value for an output template
+type templateDocument struct {
+	Value  interface{}     // The value: maps, slices, strings, json.Number, bool, or nil
+	Text   string          // The value as indented JSON
+	Tokens []templateToken // The tokens of the value as they are rendered
+}
+
+// templateToken is a single token for an output template. The white space
+// before and after it is the standard layout, as in the plugin protocol.
+type templateToken struct {
+	Kind      string // The name of its TokenKind
+	Content   string
+	Before    string
+	After     string
+	Offset    int    // Where it starts in the input, or -1 if it was added
+	Highlight string // added, changed, removed, or empty
+}
+
+// templateFunctions are the helpers that output templates can use on top of
+// the ones text/template has, such as html and js for escaping
+var templateFunctions = template.FuncMap{
+	// indent puts the prefix at the start of every line of the text
+	"indent": func(prefix, text string) string {
+		return prefix + strings.Replace(text, "\n", "\n"+prefix, -1)
+	},
+	// markdown escapes the characters that are markup in Markdown and most
+	// wiki syntaxes
+	"markdown": strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+		"<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`).Replace,
+}
+
+// templateNodes maps the maps and slices that nodeValue makes back to the
+// nodes they were made from, by their address and length
+type templateNodes map[[2]uintptr]*Node
+
+// node returns the node that a value in a template was made from, or a node
+// made from the value if it was made by the template instead
+func (nodes templateNodes) node(value interface{}) *Node {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		reflected := reflect.ValueOf(value)
+		if node, ok := nodes[[2]uintptr{reflected.Pointer(), uintptr(reflected.Len())}]; ok {
+			return node
+		}
+	}
+	return valueNode(value)
+}
+
+// printTemplate renders the documents with the output template, a Go
+// text/template that gets the parsed values, their indented text, and their
+// tokens (see templateData), so that any text format can be produced.
+func printTemplate(ctx context.Context, w io.Writer, documents [][]Token, options Options) error {
+	// json prints the values of the documents as they are in them, with
+	// their members in order, and nothing escaped that JSON does not escape
+	nodes := make(templateNodes)
+	outputTemplate, err := template.New(filepath.Base(options.outputTemplate)).
+		Funcs(templateFunctions).
+		Funcs(template.FuncMap{
+			"json": func(value interface{}) string {
+				return strings.TrimSuffix(formatText(nodeTokens(nodes.node(value))), "\n")
+			},
+		}).
+		ParseFiles(options.outputTemplate)
+	if err != nil {
+		return err
+	}
+
+	data := templateData{File: options.fileName}
+	for i, document := range documents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		values := valueTokens(document)
+		root, err := parseTokens(values)
+		if err != nil {
+			return fmt.Errorf("document %d: %v", i+1, err)
+		}
+
+		templateTokens := make([]templateToken, len(document))
+		state := printState{isLineStart: true}
+		for j, token := range document {
+			whiteSpacePre, whiteSpacePost := addWhiteSpace(token, &state)
+			templateTokens[j] = templateToken{
+				Kind:      token.kind.String(),
+				Content:   token.content,
+				Before:    whiteSpacePre,
+				After:     whiteSpacePost,
+				Offset:    token.offset,
+				Highlight: pluginHighlights[token.highlight],
+			}
+		}
+
+		data.Documents = append(data.Documents, templateDocument{
+			Value:  nodeValue(root, nodes),
+			Text:   strings.TrimSuffix(formatText(values), "\n"),
+			Tokens: templateTokens,
+		})
+	}
+
+	return outputTemplate.Execute(w, data)
+}
+
+// nodeValue returns the node as plain Go values: objects become maps, arrays
+// become slices, and numbers keep their text as a json.Number. The maps and
+// slices are added to the nodes, for the json helper.
+func nodeValue(node *Node, nodes templateNodes) interface{} {
+	switch node.kind {
+	case NodeObject:
+		value := make(map[string]interface{}, len(node.members))
+		for _, m := range node.members {
+			value[stringValue(m.key)] = nodeValue(m.value, nodes)
+		}
+		nodes[[2]uintptr{reflect.ValueOf(value).Pointer(), uintptr(len(value))}] = node
+		return value
+	case NodeArray:
+		value := make([]interface{}, len(node.elements))
+		for i, element := range node.elements {
+			value[i] = nodeValue(element, nodes)
+		}
+		nodes[[2]uintptr{reflect.ValueOf(value).Pointer(), uintptr(len(value))}] = node
+		return value
+	case NodeString:
+		return stringValue(node)
+	case NodeNumber:
+		return json.Number(rawText(node))
+	case NodeBool:
+		return rawText(node) == "true"
+	}
+	return nil
+}
+
+// valueNode returns the node of a plain Go value, the reverse of nodeValue,
+// with the keys of maps in order. Values of other types are written as
+// strings.
+func valueNode(value interface{}) *Node {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		node := newObjectNode()
+		for _, key := range keys {
+			node.members = append(node.members, Member{newStringNode(key), valueNode(value[key])})
+		}
+		return node
+	case []interface{}:
+		node := newArrayNode()
+		for _, element := range value {
+			node.elements = append(node.elements, valueNode(element))
+		}
+		return node
+	case string:
+		return newStringNode(value)
+	case json.Number:
+		return newNumberNode(string(value))
+	case bool:
+		return newBoolNode(value)
+	case nil:
+		return newNullNode()
+	}
+	return newStringNode(fmt.Sprint(value))
+}
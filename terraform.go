@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// terraformAction is how the page describes a kind of resource change
+type terraformAction struct {
+	name      string // What the summary groups the change under
+	verb      string // What the header of the change says will happen
+	highlight int
+}
+
+// terraformActions are the actions of a resource change in a plan, joined
+// with commas, as Terraform writes them
+var terraformActions = map[string]terraformAction{
+	"create":        {"create", "will be created", HighlightAdded},
+	"delete":        {"delete", "will be destroyed", HighlightRemoved},
+	"update":        {"update", "will be updated in-place", HighlightChanged},
+	"delete,create": {"replace", "must be replaced", HighlightChanged},
+	"create,delete": {"replace", "must be replaced, creating the new one first", HighlightChanged},
+	"read":          {"read", "will be read during apply", 0},
+	"no-op":         {"no-op", "", 0},
+}
+
+// terraformGroups are the names of the groups of the summary, in order
+var terraformGroups = []string{"create", "update", "replace", "delete", "read"}
+
+// runTerraform renders the output of terraform show -json. For a plan, the
+// first document is a summary of the resources that change, grouped by what
+// happens to them, and each change that does something follows it: created
+// resources highlighted as added, destroyed ones as removed, and updated ones
+// with what changed highlighted and the values they replace annotated. Values
+// that are only known after apply are null, annotated as such, and sensitive
+// values are masked. For state, each resource is a document. The counts are
+// printed on stderr, as terraform plan does.
+func runTerraform(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("terraform needs the file that terraform show -json wrote")
+	}
+	root, err := readJSONFile(arguments[0], options)
+	if err != nil {
+		panic(err)
+	}
+
+	var roots []*Node
+	var summary string
+	if changes := member(root, "resource_changes"); changes != nil {
+		roots, summary = terraformPlan(changes, options)
+	} else if values := member(root, "values"); member(values, "root_module") != nil {
+		roots = terraformState(member(values, "root_module"))
+		summary = strconv.Itoa(len(roots)) + " resource(s)"
+	} else {
+		exitOnError(errors.New(arguments[0]+" is not a plan or state from terraform show -json"), options)
+	}
+
+	documents := make([][]Token, 0, len(roots))
+	for _, root := range roots {
+		if isTreeNeeded(options) {
+			if root, err = transformTree(root, options, os.Stderr); err != nil {
+				panic(err)
+			}
+		}
+		documents = append(documents, nodeTokens(root))
+	}
+
+	ctx, cancel := timeoutContext(options)
+	defer cancel()
+	if err := printOutput(ctx, os.Stdout, documents, options, isColorEnabled(options.color, os.Stdout)); err != nil {
+		exitOnError(err, options)
+	}
+	fmt.Fprintln(os.Stderr, summary)
+}
+
+// terraformPlan returns the summary of the resource changes of a plan and a
+// document for each of them that does something, along with a line that
+// counts them the way terraform plan does
+func terraformPlan(changes *Node, options Options) ([]*Node, string) {
+	groups := make(map[string]*Node)
+	roots := []*Node{newObjectNode()}
+	add, change, destroy := 0, 0, 0
+
+	for _, resource := range changes.elements {
+		address := stringValue(member(resource, "address"))
+		details := member(resource, "change")
+		actionNames := make([]string, 0, 2)
+		if actions := member(details, "actions"); actions != nil {
+			for _, action := range actions.elements {
+				actionNames = append(actionNames, stringValue(action))
+			}
+		}
+		action, ok := terraformActions[strings.Join(actionNames, ",")]
+		if !ok {
+			action = terraformAction{strings.Join(actionNames, ","), "will be " + strings.Join(actionNames, " and "), 0}
+		}
+		if action.name == "no-op" {
+			continue
+		}
+
+		switch action.name {
+		case "create":
+			add++
+		case "update":
+			change++
+		case "delete":
+			destroy++
+		case "replace":
+			add++
+			destroy++
+		}
+
+		if groups[action.name] == nil {
+			groups[action.name] = newArrayNode()
+			groups[action.name].highlight = action.highlight
+		}
+		groups[action.name].elements = append(groups[action.name].elements, newStringNode(address))
+
+		view := terraformChange(details, action, options)
+		header := makeToken("// "+address+" "+action.verb, Comment)
+		header.highlight = action.highlight
+		view.comments = append([]Token{header}, view.comments...)
+		roots = append(roots, view)
+	}
+
+	for _, name := range terraformGroups {
+		if group := groups[name]; group != nil {
+			roots[0].members = append(roots[0].members, Member{newStringNode(name), group})
+		}
+	}
+	return roots, fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy.", add, change, destroy)
+}
+
+// terraformChange returns the resource as it will be after the change, with
+// what changes highlighted
+func terraformChange(details *Node, action terraformAction, options Options) *Node {
+	before := maskSensitive(copyValue(member(details, "before")), member(details, "before_sensitive"))
+	after := maskSensitive(copyValue(member(details, "after")), member(details, "after_sensitive"))
+	after = markUnknown(after, member(details, "after_unknown"))
+	if before == nil {
+		before = newNullNode()
+	}
+	if after == nil {
+		after = newNullNode()
+	}
+
+	var view *Node
+	switch action.name {
+	case "create":
+		view = copyNode(after)
+		view.highlight = HighlightAdded
+	case "delete":
+		view = copyNode(before)
+		view.highlight = HighlightRemoved
+	case "update", "replace":
+		changes := diffNodes(before, after, []string{}, options)
+		view = highlightChanges(after, changes)
+		for _, change := range changes {
+			old, node := lookupPath(before, change.path), lookupPath(view, change.newPath())
+			if change.op == "replace" && old != nil && node != nil && node.annotation == "" {
+				node.annotation = "was " + valueSummary(old)
+			}
+		}
+		if paths := member(details, "replace_paths"); paths != nil {
+			for _, path := range paths.elements {
+				segments := make([]string, 0, len(path.elements))
+				for _, segment := range path.elements {
+					segments = append(segments, logText(segment))
+				}
+				if node := lookupPath(view, segments); node != nil {
+					node.annotation = strings.TrimPrefix(node.annotation+", forces replacement", ", ")
+				}
+			}
+		}
+	default:
+		view = copyNode(after)
+	}
+	return view
+}
+
+// maskSensitive replaces the values that the marks, which mirror the value,
+// say are sensitive with a placeholder, since plans have them in plain text
+func maskSensitive(value, marks *Node) *Node {
+	if value == nil || marks == nil {
+		return value
+	}
+	switch marks.kind {
+	case NodeBool:
+		if rawText(marks) == "true" {
+			return annotated(newStringNode("(sensitive value)"), "sensitive")
+		}
+	case NodeObject:
+		for i, m := range value.members {
+			value.members[i].value = maskSensitive(m.value, member(marks, stringValue(m.key)))
+		}
+	case NodeArray:
+		for i := range value.elements {
+			if i < len(marks.elements) {
+				value.elements[i] = maskSensitive(value.elements[i], marks.elements[i])
+			}
+		}
+	}
+	return value
+}
+
+// markUnknown marks the values that the marks, which mirror the value, say
+// are only known after apply. Plans leave these values out, so they are added
+// as null.
+func markUnknown(value, marks *Node) *Node {
+	if marks == nil {
+		return value
+	}
+	switch marks.kind {
+	case NodeBool:
+		if rawText(marks) == "true" {
+			return annotated(newNullNode(), "known after apply")
+		}
+	case NodeObject:
+		if value == nil || value.kind == NodeNull {
+			value = newObjectNode()
+		}
+		if value.kind != NodeObject {
+			return value
+		}
+		for _, m := range marks.members {
+			key := stringValue(m.key)
+			isFound := false
+			for i := range value.members {
+				if stringValue(value.members[i].key) == key {
+					value.members[i].value = markUnknown(value.members[i].value, m.value)
+					isFound = true
+				}
+			}
+			if marked := markUnknown(nil, m.value); !isFound && marked != nil && !isEmptyContainer(marked) {
+				value.members = append(value.members, Member{newStringNode(key), marked})
+			}
+		}
+	case NodeArray:
+		if value != nil && value.kind == NodeArray {
+			for i := range value.elements {
+				if i < len(marks.elements) {
+					value.elements[i] = markUnknown(value.elements[i], marks.elements[i])
+				}
+			}
+		}
+	}
+	return value
+}
+
+// isEmptyContainer returns true if the value is an object or array with
+// nothing in it
+func isEmptyContainer(node *Node) bool {
+	return (node.kind == NodeObject && len(node.members) == 0) || (node.kind == NodeArray && len(node.elements) == 0)
+}
+
+// terraformState returns the values of each resource of a module of state and
+// the modules inside it, headed by its address
+func terraformState(module *Node) []*Node {
+	roots := make([]*Node, 0)
+	if resources := member(module, "resources"); resources != nil {
+		for _, resource := range resources.elements {
+			values := maskSensitive(copyValue(member(resource, "values")), member(resource, "sensitive_values"))
+			if values == nil {
+				values = newNullNode()
+			}
+			values.comments = append([]Token{makeToken("// "+stringValue(member(resource, "address")), Comment)}, values.comments...)
+			roots = append(roots, values)
+		}
+	}
+	if modules := member(module, "child_modules"); modules != nil {
+		for _, child := range modules.elements {
+			roots = append(roots, terraformState(child)...)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cssColorPattern matches the colors a theme can have: hex colors, rgb() and
+// hsl() colors, and named colors such as teal. Colors are written into the
+// style of the page, so nothing else is let through.
+var cssColorPattern = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|(rgba?|hsla?)\([0-9a-zA-Z.%, /+-]*\)|[a-zA-Z]+)$`)
+
+// theme is a set of colors for the page. Each color is a CSS color, and the
+// colors of the value kinds match the cases of addColor.
+type theme struct {
+	name       string
+	background string // The page behind the documents
+	separator  string // The rule between two top-level values
+	object     string // '{' and '}'
+	array      string // '[' and ']'
+	pair       string // ':'
+	member     string // ','
+	text       string // Strings
+	escape     string // Escape sequences in strings
+	number     string
+	literal    string // true, false, and null
+	comment    string
+	annotation string // Badges and labels
+	added      string // Background of added values
+	changed    string // Background of changed values
+	removed    string // Background of removed values
+
+	// Log records are shown in the color of their level, or in the usual
+	// colors if it is ""
+	levelError string
+	levelWarn  string
+	levelInfo  string
+	levelDebug string
+	levelField string            // The key of the level, if not one of the usual ones
+	levels     map[string]string // Levels named in the theme file, and what they are shown as
+}
+
+// themes lists the built-in themes, the first of which is the default. Most of
+// the colors are taken from https://github.com/reedes/vim-colors-pencil.
+var themes = []theme{
+	{
+		name:       "pencil",
+		background: "#F1F1F1",
+		separator:  "#CCCCCC",
+		object:     "#D75F5F",
+		array:      "#10A778",
+		pair:       "#005F87",
+		member:     "#CCCCCC",
+		text:       "#424242",
+		escape:     "#C30771",
+		number:     "#6855DE",
+		literal:    "#20A5BA",
+		comment:    "#A8A8A8",
+		annotation: "#9A9A9A",
+		added:      "#D7F5DD",
+		changed:    "#FFF0B3",
+		removed:    "#F9D0D0",
+		levelError: "#C30771",
+		levelWarn:  "#A89C14",
+		levelDebug: "#A8A8A8",
+	},
+	{
+		name:       "pencil-dark",
+		background: "#212121",
+		separator:  "#424242",
+		object:     "#D75F5F",
+		array:      "#5FD7A7",
+		pair:       "#20BBFC",
+		member:     "#767676",
+		text:       "#E5E6E6",
+		escape:     "#E32791",
+		number:     "#A790D5",
+		literal:    "#4FB8CC",
+		comment:    "#8A8A8A",
+		annotation: "#8A8A8A",
+		added:      "#1E4D2B",
+		changed:    "#5C4B0E",
+		removed:    "#5C1F1F",
+		levelError: "#E32791",
+		levelWarn:  "#F3E430",
+		levelDebug: "#767676",
+	},
+}
+
+// findTheme returns the built-in theme with the name, and false if there is
+// no such theme
+func findTheme(name string) (theme, bool) {
+	for _, t := range themes {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return theme{}, false
+}
+
+// themeNames returns the names of the built-in themes
+func themeNames() []string {
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.name
+	}
+	return names
+}
+
+// themeFields returns the colors of the theme along with the key that each is
+// stored under in a theme file
+func themeFields(t *theme) []struct {
+	key   string
+	color *string
+} {
+	return []struct {
+		key   string
+		color *string
+	}{
+		{"background", &t.background},
+		{"separator", &t.separator},
+		{"object", &t.object},
+		{"array", &t.array},
+		{"pair", &t.pair},
+		{"member", &t.member},
+		{"string", &t.text},
+		{"escape", &t.escape},
+		{"number", &t.number},
+		{"literal", &t.literal},
+		{"comment", &t.comment},
+		{"annotation", &t.annotation},
+		{"added", &t.added},
+		{"changed", &t.changed},
+		{"removed", &t.removed},
+		{"level-error", &t.levelError},
+		{"level-warn", &t.levelWarn},
+		{"level-info", &t.levelInfo},
+		{"level-debug", &t.levelDebug},
+	}
+}
+
+// loadTheme returns the built-in theme with the name or, if there is none,
+// reads the name as the path of a theme file
+func loadTheme(name string) (theme, error) {
+	if t, ok := findTheme(name); ok {
+		return t, nil
+	}
+
+	root, err := readJSONFile(name, Options{})
+	if err != nil {
+		return theme{}, fmt.Errorf("Unknown theme: %s is not %s or a theme file (%v)",
+			name, strings.Join(themeNames(), ", "), err)
+	}
+	return themeFromNode(root, name)
+}
+
+// themeFromNode reads a theme file, which is an object with a name and a CSS
+// color for each of the keys of themeFields. Colors that are missing are taken
+// from the default theme. For log records, level-field names the key of their
+// level, and levels maps the names of levels to the error, warn, info, or
+// debug color that they are shown in. A color that is not a CSS color is an
+// error.
+func themeFromNode(root *Node, name string) (theme, error) {
+	t := themes[0]
+	t.name = name
+	if value := member(root, "name"); value != nil && value.kind == NodeString {
+		t.name = stringValue(value)
+	}
+
+	for _, field := range themeFields(&t) {
+		if value := member(root, field.key); value != nil && value.kind == NodeString {
+			color := stringValue(value)
+			if color != "" && !cssColorPattern.MatchString(color) {
+				return theme{}, fmt.Errorf("%s: %s is not a CSS color: %q", name, field.key, color)
+			}
+			*field.color = color
+		}
+	}
+
+	if value := member(root, "level-field"); value != nil && value.kind == NodeString {
+		t.levelField = stringValue(value)
+	}
+	if value := member(root, "levels"); value != nil && value.kind == NodeObject {
+		t.levels = make(map[string]string)
+		for _, m := range value.members {
+			if m.value.kind == NodeString {
+				t.levels[strings.ToLower(stringValue(m.key))] = stringValue(m.value)
+			}
+		}
+	}
+	return t, nil
+}
+
+// themeNode returns the theme written as a theme file
+func themeNode(t theme) *Node {
+	root := newObjectNode()
+	root.members = append(root.members, Member{newStringNode("name"), newStringNode(t.name)})
+	for _, field := range themeFields(&t) {
+		root.members = append(root.members, Member{newStringNode(field.key), newStringNode(*field.color)})
+	}
+	if t.levelField != "" {
+		root.members = append(root.members, Member{newStringNode("level-field"), newStringNode(t.levelField)})
+	}
+	if len(t.levels) > 0 {
+		names := make([]string, 0, len(t.levels))
+		for name := range t.levels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		levels := newObjectNode()
+		for _, name := range names {
+			levels.members = append(levels.members, Member{newStringNode(name), newStringNode(t.levels[name])})
+		}
+		root.members = append(root.members, Member{newStringNode("levels"), levels})
+	}
+	return root
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// editorTheme is the part of an editor's color theme that can be turned into
+// a theme for this program. TextMate themes are read into the same shape as VS
+// Code themes: workbench colors such as "editor.background", and a list of
+// rules that color TextMate scopes.
+type editorTheme struct {
+	name   string
+	colors map[string]string
+	rules  []scopeRule
+}
+
+// scopeRule colors every token whose scope matches one of its selectors
+type scopeRule struct {
+	selectors  []string
+	foreground string
+	background string
+}
+
+// themeScopes lists, for each color of a theme, the TextMate scopes whose
+// color is used for it, from the most to the least specific. The scopes are
+// the ones that the JSON grammars of TextMate and VS Code give each token.
+var themeScopes = []struct {
+	key    string
+	scopes []string
+}{
+	{"object", []string{"punctuation.definition.dictionary.begin.json", "punctuation.definition.dictionary", "meta.structure.dictionary.json", "punctuation"}},
+	{"array", []string{"punctuation.definition.array.begin.json", "punctuation.definition.array", "meta.structure.array.json", "punctuation"}},
+	{"pair", []string{"punctuation.separator.dictionary.key-value.json", "punctuation.separator.key-value", "punctuation.separator", "punctuation"}},
+	{"member", []string{"punctuation.separator.dictionary.pair.json", "punctuation.separator.array.json", "punctuation.separator", "punctuation"}},
+	{"string", []string{"string.quoted.double.json", "string.quoted", "string"}},
+	{"escape", []string{"constant.character.escape.json", "constant.character.escape", "constant.character"}},
+	{"number", []string{"constant.numeric.json", "constant.numeric"}},
+	{"literal", []string{"constant.language.json", "constant.language"}},
+	{"comment", []string{"comment.line.double-slash", "comment.block", "comment"}},
+	{"annotation", []string{"comment"}},
+}
+
+// runImportTheme reads the VS Code (.json) or TextMate (.tmTheme) color theme
+// named in the arguments and prints it as a theme file for --theme, so that
+// the page can match the look of an editor
+func runImportTheme(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("import-theme needs one theme file")
+	}
+	fileName := arguments[0]
+
+	themeFile, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		panic(err)
+	}
+
+	var source editorTheme
+	if bytes.HasPrefix(bytes.TrimSpace(themeFile), []byte("<")) {
+		source, err = readTextMateTheme(themeFile)
+	} else {
+		source, err = readVSCodeTheme(themeFile)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, fileName+": "+err.Error())
+		os.Exit(1)
+	}
+
+	if source.name == "" {
+		source.name = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+
+	t, err := convertTheme(source)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, fileName+": "+err.Error())
+		os.Exit(1)
+	}
+	printText(os.Stdout, nodeTokens(themeNode(t)))
+}
+
+// convertTheme picks the colors of a theme for this program out of an editor
+// theme. Colors that the editor theme does not have fall back to its text
+// color, and then to the default theme. A color that is not a CSS color is an
+// error.
+func convertTheme(source editorTheme) (theme, error) {
+	t := themes[0]
+	t.name = source.name
+
+	background := source.colors["editor.background"]
+	if background == "" {
+		background = t.background
+	}
+	foreground := source.colors["editor.foreground"]
+
+	colors := make(map[string]string)
+	for _, role := range themeScopes {
+		for _, scope := range role.scopes {
+			if rule := matchScope(source.rules, scope); rule != nil && rule.foreground != "" {
+				colors[role.key] = rule.foreground
+				break
+			}
+		}
+		if colors[role.key] == "" && foreground != "" {
+			colors[role.key] = foreground
+		}
+	}
+
+	colors["background"] = background
+	colors["separator"] = firstColor(source.colors["editorLineNumber.foreground"], colors["comment"])
+
+	// The highlights are backgrounds, so the diff colors of the editor are
+	// used, or else the colors it gives to inserted, changed, and deleted text
+	highlights := []struct{ key, color, scope string }{
+		{"added", "diffEditor.insertedTextBackground", "markup.inserted"},
+		{"changed", "editor.findMatchHighlightBackground", "markup.changed"},
+		{"removed", "diffEditor.removedTextBackground", "markup.deleted"},
+	}
+	for _, highlight := range highlights {
+		color := source.colors[highlight.color]
+		if color == "" {
+			if rule := matchScope(source.rules, highlight.scope); rule != nil {
+				color = firstColor(rule.background, rule.foreground)
+			}
+		}
+		colors[highlight.key] = color
+	}
+
+	for _, field := range themeFields(&t) {
+		color, err := normalizeColor(colors[field.key], background)
+		if err != nil {
+			return theme{}, fmt.Errorf("%s: %v", field.key, err)
+		}
+		if color != "" {
+			*field.color = color
+		}
+	}
+	return t, nil
+}
+
+// matchScope returns the rule whose selector matches the scope most closely,
+// or nil if none of them match. A selector matches a scope if it is the scope
+// or the start of it, ending on a '.', and longer selectors are closer
+// matches. Selectors that only match within other scopes, such as
+// 'meta.embedded string', are not used.
+func matchScope(rules []scopeRule, scope string) *scopeRule {
+	var best *scopeRule
+	bestLength := 0
+
+	for i, rule := range rules {
+		for _, selector := range rule.selectors {
+			if strings.ContainsAny(selector, " >") {
+				continue
+			}
+			if selector != scope && !strings.HasPrefix(scope, selector+".") {
+				continue
+			}
+			if len(selector) > bestLength {
+				best, bestLength = &rules[i], len(selector)
+			}
+		}
+	}
+	return best
+}
+
+// firstColor returns the first of the colors that is not empty
+func firstColor(colors ...string) string {
+	for _, color := range colors {
+		if color != "" {
+			return color
+		}
+	}
+	return ""
+}
+
+// normalizeColor writes a color as #RRGGBB, which both the page and ANSI
+// output understand. Editor themes often use #RGB and colors with an alpha
+// channel, which are blended onto the background. Other CSS colors are
+// returned unchanged, and anything that is not a CSS color is an error.
+func normalizeColor(color, background string) (string, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return "", nil
+	}
+	if !cssColorPattern.MatchString(color) {
+		return "", fmt.Errorf("%q is not a CSS color", color)
+	}
+	hex := strings.TrimPrefix(color, "#")
+	if !strings.HasPrefix(color, "#") {
+		return color, nil
+	}
+
+	// Expand the short forms
+	if len(hex) == 3 || len(hex) == 4 {
+		long := ""
+		for _, digit := range hex {
+			long += string(digit) + string(digit)
+		}
+		hex = long
+	}
+
+	switch len(hex) {
+	case 6:
+		return "#" + strings.ToUpper(hex), nil
+	case 8:
+		alpha, err := strconv.ParseUint(hex[6:], 16, 8)
+		if err != nil {
+			return "", err
+		}
+
+		back, err := normalizeColor(background, "#FFFFFF")
+		if err != nil {
+			return "", err
+		}
+		back = strings.TrimPrefix(back, "#")
+		if len(back) != 6 {
+			back = "FFFFFF"
+		}
+
+		blended := "#"
+		for i := 0; i < 6; i += 2 {
+			front, _ := strconv.ParseUint(hex[i:i+2], 16, 8)
+			behind, _ := strconv.ParseUint(back[i:i+2], 16, 8)
+			value := (front*alpha + behind*(255-alpha) + 127) / 255
+			blended += fmt.Sprintf("%02X", value)
+		}
+		return blended, nil
+	}
+	return color, nil
+}
+
+// readVSCodeTheme reads a VS Code color theme, which is JSON with comments and
+// trailing commas allowed
+func readVSCodeTheme(themeFile []byte) (editorTheme, error) {
+	source := editorTheme{colors: make(map[string]string)}
+
+	tokenArray, _ := removeTrailingCommas(getTokens(themeFile, Options{allowComments: true}))
+	root, err := parseTokens(tokenArray)
+	if err != nil {
+		return source, err
+	}
+	if root.kind != NodeObject {
+		return source, errors.New("a VS Code theme is an object")
+	}
+
+	if name := member(root, "name"); name != nil && name.kind == NodeString {
+		source.name = stringValue(name)
+	}
+
+	if colors := member(root, "colors"); colors != nil && colors.kind == NodeObject {
+		for _, m := range colors.members {
+			if m.value.kind == NodeString {
+				source.colors[stringValue(m.key)] = stringValue(m.value)
+			}
+		}
+	}
+
+	tokenColors := member(root, "tokenColors")
+	if tokenColors != nil && tokenColors.kind == NodeString {
+		return source, errors.New("tokenColors refers to another file, " + stringValue(tokenColors) + ", which should be imported instead")
+	}
+	if tokenColors == nil || tokenColors.kind != NodeArray {
+		return source, nil
+	}
+
+	for _, element := range tokenColors.elements {
+		rule := scopeRule{}
+
+		switch scope := member(element, "scope"); {
+		case scope == nil:
+			// A rule without a scope sets the colors of all text
+		case scope.kind == NodeString:
+			rule.selectors = splitSelectors(stringValue(scope))
+		case scope.kind == NodeArray:
+			for _, selector := range scope.elements {
+				if selector.kind == NodeString {
+					rule.selectors = append(rule.selectors, splitSelectors(stringValue(selector))...)
+				}
+			}
+		}
+
+		if settings := member(element, "settings"); settings != nil {
+			if foreground := member(settings, "foreground"); foreground != nil && foreground.kind == NodeString {
+				rule.foreground = stringValue(foreground)
+			}
+			if background := member(settings, "background"); background != nil && background.kind == NodeString {
+				rule.background = stringValue(background)
+			}
+		}
+
+		if len(rule.selectors) == 0 {
+			setDefaultColors(&source, rule)
+		} else {
+			source.rules = append(source.rules, rule)
+		}
+	}
+
+	return source, nil
+}
+
+// readTextMateTheme reads a TextMate color theme, which is an XML property
+// list with a list of settings. The first settings without a scope hold the
+// colors of the editor itself.
+func readTextMateTheme(themeFile []byte) (editorTheme, error) {
+	source := editorTheme{colors: make(map[string]string)}
+
+	plist, err := parsePropertyList(themeFile)
+	if err != nil {
+		return source, err
+	}
+	root, ok := plist.(map[string]interface{})
+	if !ok {
+		return source, errors.New("a TextMate theme is a dictionary")
+	}
+
+	if name, ok := root["name"].(string); ok {
+		source.name = name
+	}
+
+	settings, _ := root["settings"].([]interface{})
+	for _, item := range settings {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values, _ := entry["settings"].(map[string]interface{})
+
+		rule := scopeRule{}
+		rule.foreground, _ = values["foreground"].(string)
+		rule.background, _ = values["background"].(string)
+
+		scope, _ := entry["scope"].(string)
+		if scope == "" {
+			setDefaultColors(&source, rule)
+			if gutter, ok := values["gutterForeground"].(string); ok {
+				source.colors["editorLineNumber.foreground"] = gutter
+			}
+			continue
+		}
+
+		rule.selectors = splitSelectors(scope)
+		source.rules = append(source.rules, rule)
+	}
+
+	return source, nil
+}
+
+// setDefaultColors uses the colors of a rule without a scope as the colors of
+// the editor, unless the theme already set them
+func setDefaultColors(source *editorTheme, rule scopeRule) {
+	if rule.foreground != "" && source.colors["editor.foreground"] == "" {
+		source.colors["editor.foreground"] = rule.foreground
+	}
+	if rule.background != "" && source.colors["editor.background"] == "" {
+		source.colors["editor.background"] = rule.background
+	}
+}
+
+// splitSelectors splits a comma-separated list of scope selectors
+func splitSelectors(text string) []string {
+	selectors := make([]string, 0)
+	for _, selector := range strings.Split(text, ",") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
+
+// parsePropertyList reads an XML property list into maps for <dict>, slices
+// for <array>, booleans for <true/> and <false/>, and strings for everything
+// else
+func parsePropertyList(file []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(file))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return readPropertyListValue(decoder, start)
+		}
+	}
+}
+
+// readPropertyListValue reads the value that starts with the element
+func readPropertyListValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch token := token.(type) {
+			case xml.StartElement:
+				if token.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &token); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := readPropertyListValue(decoder, token)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		array := make([]interface{}, 0)
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch token := token.(type) {
+			case xml.StartElement:
+				value, err := readPropertyListValue(decoder, token)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		return start.Name.Local == "true", decoder.Skip()
+	}
+
+	var text string
+	err := decoder.DecodeElement(&text, &start)
+	return text, err
+}
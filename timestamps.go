@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// annotateTimestamps gives every value in the tree that holds a time an
+// annotation with the time written out for --normalize-timestamps: in local
+// time, in UTC, or relative to now, such as 3h ago. The values themselves are
+// left as they are, and times in local time or UTC are written with the time
+// format, if there is one. It returns how many were annotated.
+func annotateTimestamps(node *Node, mode string, format TimeFormat, now time.Time) int {
+	count := 0
+	if moment, ok := timestampValue(node); ok {
+		text := formatTimestamp(moment, mode, format, now)
+		if node.annotation != "" {
+			text = node.annotation + " " + text
+		}
+		node.annotation = text
+		count++
+	}
+
+	for _, m := range node.members {
+		count += annotateTimestamps(m.value, mode, format, now)
+	}
+	for _, element := range node.elements {
+		count += annotateTimestamps(element, mode, format, now)
+	}
+	return count
+}
+
+// timestampValue returns the time that a value holds and true, or false if it
+// does not look like a time. Strings are times in RFC 3339, the ISO 8601 form
+// with a time zone. Numbers are times since the Unix epoch if they fall
+// between 2001 and 2096 in seconds, or as whole milliseconds, microseconds, or
+// nanoseconds, which leaves out most counts and sizes.
+func timestampValue(node *Node) (time.Time, bool) {
+	switch node.kind {
+	case NodeString:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05.999999999Z07:00"} {
+			if moment, err := time.Parse(layout, stringValue(node)); err == nil {
+				return moment, true
+			}
+		}
+	case NodeNumber:
+		epoch := numberValue(node)
+		isWhole := epoch == math.Trunc(epoch)
+		if (epoch >= 1e9 && epoch < 4e9) || (isWhole && ((epoch >= 1e12 && epoch < 4e12) ||
+			(epoch >= 1e15 && epoch < 4e15) || (epoch >= 1e18 && epoch < 4e18))) {
+			return epochTime(epoch), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// epochTime returns the time of a number since the Unix epoch, in seconds,
+// milliseconds, microseconds, or nanoseconds, which are told apart by size
+func epochTime(epoch float64) time.Time {
+	switch {
+	case epoch < 1e11:
+		return time.Unix(0, int64(epoch*1e9))
+	case epoch < 1e14:
+		return time.Unix(0, int64(epoch*1e6))
+	case epoch < 1e17:
+		return time.Unix(0, int64(epoch*1e3))
+	}
+	return time.Unix(0, int64(epoch))
+}
+
+// formatTimestamp writes the time in the form of the mode: local, utc, or
+// relative to now. Local times and UTC are written with the format if there
+// is one, and in RFC 3339 otherwise.
+func formatTimestamp(moment time.Time, mode string, format TimeFormat, now time.Time) string {
+	switch mode {
+	case "relative":
+		return relativeTime(moment.Sub(now))
+	case "local":
+		moment = moment.Local()
+	default:
+		moment = moment.UTC()
+	}
+	if format != nil {
+		return format(moment)
+	}
+	return moment.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// relativeTime writes how far a time is from now in its largest whole unit,
+// such as 3h ago or in 2d
+func relativeTime(offset time.Duration) string {
+	distance := offset
+	if distance < 0 {
+		distance = -distance
+	}
+
+	var text string
+	switch {
+	case distance < time.Second:
+		return "now"
+	case distance < time.Minute:
+		text = fmt.Sprintf("%ds", int(distance/time.Second))
+	case distance < time.Hour:
+		text = fmt.Sprintf("%dm", int(distance/time.Minute))
+	case distance < 48*time.Hour:
+		text = fmt.Sprintf("%dh", int(distance/time.Hour))
+	default:
+		text = fmt.Sprintf("%dd", int(distance/(24*time.Hour)))
+	}
+
+	if offset < 0 {
+		return text + " ago"
+	}
+	return "in " + text
+}
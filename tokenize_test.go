@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{
+		`{"a": [1, -2.5e3, true, false, null], "b": "é\n"}`,
+		`// comment` + "\n" + `{"a": /* inside */ 1}`,
+		`{"cut off": "\u12`,
+		`"\`,
+		`-`,
+		`[1, 2,]`,
+		"\xff\x00{",
+	} {
+		f.Add([]byte(seed), false)
+		f.Add([]byte(seed), true)
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte, allowComments bool) {
+		options := Options{allowComments: allowComments}
+		for _, token := range Tokenize(input, options) {
+			if token.offset < 0 || token.offset >= len(input) {
+				t.Fatalf("token %q has offset %d outside of the %d byte(s) of input", token.content, token.offset, len(input))
+			}
+		}
+
+		// --analyze reads any input without recovering from panics
+		checkInput(input, options)
+	})
+}
+
+// BenchmarkTokenizeExport tokenizes an export of records with repeated keys
+// and unique values, and reports how much memory the tokens keep alive
+func BenchmarkTokenizeExport(b *testing.B) {
+	var export strings.Builder
+	export.WriteString("[")
+	for i := 0; i < 50000; i++ {
+		if i > 0 {
+			export.WriteString(",")
+		}
+		fmt.Fprintf(&export, `{"id": %d, "name": "user %d", "email": "user%d@example.com", "active": true, "score": %d.5}`, i, i, i, i%100)
+	}
+	export.WriteString("]")
+	input := []byte(export.String())
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+
+	var before, after runtime.MemStats
+	var tokens []Token
+	for i := 0; i < b.N; i++ {
+		tokens = nil
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		tokens = Tokenize(input, Options{})
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+	}
+	b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc), "retained-B/op")
+	runtime.KeepAlive(tokens)
+}
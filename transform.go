@@ -0,0 +1,620 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// transformFilter is a compiled --transform expression, which takes a value
+// and returns a new one without changing the value it was given
+type transformFilter func(node *Node) (*Node, error)
+
+// transformPath is a path in a --transform expression, such as .items[].name.
+// Each segment is a key, an index, or "[]" for every element or member value.
+type transformPath []transformSegment
+
+// transformSegment is a single step of a transformPath
+type transformSegment struct {
+	key     string
+	index   int
+	isIndex bool
+	isEach  bool
+}
+
+// transformParser reads a --transform expression, which is a small part of
+// jq's language:
+//
+//	.  .key  ."key"  .[0]  .[]         the value, or one inside it
+//	f | g                              g applied to what f returns
+//	.path |= f                         f applied to the value at the path
+//	del(.path)                         the value without what is at the path
+//	rename(.path; "key")               the member at the path with a new key
+//	map(f)  filter(f)  sort_by(f)      each element changed, kept, or sorted by f
+//	f == g  !=  <  <=  >  >=           comparisons, and "text", 1, true, false, null
+//
+// Reading a path through [] gives an array of the values it reaches.
+type transformParser struct {
+	text     string
+	position int
+}
+
+// parseTransform compiles the expression into a filter
+func parseTransform(text string) (transformFilter, error) {
+	parser := &transformParser{text: text}
+	filter, err := parser.parsePipe()
+	if err != nil {
+		return nil, fmt.Errorf("--transform: %v", err)
+	}
+	if parser.skipSpace(); parser.position < len(text) {
+		return nil, fmt.Errorf("--transform: unexpected %q at column %d", text[parser.position:], parser.position+1)
+	}
+	return filter, nil
+}
+
+// skipSpace moves past white space
+func (parser *transformParser) skipSpace() {
+	for parser.position < len(parser.text) && unicode.IsSpace(rune(parser.text[parser.position])) {
+		parser.position++
+	}
+}
+
+// accept moves past the text if it comes next and returns whether it did
+func (parser *transformParser) accept(text string) bool {
+	parser.skipSpace()
+	if strings.HasPrefix(parser.text[parser.position:], text) {
+		parser.position += len(text)
+		return true
+	}
+	return false
+}
+
+// expect moves past the text, or returns an error if it does not come next
+func (parser *transformParser) expect(text string) error {
+	if !parser.accept(text) {
+		return parser.errorf("expected %q", text)
+	}
+	return nil
+}
+
+// errorf returns an error that points at where the parser is
+func (parser *transformParser) errorf(format string, arguments ...interface{}) error {
+	return fmt.Errorf(format+" at column %d", append(arguments, parser.position+1)...)
+}
+
+// parsePipe reads filters joined by |
+func (parser *transformParser) parsePipe() (transformFilter, error) {
+	filter, err := parser.parseUpdate()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		// |= is an update, which parseUpdate reads
+		parser.skipSpace()
+		if strings.HasPrefix(parser.text[parser.position:], "|=") || !parser.accept("|") {
+			return filter, nil
+		}
+
+		first := filter
+		second, err := parser.parseUpdate()
+		if err != nil {
+			return nil, err
+		}
+		filter = func(node *Node) (*Node, error) {
+			result, err := first(node)
+			if err != nil {
+				return nil, err
+			}
+			return second(result)
+		}
+	}
+}
+
+// parseUpdate reads a comparison, or a path followed by |= and a filter
+func (parser *transformParser) parseUpdate() (transformFilter, error) {
+	start := parser.position
+	parser.skipSpace()
+	if parser.position < len(parser.text) && parser.text[parser.position] == '.' {
+		path, err := parser.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if parser.accept("|=") {
+			update, err := parser.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			return func(node *Node) (*Node, error) {
+				return updatePath(node, path, update)
+			}, nil
+		}
+	}
+
+	parser.position = start
+	return parser.parseComparison()
+}
+
+// parseComparison reads a term, or two terms compared with each other
+func (parser *transformParser) parseComparison() (transformFilter, error) {
+	left, err := parser.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !parser.accept(operator) {
+			continue
+		}
+
+		right, err := parser.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return func(node *Node) (*Node, error) {
+			a, err := left(node)
+			if err != nil {
+				return nil, err
+			}
+			b, err := right(node)
+			if err != nil {
+				return nil, err
+			}
+			return newBoolNode(compareTransformValues(a, b, operator)), nil
+		}, nil
+	}
+	return left, nil
+}
+
+// parseTerm reads a path, a literal, a function call, or a filter in
+// parentheses
+func (parser *transformParser) parseTerm() (transformFilter, error) {
+	parser.skipSpace()
+	if parser.position == len(parser.text) {
+		return nil, parser.errorf("unexpected end")
+	}
+
+	switch character := parser.text[parser.position]; {
+	case character == '.':
+		path, err := parser.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		return func(node *Node) (*Node, error) {
+			return readPath(node, path), nil
+		}, nil
+	case character == '(':
+		parser.position++
+		filter, err := parser.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return filter, parser.expect(")")
+	case character == '"' || character == '-' || (character >= '0' && character <= '9'):
+		literal, err := parser.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return func(node *Node) (*Node, error) {
+			return copyNode(literal), nil
+		}, nil
+	}
+
+	name := parser.parseName()
+	switch name {
+	case "true", "false":
+		value := name == "true"
+		return func(node *Node) (*Node, error) { return newBoolNode(value), nil }, nil
+	case "null":
+		return func(node *Node) (*Node, error) { return newNullNode(), nil }, nil
+	case "del", "rename":
+		return parser.parsePathCall(name)
+	case "map", "filter", "sort_by":
+		return parser.parseFilterCall(name)
+	case "":
+		return nil, parser.errorf("unexpected %q", parser.text[parser.position:parser.position+1])
+	}
+	return nil, parser.errorf("unknown function %q", name)
+}
+
+// parseName reads a name made of letters, digits, and underscores
+func (parser *transformParser) parseName() string {
+	start := parser.position
+	for parser.position < len(parser.text) {
+		character := rune(parser.text[parser.position])
+		if !unicode.IsLetter(character) && !unicode.IsDigit(character) && character != '_' {
+			break
+		}
+		parser.position++
+	}
+	return parser.text[start:parser.position]
+}
+
+// parseLiteral reads a string or a number, which are tokenized the same way
+// as in the input
+func (parser *transformParser) parseLiteral() (*Node, error) {
+	start := parser.position
+	if parser.text[start] == '"' {
+		for parser.position++; parser.position < len(parser.text) && parser.text[parser.position] != '"'; parser.position++ {
+			if parser.text[parser.position] == '\\' {
+				parser.position++
+			}
+		}
+		if parser.position >= len(parser.text) {
+			return nil, parser.errorf("unterminated string")
+		}
+		parser.position++
+		return newStringNode(unquoteString(parser.text[start:parser.position])), nil
+	}
+
+	for parser.position < len(parser.text) && strings.ContainsRune("+-.0123456789eE", rune(parser.text[parser.position])) {
+		parser.position++
+	}
+	number := parser.text[start:parser.position]
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return nil, parser.errorf("bad number %q", number)
+	}
+	return newNumberNode(number), nil
+}
+
+// parsePath reads a path that starts with '.'
+func (parser *transformParser) parsePath() (transformPath, error) {
+	path := transformPath{}
+	if err := parser.expect("."); err != nil {
+		return nil, err
+	}
+
+	// The first key follows the '.' directly, as in .key, or is left out, as
+	// in . and .[0]
+	isKeyNext := true
+	for parser.position < len(parser.text) {
+		switch character := parser.text[parser.position]; {
+		case character == '[':
+			parser.position++
+			parser.skipSpace()
+			if parser.accept("]") {
+				path = append(path, transformSegment{isEach: true})
+				break
+			}
+			start := parser.position
+			for parser.position < len(parser.text) && parser.text[parser.position] >= '0' && parser.text[parser.position] <= '9' {
+				parser.position++
+			}
+			index, err := strconv.Atoi(parser.text[start:parser.position])
+			if err != nil {
+				return nil, parser.errorf("expected an index")
+			}
+			if err := parser.expect("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, transformSegment{index: index, isIndex: true})
+		case character == '.' && !isKeyNext:
+			parser.position++
+			isKeyNext = true
+			continue
+		case character == '"' && isKeyNext:
+			key, err := parser.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, transformSegment{key: stringValue(key)})
+		case isKeyNext:
+			name := parser.parseName()
+			if name == "" {
+				if len(path) > 0 {
+					return nil, parser.errorf("expected a key")
+				}
+				return path, nil
+			}
+			path = append(path, transformSegment{key: name})
+		default:
+			return path, nil
+		}
+		isKeyNext = false
+	}
+	return path, nil
+}
+
+// parsePathCall reads the arguments of del or rename, which take a path
+func (parser *transformParser) parsePathCall(name string) (transformFilter, error) {
+	if err := parser.expect("("); err != nil {
+		return nil, err
+	}
+	parser.skipSpace()
+	path, err := parser.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return nil, parser.errorf("%s needs a path inside the value", name)
+	}
+
+	if name == "del" {
+		return func(node *Node) (*Node, error) {
+			return deletePath(node, path)
+		}, parser.expect(")")
+	}
+
+	if err := parser.expect(";"); err != nil {
+		return nil, err
+	}
+	parser.skipSpace()
+	if parser.position == len(parser.text) || parser.text[parser.position] != '"' {
+		return nil, parser.errorf("rename needs the new key as a string")
+	}
+	newKey, err := parser.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	if last.isIndex || last.isEach {
+		return nil, parser.errorf("rename needs a path that ends in a key")
+	}
+
+	return func(node *Node) (*Node, error) {
+		return updatePath(node, path[:len(path)-1], func(parent *Node) (*Node, error) {
+			renamed := copyNode(parent)
+			for _, m := range renamed.members {
+				if stringValue(m.key) == last.key {
+					m.key.tokens = copyNode(newKey).tokens
+				}
+			}
+			return renamed, nil
+		})
+	}, parser.expect(")")
+}
+
+// parseFilterCall reads the argument of map, filter, or sort_by, which is a
+// filter that is applied to each element
+func (parser *transformParser) parseFilterCall(name string) (transformFilter, error) {
+	if err := parser.expect("("); err != nil {
+		return nil, err
+	}
+	argument, err := parser.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(node *Node) (*Node, error) {
+		if node.kind != NodeArray && (node.kind != NodeObject || name == "sort_by") {
+			return nil, fmt.Errorf("--transform: %s cannot be applied to %s", name, valueSummary(node))
+		}
+
+		result := copyNode(node)
+		switch name {
+		case "map":
+			for i := range result.elements {
+				if result.elements[i], err = argument(result.elements[i]); err != nil {
+					return nil, err
+				}
+			}
+			for i := range result.members {
+				if result.members[i].value, err = argument(result.members[i].value); err != nil {
+					return nil, err
+				}
+			}
+		case "filter":
+			elements, members := result.elements[:0], result.members[:0]
+			for _, element := range result.elements {
+				if keep, err := argument(element); err != nil {
+					return nil, err
+				} else if isTruthy(keep) {
+					elements = append(elements, element)
+				}
+			}
+			for _, m := range result.members {
+				if keep, err := argument(m.value); err != nil {
+					return nil, err
+				} else if isTruthy(keep) {
+					members = append(members, m)
+				}
+			}
+			result.elements, result.members = elements, members
+		case "sort_by":
+			keys := make([]*Node, len(result.elements))
+			for i, element := range result.elements {
+				if keys[i], err = argument(element); err != nil {
+					return nil, err
+				}
+			}
+			order := make([]int, len(keys))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(i, j int) bool {
+				return compareSortValues(keys[order[i]], keys[order[j]]) < 0
+			})
+			elements := make([]*Node, len(order))
+			for i, index := range order {
+				elements[i] = result.elements[index]
+			}
+			result.elements = elements
+		}
+		return result, nil
+	}, parser.expect(")")
+}
+
+// readPath returns the value at the path, or null if there is none. A path
+// through [] gives an array of every value it reaches.
+func readPath(node *Node, path transformPath) *Node {
+	if len(path) == 0 {
+		return copyNode(node)
+	}
+
+	segment := path[0]
+	if segment.isEach {
+		values := newArrayNode()
+		for _, child := range childValues(node) {
+			value := readPath(child, path[1:])
+			if path.hasEach(1) {
+				values.elements = append(values.elements, value.elements...)
+			} else {
+				values.elements = append(values.elements, value)
+			}
+		}
+		return values
+	}
+
+	child := segment.child(node)
+	if child == nil {
+		return newNullNode()
+	}
+	return readPath(child, path[1:])
+}
+
+// hasEach returns true if a segment from the start on is []
+func (path transformPath) hasEach(start int) bool {
+	for _, segment := range path[start:] {
+		if segment.isEach {
+			return true
+		}
+	}
+	return false
+}
+
+// child returns the member or element that the segment names, or nil if there
+// is none
+func (segment transformSegment) child(node *Node) *Node {
+	if segment.isIndex {
+		if node.kind == NodeArray && segment.index < len(node.elements) {
+			return node.elements[segment.index]
+		}
+		return nil
+	}
+	if node.kind == NodeObject {
+		return member(node, segment.key)
+	}
+	return nil
+}
+
+// childValues returns the elements of an array or the values of an object
+func childValues(node *Node) []*Node {
+	if node.kind == NodeObject {
+		values := make([]*Node, len(node.members))
+		for i, m := range node.members {
+			values[i] = m.value
+		}
+		return values
+	}
+	return node.elements
+}
+
+// updatePath returns a copy of the value with the filter applied to what is
+// at the path. A member that is not there is added with the result of the
+// filter applied to null, inside a new object if the value is null.
+func updatePath(node *Node, path transformPath, update transformFilter) (*Node, error) {
+	if len(path) == 0 {
+		return update(node)
+	}
+
+	result := copyNode(node)
+	segment := path[0]
+	switch {
+	case segment.isEach:
+		for i := range result.elements {
+			value, err := updatePath(result.elements[i], path[1:], update)
+			if err != nil {
+				return nil, err
+			}
+			result.elements[i] = value
+		}
+		for i := range result.members {
+			value, err := updatePath(result.members[i].value, path[1:], update)
+			if err != nil {
+				return nil, err
+			}
+			result.members[i].value = value
+		}
+	case segment.isIndex:
+		if result.kind != NodeArray || segment.index >= len(result.elements) {
+			return nil, fmt.Errorf("--transform: no element %d in %s", segment.index, valueSummary(node))
+		}
+		value, err := updatePath(result.elements[segment.index], path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		result.elements[segment.index] = value
+	default:
+		if result.kind == NodeNull {
+			result = newObjectNode()
+		}
+		if result.kind != NodeObject {
+			return nil, fmt.Errorf("--transform: no key %q in %s", segment.key, valueSummary(node))
+		}
+		found := false
+		for i := range result.members {
+			if stringValue(result.members[i].key) == segment.key {
+				value, err := updatePath(result.members[i].value, path[1:], update)
+				if err != nil {
+					return nil, err
+				}
+				result.members[i].value = value
+				found = true
+			}
+		}
+		if !found {
+			value, err := updatePath(newNullNode(), path[1:], update)
+			if err != nil {
+				return nil, err
+			}
+			result.members = append(result.members, Member{newStringNode(segment.key), value})
+		}
+	}
+	return result, nil
+}
+
+// deletePath returns a copy of the value without what is at the path. Paths
+// that are not there are left alone.
+func deletePath(node *Node, path transformPath) (*Node, error) {
+	last := path[len(path)-1]
+	return updatePath(node, path[:len(path)-1], func(parent *Node) (*Node, error) {
+		result := copyNode(parent)
+		switch {
+		case last.isEach:
+			result.elements, result.members = nil, nil
+		case last.isIndex:
+			if result.kind == NodeArray && last.index < len(result.elements) {
+				result.elements = append(result.elements[:last.index], result.elements[last.index+1:]...)
+			}
+		case result.kind == NodeObject:
+			members := result.members[:0]
+			for _, m := range result.members {
+				if stringValue(m.key) != last.key {
+					members = append(members, m)
+				}
+			}
+			result.members = members
+		}
+		return result, nil
+	})
+}
+
+// compareTransformValues compares two values with the operator. Numbers are
+// compared by value, strings by text, and other values are only equal or not.
+func compareTransformValues(a, b *Node, operator string) bool {
+	order := compareSortValues(a, b)
+	isEqual := nodesEqual(a, b)
+	isOrdered := a.kind == b.kind && (a.kind == NodeNumber || a.kind == NodeString)
+
+	switch operator {
+	case "==":
+		return isEqual
+	case "!=":
+		return !isEqual
+	case "<":
+		return isOrdered && order < 0
+	case "<=":
+		return isOrdered && order <= 0
+	case ">":
+		return isOrdered && order > 0
+	}
+	return isOrdered && order >= 0
+}
+
+// isTruthy returns false for false and null, as jq does, and true for any
+// other value
+func isTruthy(node *Node) bool {
+	return node.kind != NodeNull && !(node.kind == NodeBool && rawText(node) == "false")
+}
@@ -0,0 +1,655 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Node is a single JSON value in the parse tree. Strings, numbers, and literals
+// keep the tokens they were read from so that they are printed exactly as they
+// appeared; objects and arrays keep their opening and closing brackets.
+type Node struct {
+	kind     int      // One of the node kinds listed below
+	tokens   []Token  // The tokens of a scalar, or the brackets of a container
+	members  []Member // The members of an object, in the order they appeared
+	elements []*Node  // The elements of an array
+
+	// Comments are kept with the value that follows them. Comments before a
+	// closing bracket and after a top-level value have nothing following them
+	// and are kept separately.
+	comments         []Token
+	closingComments  []Token
+	trailingComments []Token
+
+	highlight   int    // Applied to every token of the value when it is printed
+	annotation  string // Printed after the value to describe it, if not empty
+	explanation string // Printed after the value for --explain, if not empty
+	label       string // Printed before the value to name it, if not empty
+}
+
+// Member is a single key and value pair of an object
+type Member struct {
+	key   *Node
+	value *Node
+}
+
+// Node kinds, one for each type of JSON value
+const (
+	NodeObject = 1
+	NodeArray  = 2
+	NodeString = 3
+	NodeNumber = 4
+	NodeBool   = 5
+	NodeNull   = 6
+)
+
+// SyntaxError is the error for input that is not valid JSON. It says where the
+// problem is, so that it can be pointed out in the input.
+type SyntaxError struct {
+	Offset  int // Where the problem is in the input, or -1 at the end of it
+	message string
+}
+
+// Error returns the description of the problem, which includes the offset
+func (err *SyntaxError) Error() string {
+	return err.message
+}
+
+// parseDocuments parses each top-level value of the tokens into its own tree
+func parseDocuments(tokenArray []Token) ([]*Node, error) {
+	documents := splitDocuments(tokenArray)
+	roots := make([]*Node, 0, len(documents))
+
+	for _, document := range documents {
+		root, err := parseTokens(document)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+
+	return roots, nil
+}
+
+// parseTokens builds the tree of a single JSON value from its tokens. It
+// returns an error describing the first token that does not fit the grammar.
+func parseTokens(tokenArray []Token) (*Node, error) {
+	return parseTokensContext(context.Background(), tokenArray)
+}
+
+// parseTokensContext is parseTokens that gives up with the context's error
+// once the context is done
+func parseTokensContext(ctx context.Context, tokenArray []Token) (*Node, error) {
+	p := parser{tokenArray: tokenArray, ctx: ctx}
+
+	root, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	root.trailingComments = p.skipComments()
+	if p.position < len(p.tokenArray) {
+		return nil, p.unexpected("after the end of the value")
+	}
+
+	return root, nil
+}
+
+// parser walks through the tokens of a single value while building its tree
+type parser struct {
+	tokenArray []Token
+	position   int // The index of the next token to read
+	ctx        context.Context
+	values     int // How many values have been parsed, to check ctx now and then
+	depth      int // How many objects and arrays the next token is inside
+}
+
+// maxNestingDepth is how deeply objects and arrays can nest, as deep as
+// encoding/json lets them, so that parsing cannot run out of stack
+const maxNestingDepth = 10000
+
+// enter goes into an object or array at the next token. It fails if that is
+// deeper than maxNestingDepth or if ctx is done.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxNestingDepth {
+		token := p.tokenArray[p.position]
+		return &SyntaxError{Offset: token.offset,
+			message: fmt.Sprintf("nested deeper than %d levels at offset %d", maxNestingDepth, token.offset)}
+	}
+	return p.ctx.Err()
+}
+
+// leave goes back out of an object or array that enter went into
+func (p *parser) leave() {
+	p.depth--
+}
+
+// skipComments moves past any comments and returns them
+func (p *parser) skipComments() []Token {
+	var comments []Token
+	for p.position < len(p.tokenArray) && p.tokenArray[p.position].kind == Comment {
+		comments = append(comments, p.tokenArray[p.position])
+		p.position++
+	}
+	return comments
+}
+
+// peekKind returns the kind of the next token that is not a comment, or 0 at
+// the end of the tokens
+func (p *parser) peekKind() TokenKind {
+	for i := p.position; i < len(p.tokenArray); i++ {
+		if p.tokenArray[i].kind != Comment {
+			return p.tokenArray[i].kind
+		}
+	}
+	return 0
+}
+
+// unexpected returns an error for the next token, or for the end of the input
+// if there are no tokens left
+func (p *parser) unexpected(context string) error {
+	if p.position >= len(p.tokenArray) {
+		return &SyntaxError{Offset: -1, message: "unexpected end of input " + context}
+	}
+
+	token := p.tokenArray[p.position]
+	return &SyntaxError{Offset: token.offset,
+		message: fmt.Sprintf("unexpected %q at offset %d %s", token.content, token.offset, context)}
+}
+
+// parseValue parses the next value along with the comments before it
+func (p *parser) parseValue() (*Node, error) {
+	p.values++
+	if p.values%4096 == 0 {
+		if err := p.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	comments := p.skipComments()
+	if p.position >= len(p.tokenArray) {
+		return nil, p.unexpected("where a value was expected")
+	}
+
+	var node *Node
+	var err error
+
+	token := p.tokenArray[p.position]
+	switch token.kind {
+	case ObjectOpen:
+		node, err = p.parseObject()
+	case ArrayOpen:
+		node, err = p.parseArray()
+	case StringRegular:
+		node, err = p.parseString()
+	case Number:
+		node = &Node{kind: NodeNumber, tokens: []Token{token}}
+		p.position++
+	case LiteralBoolTrue, LiteralBoolFalse:
+		node = &Node{kind: NodeBool, tokens: []Token{token}}
+		p.position++
+	case LiteralNull:
+		node = &Node{kind: NodeNull, tokens: []Token{token}}
+		p.position++
+	default:
+		return nil, p.unexpected("where a value was expected")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	node.comments = comments
+	return node, nil
+}
+
+// parseString gathers the tokens of a string, which is split into several
+// tokens whenever it contains escape characters
+func (p *parser) parseString() (*Node, error) {
+	node := &Node{kind: NodeString}
+
+	for isOpening := true; ; isOpening = false {
+		if p.position >= len(p.tokenArray) {
+			return nil, p.unexpected("inside a string")
+		}
+
+		token := p.tokenArray[p.position]
+		node.tokens = append(node.tokens, token)
+		p.position++
+
+		if isStringEnd(token, isOpening) {
+			return node, nil
+		}
+	}
+}
+
+// parseObject parses an object, starting at its opening bracket
+func (p *parser) parseObject() (*Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	node := &Node{kind: NodeObject, tokens: []Token{p.tokenArray[p.position]}}
+	p.position++
+
+	for p.peekKind() != ObjectClose {
+		if len(node.members) > 0 {
+			p.skipComments()
+			if p.peekKind() != DelimiterMember {
+				return nil, p.unexpected("where ',' or '}' was expected")
+			}
+			p.position++
+		}
+
+		if p.peekKind() != StringRegular {
+			p.skipComments()
+			return nil, p.unexpected("where a key was expected")
+		}
+
+		key, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipComments()
+		if p.peekKind() != DelimiterPair {
+			return nil, p.unexpected("where ':' was expected")
+		}
+		p.position++
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		node.members = append(node.members, Member{key, value})
+	}
+
+	node.closingComments = p.skipComments()
+	node.tokens = append(node.tokens, p.tokenArray[p.position])
+	p.position++
+
+	return node, nil
+}
+
+// parseArray parses an array, starting at its opening bracket
+func (p *parser) parseArray() (*Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	node := &Node{kind: NodeArray, tokens: []Token{p.tokenArray[p.position]}}
+	p.position++
+
+	for p.peekKind() != ArrayClose {
+		if len(node.elements) > 0 {
+			p.skipComments()
+			if p.peekKind() != DelimiterMember {
+				return nil, p.unexpected("where ',' or ']' was expected")
+			}
+			p.position++
+		}
+
+		if p.peekKind() == 0 {
+			p.skipComments()
+			return nil, p.unexpected("where ']' was expected")
+		}
+
+		element, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		node.elements = append(node.elements, element)
+	}
+
+	node.closingComments = p.skipComments()
+	node.tokens = append(node.tokens, p.tokenArray[p.position])
+	p.position++
+
+	return node, nil
+}
+
+// nodeTokens turns a tree back into tokens so that it can be printed. The
+// delimiters between members and elements are added fresh.
+func nodeTokens(node *Node) []Token {
+	tokenArray := make([]Token, 0)
+	tokenArray = appendNodeTokens(tokenArray, node)
+	tokenArray = append(tokenArray, node.trailingComments...)
+	return tokenArray
+}
+
+// appendNodeTokens appends the tokens of a node and its children. A highlight
+// on the node covers all of its tokens that are not highlighted already.
+func appendNodeTokens(tokenArray []Token, node *Node) []Token {
+	tokenArray = append(tokenArray, node.comments...)
+	if node.label != "" {
+		tokenArray = append(tokenArray, makeToken(node.label, Annotation))
+	}
+
+	start := len(tokenArray)
+	tokenArray = appendValueTokens(tokenArray, node)
+
+	for i := start; i < len(tokenArray); i++ {
+		if tokenArray[i].highlight == 0 {
+			tokenArray[i].highlight = node.highlight
+		}
+	}
+
+	if node.annotation != "" {
+		tokenArray = append(tokenArray, makeToken(node.annotation, Annotation))
+	}
+	if node.explanation != "" {
+		explanation := makeToken(node.explanation, Annotation)
+		explanation.highlight = HighlightExplanation
+		tokenArray = append(tokenArray, explanation)
+	}
+
+	return tokenArray
+}
+
+// appendValueTokens appends the tokens of a value without its comments
+func appendValueTokens(tokenArray []Token, node *Node) []Token {
+	switch node.kind {
+	case NodeObject:
+		tokenArray = append(tokenArray, node.tokens[0])
+		for i, member := range node.members {
+			if i > 0 {
+				tokenArray = append(tokenArray, makeToken(",", DelimiterMember))
+			}
+			tokenArray = appendNodeTokens(tokenArray, member.key)
+
+			// The ':' of a member that is highlighted as a whole stands out
+			// along with its key and value
+			delimiter := makeToken(":", DelimiterPair)
+			if member.key.highlight == member.value.highlight {
+				delimiter.highlight = member.key.highlight
+			}
+			tokenArray = append(tokenArray, delimiter)
+			tokenArray = appendNodeTokens(tokenArray, member.value)
+		}
+		tokenArray = append(tokenArray, node.closingComments...)
+		tokenArray = append(tokenArray, node.tokens[1])
+	case NodeArray:
+		tokenArray = append(tokenArray, node.tokens[0])
+		for i, element := range node.elements {
+			if i > 0 {
+				tokenArray = append(tokenArray, makeToken(",", DelimiterMember))
+			}
+			tokenArray = appendNodeTokens(tokenArray, element)
+		}
+		tokenArray = append(tokenArray, node.closingComments...)
+		tokenArray = append(tokenArray, node.tokens[1])
+	default:
+		tokenArray = append(tokenArray, node.tokens...)
+	}
+
+	return tokenArray
+}
+
+// readJSONFile reads, tokenizes, and parses a file that holds a single JSON
+// value, such as a patch
+func readJSONFile(fileName string, options Options) (*Node, error) {
+	jsonFile, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseTokens(getTokens(jsonFile, options))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return root, nil
+}
+
+// makeToken returns a token that was not read from the input
+func makeToken(content string, kind TokenKind) Token {
+	return Token{content: content, kind: kind, offset: -1}
+}
+
+// newObjectNode returns an empty object with brackets that were not read from
+// the input
+func newObjectNode() *Node {
+	return &Node{kind: NodeObject, tokens: []Token{makeToken("{", ObjectOpen), makeToken("}", ObjectClose)}}
+}
+
+// newArrayNode returns an empty array with brackets that were not read from
+// the input
+func newArrayNode() *Node {
+	return &Node{kind: NodeArray, tokens: []Token{makeToken("[", ArrayOpen), makeToken("]", ArrayClose)}}
+}
+
+// newStringNode returns a string node holding the text, which is escaped and
+// then tokenized the same way as a string in the input would be
+func newStringNode(text string) *Node {
+	tokenArray := getTokens([]byte(quoteString(text)), Options{})
+	for i := range tokenArray {
+		tokenArray[i].offset = -1
+	}
+	return &Node{kind: NodeString, tokens: tokenArray}
+}
+
+// newNumberNode returns a number node with the given text, such as "12.5"
+func newNumberNode(text string) *Node {
+	return &Node{kind: NodeNumber, tokens: []Token{makeToken(text, Number)}}
+}
+
+// newBoolNode returns a node for 'true' or 'false'
+func newBoolNode(value bool) *Node {
+	if value {
+		return &Node{kind: NodeBool, tokens: []Token{makeToken("true", LiteralBoolTrue)}}
+	}
+	return &Node{kind: NodeBool, tokens: []Token{makeToken("false", LiteralBoolFalse)}}
+}
+
+// newNullNode returns a node for 'null'
+func newNullNode() *Node {
+	return &Node{kind: NodeNull, tokens: []Token{makeToken("null", LiteralNull)}}
+}
+
+// rawText returns the text of a scalar exactly as it appeared in the input
+func rawText(node *Node) string {
+	var text string
+	for _, token := range node.tokens {
+		text += token.content
+	}
+	return text
+}
+
+// stringValue returns the decoded text of a string node. Keys are string nodes
+// as well.
+func stringValue(node *Node) string {
+	return unquoteString(rawText(node))
+}
+
+// numberValue returns the value of a number node as a float
+func numberValue(node *Node) float64 {
+	value, _ := strconv.ParseFloat(rawText(node), 64)
+	return value
+}
+
+// member returns the value of the object member with the given key, or nil if
+// the node is missing, is not an object, or has no such member. If a key
+// appears more than once, the last one wins.
+func member(node *Node, key string) *Node {
+	var value *Node
+	if node != nil && node.kind == NodeObject {
+		for _, m := range node.members {
+			if stringValue(m.key) == key {
+				value = m.value
+			}
+		}
+	}
+	return value
+}
+
+// quoteString writes the text as a JSON string, escaping the characters that
+// JSON does not allow inside strings
+func quoteString(text string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+
+	for _, character := range text {
+		switch character {
+		case '"':
+			quoted.WriteString("\\\"")
+		case '\\':
+			quoted.WriteString("\\\\")
+		case '\n':
+			quoted.WriteString("\\n")
+		case '\r':
+			quoted.WriteString("\\r")
+		case '\t':
+			quoted.WriteString("\\t")
+		case '\b':
+			quoted.WriteString("\\b")
+		case '\f':
+			quoted.WriteString("\\f")
+		default:
+			if character < 0x20 {
+				quoted.WriteString(fmt.Sprintf("\\u%04x", character))
+			} else {
+				quoted.WriteRune(character)
+			}
+		}
+	}
+
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+// unquoteString decodes a JSON string, including its surrounding quotes, into
+// the text it represents. Escapes that are not valid are kept as they are.
+func unquoteString(quoted string) string {
+	if len(quoted) >= 2 && quoted[0] == '"' && quoted[len(quoted)-1] == '"' {
+		quoted = quoted[1 : len(quoted)-1]
+	}
+
+	var text strings.Builder
+	for i := 0; i < len(quoted); i++ {
+		if quoted[i] != '\\' || i+1 >= len(quoted) {
+			text.WriteByte(quoted[i])
+			continue
+		}
+
+		i++
+		switch quoted[i] {
+		case '"', '\\', '/':
+			text.WriteByte(quoted[i])
+		case 'b':
+			text.WriteByte('\b')
+		case 'f':
+			text.WriteByte('\f')
+		case 'n':
+			text.WriteByte('\n')
+		case 'r':
+			text.WriteByte('\r')
+		case 't':
+			text.WriteByte('\t')
+		case 'u':
+			character, length := decodeUnicodeEscape(quoted[i-1:])
+			if length == 0 {
+				text.WriteString(quoted[i-1 : i+1])
+				continue
+			}
+			text.WriteRune(character)
+			i += length - 2
+		default:
+			text.WriteString(quoted[i-1 : i+1])
+		}
+	}
+
+	return text.String()
+}
+
+// decodeUnicodeEscape decodes the '\uXXXX' escape at the start of the text,
+// combining UTF-16 surrogate pairs, and returns the character along with how
+// many bytes of text it used. The length is 0 if the escape is not valid.
+func decodeUnicodeEscape(text string) (rune, int) {
+	if len(text) < 6 {
+		return 0, 0
+	}
+
+	first, err := strconv.ParseUint(text[2:6], 16, 16)
+	if err != nil {
+		return 0, 0
+	}
+
+	if utf16.IsSurrogate(rune(first)) && len(text) >= 12 && text[6:8] == "\\u" {
+		second, err := strconv.ParseUint(text[8:12], 16, 16)
+		if err == nil {
+			if character := utf16.DecodeRune(rune(first), rune(second)); character != utf8.RuneError {
+				return character, 12
+			}
+		}
+	}
+
+	return rune(first), 6
+}
+
+// copyNode returns a deep copy of a node, so that the copy can be changed
+// without touching the original
+func copyNode(node *Node) *Node {
+	copied := *node
+	copied.tokens = append([]Token(nil), node.tokens...)
+
+	copied.members = nil
+	for _, m := range node.members {
+		copied.members = append(copied.members, Member{copyNode(m.key), copyNode(m.value)})
+	}
+
+	copied.elements = nil
+	for _, element := range node.elements {
+		copied.elements = append(copied.elements, copyNode(element))
+	}
+
+	return &copied
+}
+
+// nodesEqual returns true if two values are the same. Numbers are compared by
+// value, strings by their decoded text, and objects regardless of the order of
+// their members.
+func nodesEqual(a, b *Node) bool {
+	if a.kind != b.kind {
+		return false
+	}
+
+	switch a.kind {
+	case NodeObject:
+		if len(a.members) != len(b.members) {
+			return false
+		}
+		for _, m := range a.members {
+			other := member(b, stringValue(m.key))
+			if other == nil || !nodesEqual(m.value, other) {
+				return false
+			}
+		}
+		return true
+	case NodeArray:
+		if len(a.elements) != len(b.elements) {
+			return false
+		}
+		for i := range a.elements {
+			if !nodesEqual(a.elements[i], b.elements[i]) {
+				return false
+			}
+		}
+		return true
+	case NodeString:
+		return stringValue(a) == stringValue(b)
+	case NodeNumber:
+		return numberValue(a) == numberValue(b)
+	case NodeBool:
+		return a.tokens[0].kind == b.tokens[0].kind
+	}
+	return true
+}
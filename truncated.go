@@ -0,0 +1,72 @@
+package main
+
+// closeTruncated finishes input that is cut off in the middle of a value, as
+// logs often cut off JSON, for --allow-truncated. An open string is closed, a
+// key without a value gets null, a trailing comma is dropped, and the open
+// objects and arrays are closed. The tokens that are added are highlighted as
+// synthetic, so they stand apart from the input. It returns the tokens along
+// with how many were added, which is 0 if the input was not cut off.
+func closeTruncated(tokenArray []Token) ([]Token, int) {
+	var open []TokenKind // The objects and arrays that are open
+	var lastKind TokenKind
+	last := -1 // Where the last token that is not a comment is
+	isInString := false
+	isKey := false // Whether the last string is the key of a member
+
+	for i, token := range tokenArray {
+		switch token.kind {
+		case Comment:
+			continue
+		case ObjectOpen, ArrayOpen:
+			open = append(open, token.kind)
+		case ObjectClose, ArrayClose:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case StringRegular, StringEscaped, StringClose:
+			isOpening := !isInString
+			if isOpening {
+				isKey = len(open) > 0 && open[len(open)-1] == ObjectOpen &&
+					(lastKind == ObjectOpen || lastKind == DelimiterMember)
+			}
+			isInString = !isStringEnd(token, isOpening)
+		}
+		lastKind, last = token.kind, i
+	}
+
+	if !isInString && len(open) == 0 && lastKind != DelimiterPair {
+		return tokenArray, 0
+	}
+
+	added := 0
+	// addToken adds a token that was not in the input
+	addToken := func(content string, kind TokenKind) {
+		token := makeToken(content, kind)
+		token.highlight = HighlightSynthetic
+		tokenArray = append(tokenArray, token)
+		added++
+	}
+
+	if lastKind == DelimiterMember {
+		tokenArray = append(tokenArray[:last], tokenArray[last+1:]...)
+	}
+
+	if isInString {
+		addToken("\"", StringClose)
+	}
+	switch {
+	case isKey && (lastKind == StringRegular || lastKind == StringEscaped || lastKind == StringClose):
+		addToken(":", DelimiterPair)
+		addToken("null", LiteralNull)
+	case lastKind == DelimiterPair:
+		addToken("null", LiteralNull)
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] == ObjectOpen {
+			addToken("}", ObjectClose)
+		} else {
+			addToken("]", ArrayClose)
+		}
+	}
+	return tokenArray, added
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+)
+
+// unifiedContext is how many unchanged lines are shown around each change
+const unifiedContext = 3
+
+// unifiedLine is a line of a unified diff: ' ' for one in both files, '-' for
+// one only in the first, and '+' for one only in the second
+type unifiedLine struct {
+	op   byte
+	runs []textRun
+}
+
+// printUnified prints a unified diff, as diff -u does, of the two documents
+// formatted the usual way, for --output=unified. Each hunk starts with the
+// lines it covers in each file. With --format=ansi the lines keep the colors
+// of the tokens, which --color=never leaves out for tools that apply the
+// diff, and otherwise they are printed as a page.
+func printUnified(ctx context.Context, w io.Writer, before, after *Node, names []string, options Options) error {
+	colors := pageTheme(options)
+	beforeLines, err := layoutText(ctx, [][]Token{nodeTokens(before)}, colors, 0)
+	if err != nil {
+		return err
+	}
+	afterLines, err := layoutText(ctx, [][]Token{nodeTokens(after)}, colors, 0)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"--- " + names[0], "+++ " + names[1]}
+	hunks := unifiedHunks(beforeLines, afterLines)
+
+	switch options.format {
+	case "ansi":
+		isColored := isColorEnabled(options.color, os.Stdout)
+		for _, line := range header {
+			fmt.Fprintln(w, line)
+		}
+		for _, hunk := range hunks {
+			fmt.Fprintln(w, ansiRun(textRun{text: hunk.header, color: colors.annotation}, isColored))
+			for _, line := range hunk.lines {
+				fmt.Fprintln(w, ansiUnifiedLine(line, colors, isColored))
+			}
+		}
+	case "html":
+		printHeader(w, options)
+		fmt.Fprintln(w, "\t\t"+"<span style=\"font-family:monospace; white-space:pre\">")
+		for _, line := range header {
+			fmt.Fprintln(w, "<span style=\"color:"+colors.comment+"\">"+html.EscapeString(line)+"</span>")
+		}
+		for _, hunk := range hunks {
+			fmt.Fprintln(w, "<span style=\"color:"+colors.annotation+"\">"+html.EscapeString(hunk.header)+"</span>")
+			for _, line := range hunk.lines {
+				fmt.Fprintln(w, htmlUnifiedLine(line, colors))
+			}
+		}
+		fmt.Fprintln(w, "\t\t"+"</span>")
+		printFooter(w)
+	default:
+		return fmt.Errorf("--output=unified can only be printed with --format=html or ansi, not %s", options.format)
+	}
+	return nil
+}
+
+// unifiedHunk is a run of changed lines and the lines around them
+type unifiedHunk struct {
+	header string // The @@ line with where the hunk is in each file
+	lines  []unifiedLine
+}
+
+// unifiedHunks returns the hunks that turn the lines before into the lines
+// after. The lines are matched up by their longest common subsequence, after
+// the lines both start and end with, and the rest of the lines are all
+// replaced if there are too many to match up.
+func unifiedHunks(before, after [][]textRun) []unifiedHunk {
+	beforeText, afterText := lineTexts(before), lineTexts(after)
+
+	start := 0
+	for start < len(before) && start < len(after) && beforeText[start] == afterText[start] {
+		start++
+	}
+	end := 0
+	for end < len(before)-start && end < len(after)-start &&
+		beforeText[len(before)-1-end] == afterText[len(after)-1-end] {
+		end++
+	}
+
+	pairs := [][2]int{}
+	beforeMiddle, afterMiddle := beforeText[start:len(before)-end], afterText[start:len(after)-end]
+	if len(beforeMiddle)*len(afterMiddle) <= diffMaxLCSCells {
+		pairs = longestCommonSubsequence(beforeMiddle, afterMiddle)
+	}
+
+	// Every line of either file in order, along with its number in each
+	type numberedLine struct {
+		unifiedLine
+		beforeNumber, afterNumber int
+	}
+	lines := make([]numberedLine, 0, len(before)+len(after))
+	i, j := 0, 0
+	addUntil := func(nextBefore, nextAfter int) {
+		for ; i < nextBefore; i++ {
+			lines = append(lines, numberedLine{unifiedLine{'-', before[i]}, i, j})
+		}
+		for ; j < nextAfter; j++ {
+			lines = append(lines, numberedLine{unifiedLine{'+', after[j]}, i, j})
+		}
+	}
+	for k := 0; k < start; k++ {
+		lines = append(lines, numberedLine{unifiedLine{' ', after[j]}, i, j})
+		i++
+		j++
+	}
+	for _, pair := range append(pairs, [2]int{len(beforeMiddle), len(afterMiddle)}) {
+		addUntil(start+pair[0], start+pair[1])
+		if pair[0] < len(beforeMiddle) {
+			lines = append(lines, numberedLine{unifiedLine{' ', after[j]}, i, j})
+			i++
+			j++
+		}
+	}
+	for k := 0; k < end; k++ {
+		lines = append(lines, numberedLine{unifiedLine{' ', after[j]}, i, j})
+		i++
+		j++
+	}
+
+	// Changes that are close enough to share context are in the same hunk
+	hunks := make([]unifiedHunk, 0)
+	for first := 0; first < len(lines); first++ {
+		if lines[first].op == ' ' {
+			continue
+		}
+
+		last := first
+		for next := first + 1; next < len(lines) && next <= last+2*unifiedContext+1; next++ {
+			if lines[next].op != ' ' {
+				last = next
+			}
+		}
+
+		from, to := first-unifiedContext, last+unifiedContext+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		hunk := unifiedHunk{}
+		beforeCount, afterCount := 0, 0
+		for _, line := range lines[from:to] {
+			hunk.lines = append(hunk.lines, line.unifiedLine)
+			if line.op != '+' {
+				beforeCount++
+			}
+			if line.op != '-' {
+				afterCount++
+			}
+		}
+		hunk.header = fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(lines[from].beforeNumber, beforeCount), hunkRange(lines[from].afterNumber, afterCount))
+		hunks = append(hunks, hunk)
+		first = to - 1
+	}
+	return hunks
+}
+
+// hunkRange writes where a hunk is in a file, given the index of its first
+// line and how many lines it has, with line numbers starting at 1. A hunk with
+// no lines is written as the line before it, as diff -u does.
+func hunkRange(index, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", index)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", index+1)
+	}
+	return fmt.Sprintf("%d,%d", index+1, count)
+}
+
+// lineTexts returns the text of each line without its styles
+func lineTexts(lines [][]textRun) []string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		var text strings.Builder
+		for _, run := range line {
+			text.WriteString(run.text)
+		}
+		texts[i] = text.String()
+	}
+	return texts
+}
+
+// unifiedBackground returns the color behind a line that was removed or
+// added, or "" for one that is in both files
+func unifiedBackground(op byte, colors theme) string {
+	switch op {
+	case '-':
+		return colors.removed
+	case '+':
+		return colors.added
+	}
+	return ""
+}
+
+// ansiUnifiedLine returns the line for a terminal, behind the color of its
+// change
+func ansiUnifiedLine(line unifiedLine, colors theme, isColored bool) string {
+	background := unifiedBackground(line.op, colors)
+	text := ansiRun(textRun{text: string(line.op), background: background}, isColored)
+	for _, run := range line.runs {
+		if background != "" {
+			run.background = background
+		}
+		text += ansiRun(run, isColored)
+	}
+	return text
+}
+
+// ansiRun returns the text of the run in its colors, or plain if isColored is
+// false
+func ansiRun(run textRun, isColored bool) string {
+	codes := make([]string, 0, 3)
+	if run.color != "" {
+		codes = append(codes, "38;2;"+ansiRGB(run.color))
+	}
+	if run.background != "" {
+		codes = append(codes, "48;2;"+ansiRGB(run.background))
+	}
+	if run.isStruck {
+		codes = append(codes, "9")
+	}
+	if !isColored || len(codes) == 0 {
+		return run.text
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + run.text + "\x1b[0m"
+}
+
+// htmlUnifiedLine returns the line for the page, behind the color of its
+// change
+func htmlUnifiedLine(line unifiedLine, colors theme) string {
+	var text strings.Builder
+	if background := unifiedBackground(line.op, colors); background != "" {
+		text.WriteString("<span style=\"display:inline-block; min-width:100%; background-color:" + background + "\">")
+	} else {
+		text.WriteString("<span>")
+	}
+	text.WriteString(html.EscapeString(string(line.op)))
+	for _, run := range line.runs {
+		styles := make([]string, 0, 3)
+		if run.color != "" {
+			styles = append(styles, "color:"+run.color)
+		}
+		if run.background != "" {
+			styles = append(styles, "background-color:"+run.background)
+		}
+		if run.isStruck {
+			styles = append(styles, "text-decoration:line-through")
+		}
+		if len(styles) == 0 {
+			text.WriteString(html.EscapeString(run.text))
+			continue
+		}
+		text.WriteString("<span style=\"" + strings.Join(styles, "; ") + "\">" + html.EscapeString(run.text) + "</span>")
+	}
+	text.WriteString("</span>")
+	return text.String()
+}
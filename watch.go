@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// watchInterval is how often --watch checks whether the file has changed
+const watchInterval = 500 * time.Millisecond
+
+// watchScript reloads a page from --serve --watch when its file changes, which
+// the server tells it through the ETag of a HEAD request. It asks every two
+// seconds, well within the default --rate-limit.
+const watchScript = `(function () {
+	var version = null;
+	setInterval(function () {
+		fetch(location.href, {method: "HEAD", cache: "no-store"}).then(function (response) {
+			var current = response.headers.get("ETag");
+			if (version === null) {
+				version = current;
+			} else if (current !== version) {
+				location.reload();
+			}
+		}).catch(function () {});
+	}, 2000);
+})();`
+
+// runWatch formats the file named in the arguments and formats it again each
+// time it changes, clearing the terminal first, until the program is stopped.
+// With --baseline (or --diff-against), every render highlights what differs
+// from the baseline, which gives a live view of how a file drifts while it is
+// being edited. A file that is not valid JSON for a moment, such as half way
+// through an edit, only reports its error until it is fixed.
+func runWatch(options Options, arguments []string) {
+	if len(arguments) != 1 {
+		panic("--watch needs one file")
+	}
+	fileName := arguments[0]
+
+	var lastVersion string
+	for {
+		if info, err := os.Stat(fileName); err == nil {
+			if version := fileVersion(info); version != lastVersion {
+				lastVersion = version
+				renderWatched(fileName, options)
+			}
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// renderWatched formats the file once for runWatch
+func renderWatched(fileName string, options Options) {
+	if isTerminal(os.Stdout) {
+		fmt.Fprint(os.Stdout, "\x1b[H\x1b[2J")
+	}
+
+	jsonFile, err := ioutil.ReadFile(fileName)
+	if err == nil {
+		var documents [][]Token
+		options.fileName = fileName
+		if documents, err = formatDocuments(context.Background(), jsonFile, options, os.Stderr); err == nil {
+			err = printOutput(context.Background(), os.Stdout, documents, options, isColorEnabled(options.color, os.Stdout))
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, fileName+": "+err.Error())
+	}
+	fmt.Fprintln(os.Stderr, "Watching "+fileName+" for changes, rendered at "+time.Now().Format("15:04:05"))
+}
+
+// fileVersion returns a value that changes whenever the file does
+func fileVersion(info os.FileInfo) string {
+	return fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+}
@@ -0,0 +1,29 @@
+#!/bin/sh
+# Builds the formatter as WebAssembly for index.html. The browser entry point
+# lives in this directory so that `go run .` at the top level does not pick it
+# up. Go only builds files from a single directory, so it is copied next to
+# every top-level file except the command-line main and the tests, and the
+# directory is built as a package so that build constraints such as those of
+# the *_windows.go files are kept.
+set -e
+cd "$(dirname "$0")"
+
+build="$(mktemp -d)"
+trap 'rm -rf "$build"' EXIT
+cp ../go.mod wasm_main.go "$build"
+for file in ../*.go; do
+	case "$file" in
+	../main.go | *_test.go) ;;
+	*) cp "$file" "$build" ;;
+	esac
+done
+
+(cd "$build" && GOOS=js GOARCH=wasm go build -o "$OLDPWD/json-pretty-printer.wasm" .)
+
+# The loader that comes with Go moved from misc/wasm to lib/wasm in Go 1.24
+root="$(go env GOROOT)"
+if [ -f "$root/lib/wasm/wasm_exec.js" ]; then
+	cp "$root/lib/wasm/wasm_exec.js" .
+else
+	cp "$root/misc/wasm/wasm_exec.js" .
+fi
@@ -0,0 +1,53 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"syscall/js"
+)
+
+// main makes the formatter available to JavaScript as a global function,
+// jsonPrettyPrint(input, flags), and then keeps running so that the function
+// can be called again. The flags are an optional array of the same flags the
+// program accepts, such as ["--collapsible"]. The function returns an object
+// with the rendered page in html, or a message in error if the input could
+// not be formatted. It is built by web/build.sh, and web/index.html is a page
+// that uses it.
+func main() {
+	js.Global().Set("jsonPrettyPrint", js.FuncOf(formatForJS))
+	select {}
+}
+
+// formatForJS is the function that JavaScript calls as jsonPrettyPrint
+func formatForJS(this js.Value, arguments []js.Value) (result interface{}) {
+	// A panic would stop the program for good, so it becomes an error instead
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = map[string]interface{}{"error": fmt.Sprint(recovered)}
+		}
+	}()
+
+	if len(arguments) < 1 {
+		return map[string]interface{}{"error": "jsonPrettyPrint needs the JSON to format"}
+	}
+
+	flagArguments := make([]string, 0)
+	if len(arguments) > 1 && !arguments[1].IsUndefined() && !arguments[1].IsNull() {
+		for i := 0; i < arguments[1].Length(); i++ {
+			flagArguments = append(flagArguments, arguments[1].Index(i).String())
+		}
+	}
+
+	options, _, err := readOptions(flagArguments, flag.ContinueOnError)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+
+	page, err := Format([]byte(arguments[0].String()), options)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	return map[string]interface{}{"html": page}
+}